@@ -0,0 +1,169 @@
+// Package assign solves the (rectangular) assignment problem with the
+// Hungarian (Kuhn-Munkres) method.
+package assign
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInfeasible is returned (wrapped) by Solve() when the rows (or the
+// columns if there are less of them) can't all be assigned because of
+// the forbidden cells.
+var ErrInfeasible = errors.New("no complete assignment")
+
+// Assignment of rows to columns.
+type Assignment struct {
+	// Match[i] is the column assigned to row i, -1 if row i is left
+	// out because there are more rows than columns
+	Match []int
+
+	// total cost of the matched cells
+	Cost float64
+
+	// dual values (potentials) of the rows and the columns, they
+	// satisfy U[i]+V[j]<=costs[i][j] on every cell and U[i]+V[j]=
+	// costs[i][j] on the matched ones
+	U, V []float64
+}
+
+// Solve the assignment problem of the given cost matrix, every row is
+// assigned to a different column (every column to a different row if
+// there are less columns than rows) so the total cost is minimal.
+//
+//	costs: 2-D matrix, at least one row and one column, all rows have
+//	       the same length. math.Inf(1) marks a forbidden cell.
+//
+//	returns the optimal Assignment{}.
+//
+// It runs in O(n^2*m) where n is the smaller and m the bigger dimension.
+func Solve(costs [][]float64) (*Assignment, error) {
+	rows := len(costs)
+	if rows < 1 {
+		return nil, fmt.Errorf("not enough rows, need at least 1!")
+	}
+	cols := len(costs[0])
+	if cols < 1 {
+		return nil, fmt.Errorf("not enough columns, need at least 1!")
+	}
+	for i := 0; i < rows; i++ {
+		if len(costs[i]) != cols {
+			return nil, fmt.Errorf("row %v has %v columns, should be %v!", i, len(costs[i]), cols)
+		}
+		for j := 0; j < cols; j++ {
+			if c := costs[i][j]; math.IsNaN(c) || math.IsInf(c, -1) {
+				return nil, fmt.Errorf("costs[%v][%v]=%v is invalid!", i, j, c)
+			}
+		}
+	}
+
+	if rows <= cols {
+		colOf, u, v, err := hungarian(rows, cols, func(i, j int) float64 { return costs[i][j] })
+		if err != nil {
+			return nil, err
+		}
+		return newAssignment(costs, colOf, u, v), nil
+	}
+
+	// more rows than columns, assign the columns of the transposed matrix
+	rowOf, v, u, err := hungarian(cols, rows, func(j, i int) float64 { return costs[i][j] })
+	if err != nil {
+		return nil, err
+	}
+	colOf := make([]int, rows)
+	for i := 0; i < rows; i++ {
+		colOf[i] = -1
+	}
+	for j := 0; j < cols; j++ {
+		colOf[rowOf[j]] = j
+	}
+	return newAssignment(costs, colOf, u, v), nil
+}
+
+func newAssignment(costs [][]float64, match []int, u, v []float64) *Assignment {
+	cost := float64(0)
+	for i, j := range match {
+		if j >= 0 {
+			cost += costs[i][j]
+		}
+	}
+	return &Assignment{
+		Match: match,
+		Cost:  cost,
+		U:     u,
+		V:     v,
+	}
+}
+
+// The Hungarian method with potentials for n<=m: the rows are added one
+// by one, each along the shortest augmenting path (in reduced costs)
+// from it to a free column. Returns the column of every row and the
+// potentials of the rows and the columns.
+func hungarian(n, m int, cost func(i, j int) float64) ([]int, []float64, []float64, error) {
+	infinity := math.Inf(1)
+
+	// 1-based, column 0 is the virtual start of the augmenting paths,
+	// rowOf[j] is the row assigned to column j (0 if it is free)
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	rowOf := make([]int, m+1)
+	way := make([]int, m+1)
+	minv := make([]float64, m+1)
+	used := make([]bool, m+1)
+
+	for i := 1; i <= n; i++ {
+		rowOf[0] = i
+		j0 := 0
+		for j := 0; j <= m; j++ {
+			minv[j] = infinity
+			used[j] = false
+		}
+		for {
+			used[j0] = true
+			i0, delta, j1 := rowOf[j0], infinity, -1
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := cost(i0-1, j-1) - u[i0] - v[j]; cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			if j1 == -1 {
+				return nil, nil, nil, fmt.Errorf("%w: row %v can't be assigned", ErrInfeasible, i-1)
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[rowOf[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if rowOf[j0] == 0 {
+				break
+			}
+		}
+		// flip the matching along the path
+		for j0 != 0 {
+			j1 := way[j0]
+			rowOf[j0] = rowOf[j1]
+			j0 = j1
+		}
+	}
+
+	colOf := make([]int, n)
+	for j := 1; j <= m; j++ {
+		if rowOf[j] > 0 {
+			colOf[rowOf[j]-1] = j - 1
+		}
+	}
+	return colOf, u[1:], v[1:], nil
+}
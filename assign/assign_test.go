@@ -0,0 +1,159 @@
+package assign
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// the minimal cost by trying every assignment of the rows (columns if
+// there are less of them)
+func bruteForce(costs [][]float64) float64 {
+	rows, cols := len(costs), len(costs[0])
+	best := math.Inf(1)
+	usedCols := make([]bool, cols)
+	usedRows := make([]bool, rows)
+	var walk func(k int, cost float64)
+	if rows <= cols {
+		walk = func(i int, cost float64) {
+			if i == rows {
+				best = math.Min(best, cost)
+				return
+			}
+			for j := 0; j < cols; j++ {
+				if !usedCols[j] {
+					usedCols[j] = true
+					walk(i+1, cost+costs[i][j])
+					usedCols[j] = false
+				}
+			}
+		}
+	} else {
+		walk = func(j int, cost float64) {
+			if j == cols {
+				best = math.Min(best, cost)
+				return
+			}
+			for i := 0; i < rows; i++ {
+				if !usedRows[i] {
+					usedRows[i] = true
+					walk(j+1, cost+costs[i][j])
+					usedRows[i] = false
+				}
+			}
+		}
+	}
+	walk(0, 0)
+	return best
+}
+
+func checkAssignment(costs [][]float64, a *Assignment) error {
+	rows, cols := len(costs), len(costs[0])
+	if len(a.Match) != rows || len(a.U) != rows || len(a.V) != cols {
+		return fmt.Errorf("wrong sizes: %v, %v, %v", len(a.Match), len(a.U), len(a.V))
+	}
+	taken := make([]bool, cols)
+	cnt, cost := 0, float64(0)
+	for i, j := range a.Match {
+		if j == -1 {
+			continue
+		}
+		if taken[j] {
+			return fmt.Errorf("column %v is assigned twice", j)
+		}
+		taken[j] = true
+		cnt += 1
+		cost += costs[i][j]
+		if d := costs[i][j] - a.U[i] - a.V[j]; math.Abs(d) > 1e-9 {
+			return fmt.Errorf("matched cell (%v,%v) has reduced cost %v", i, j, d)
+		}
+	}
+	want := rows
+	if cols < rows {
+		want = cols
+	}
+	if cnt != want {
+		return fmt.Errorf("%v cells are assigned, should be %v", cnt, want)
+	}
+	if math.Abs(cost-a.Cost) > 1e-9 {
+		return fmt.Errorf("cost %v != %v", a.Cost, cost)
+	}
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			if d := costs[i][j] - a.U[i] - a.V[j]; d < -1e-9 {
+				return fmt.Errorf("cell (%v,%v) has negative reduced cost %v", i, j, d)
+			}
+		}
+	}
+	return nil
+}
+
+func TestSolve(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	for k := 0; k < 200; k++ {
+		rows, cols := 1+r.Intn(6), 1+r.Intn(6)
+		costs := make([][]float64, rows)
+		for i := range costs {
+			costs[i] = make([]float64, cols)
+			for j := range costs[i] {
+				costs[i][j] = float64(r.Intn(20) - 5)
+				if r.Intn(8) == 0 {
+					costs[i][j] = math.Inf(1)
+				}
+			}
+		}
+		best := bruteForce(costs)
+		a, err := Solve(costs)
+		if math.IsInf(best, 1) {
+			if !errors.Is(err, ErrInfeasible) {
+				t.Error(fmt.Sprintf("%v: expect ErrInfeasible, got %v", costs, err))
+				return
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve %v", costs), err)
+			return
+		}
+		if err = checkAssignment(costs, a); err != nil {
+			t.Error(fmt.Sprintf("bad assignment of %v", costs), err)
+			return
+		}
+		if math.Abs(a.Cost-best) > 1e-9 {
+			t.Error(fmt.Sprintf("%v: cost %v, should be %v", costs, a.Cost, best))
+			return
+		}
+	}
+
+	// invalid inputs
+	for _, costs := range [][][]float64{
+		{},
+		{{}},
+		{{1, 2}, {3}},
+		{{1, math.NaN()}},
+		{{1, math.Inf(-1)}},
+	} {
+		if _, err := Solve(costs); err == nil {
+			t.Error(fmt.Sprintf("expect error for %v", costs))
+			return
+		}
+	}
+}
+
+func BenchmarkSolve(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	costs := make([][]float64, 300)
+	for i := range costs {
+		costs[i] = make([]float64, 300)
+		for j := range costs[i] {
+			costs[i][j] = float64(r.Intn(1000))
+		}
+	}
+	for n := 0; n < b.N; n++ {
+		if _, err := Solve(costs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+// Command tpsolve solves a transportation problem read from a file (or
+// stdin) with the tp package and prints the solution.
+//
+// Usage:
+//
+//	tpsolve [flags] [file]
+//
+// The problem is read from stdin if there is no file or it is "-". It
+// can be in the JSON or DIMACS format of tp.Instance, or CSV: the
+// supply on the first line, the demand on the second one and then a
+// line of costs for every producer, an empty cell or "inf" marks a
+// forbidden route. The format is found from the file extension (.json,
+// .csv, .dimacs/.min) or the content unless -format is given.
+//
+// It prints the status, the cost, the routes with flow, the shortages/
+// surpluses and the duals, as text or JSON (-output json).
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yizha/go/tp"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// route with flow in the output
+type route struct {
+	From   int     `json:"from"`
+	To     int     `json:"to"`
+	Amount float64 `json:"amount"`
+	Cost   float64 `json:"cost"`
+}
+
+// solution in the output
+type solution struct {
+	Name       string    `json:"name,omitempty"`
+	Status     tp.Status `json:"status"`
+	Cost       float64   `json:"cost"`
+	Iterations int       `json:"iterations"`
+	Flow       []route   `json:"flow"`
+	Shortages  []float64 `json:"shortages,omitempty"`
+	Surpluses  []float64 `json:"surpluses,omitempty"`
+	U          []float64 `json:"u"`
+	V          []float64 `json:"v"`
+}
+
+// returns the exit code: 0 if it is solved (even if it isn't optimal),
+// 1 if it fails to read or solve the problem and 2 for invalid flags
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tpsolve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: tpsolve [flags] [file]\n\nSolves the transportation problem in the file (stdin if there is none or it is \"-\").\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "auto", "input format: auto, json, csv or dimacs")
+	output := fs.String("output", "text", "output format: text or json")
+	maxIter := fs.Int("max-iter", 0, "max iterations to run, 0 means no limit")
+	epsilon := fs.Float64("epsilon", tp.EPSILON, "used to tell if a value is zero or not")
+	algorithm := fs.String("algorithm", tp.AlgoMODI.String(), "optimization method: "+names(algorithms()))
+	strategy := fs.String("init", tp.InitAuto.String(), "initial solution strategy: "+names(strategies()))
+	pivotRule := fs.String("pivot", tp.PivotDantzig.String(), "pivot rule: "+names(pivotRules()))
+	timeLimit := fs.Duration("time-limit", 0, "max time to spend on the optimization, 0 means no limit")
+	trace := fs.Bool("trace", false, "write what the solver does to stderr")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(stderr, "unknown output format: %v\n", *output)
+		return 2
+	}
+	opts := []tp.Option{tp.WithMaxIter(*maxIter), tp.WithEpsilon(*epsilon), tp.WithTimeLimit(*timeLimit)}
+	if a, ok := algorithms()[*algorithm]; ok {
+		opts = append(opts, tp.WithAlgorithm(a))
+	} else {
+		fmt.Fprintf(stderr, "unknown algorithm: %v\n", *algorithm)
+		return 2
+	}
+	if s, ok := strategies()[*strategy]; ok {
+		opts = append(opts, tp.WithInitialStrategy(s))
+	} else {
+		fmt.Fprintf(stderr, "unknown initial strategy: %v\n", *strategy)
+		return 2
+	}
+	if r, ok := pivotRules()[*pivotRule]; ok {
+		opts = append(opts, tp.WithPivotRule(r))
+	} else {
+		fmt.Fprintf(stderr, "unknown pivot rule: %v\n", *pivotRule)
+		return 2
+	}
+	if *trace {
+		opts = append(opts, tp.WithTrace(stderr))
+	}
+
+	path := fs.Arg(0)
+	var data []byte
+	var err error
+	if path == "" || path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	in, err := readInstance(data, *format, path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	p, err := in.NewProblem(opts...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	result, err := p.Solve()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	s := &solution{
+		Name:       in.Name,
+		Status:     result.Status,
+		Cost:       result.Objective,
+		Iterations: result.Iterations,
+		Flow:       make([]route, 0),
+	}
+	for i := range result.Flow {
+		for j, x := range result.Flow[i] {
+			if x != 0 {
+				s.Flow = append(s.Flow, route{From: i, To: j, Amount: x, Cost: in.Costs[i][j]})
+			}
+		}
+	}
+	if nonZero(result.Shortages) {
+		s.Shortages = result.Shortages
+	}
+	if nonZero(result.Surpluses) {
+		s.Surpluses = result.Surpluses
+	}
+	s.U, s.V = p.GetDuals()
+	if *output == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(s)
+	} else {
+		err = writeText(stdout, s)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func nonZero(x []float64) bool {
+	for _, v := range x {
+		if v != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func algorithms() map[string]tp.Algorithm {
+	m := make(map[string]tp.Algorithm)
+	for _, a := range []tp.Algorithm{tp.AlgoMODI, tp.AlgoNetworkSimplex} {
+		m[a.String()] = a
+	}
+	return m
+}
+
+func strategies() map[string]tp.InitialStrategy {
+	m := make(map[string]tp.InitialStrategy)
+	for _, s := range []tp.InitialStrategy{tp.InitLeastCost, tp.InitVogel, tp.InitNorthwestCorner, tp.InitRowMinimum, tp.InitAssignment, tp.InitAuto} {
+		m[s.String()] = s
+	}
+	return m
+}
+
+func pivotRules() map[string]tp.PivotRule {
+	m := make(map[string]tp.PivotRule)
+	for _, r := range []tp.PivotRule{tp.PivotDantzig, tp.PivotFirstEligible, tp.PivotBland, tp.PivotPerturbation, tp.PivotBlockSearch} {
+		m[r.String()] = r
+	}
+	return m
+}
+
+// the keys of the map, sorted and comma separated
+func names[T any](m map[string]T) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+// read the instance in the given format, found from the file extension
+// or the content if it is "auto"
+func readInstance(data []byte, format, path string) (*tp.Instance, error) {
+	if format == "auto" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			format = "json"
+		case ".csv":
+			format = "csv"
+		case ".dimacs", ".min":
+			format = "dimacs"
+		default:
+			format = detectFormat(data)
+		}
+	}
+	switch format {
+	case "json":
+		return tp.ReadJSON(bytes.NewReader(data))
+	case "dimacs":
+		return tp.ReadDIMACS(bytes.NewReader(data))
+	case "csv":
+		return readCSV(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unknown input format: %v", format)
+	}
+}
+
+// JSON starts with "{", DIMACS with a "c" or "p" line, CSV otherwise
+func detectFormat(data []byte) string {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "{") {
+			return "json"
+		}
+		if fields := strings.Fields(line); fields[0] == "c" || fields[0] == "p" {
+			return "dimacs"
+		}
+		break
+	}
+	return "csv"
+}
+
+// read the supply line, the demand line and the cost lines
+func readCSV(r io.Reader) (*tp.Instance, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 3 {
+		return nil, fmt.Errorf("CSV needs the supply, demand and cost lines, got %v line(s)", len(records))
+	}
+	parse := func(line int, record []string, forbidden bool) ([]float64, error) {
+		x := make([]float64, len(record))
+		for k, f := range record {
+			f = strings.TrimSpace(f)
+			if forbidden && (f == "" || strings.EqualFold(f, "inf")) {
+				x[k] = math.Inf(1)
+				continue
+			}
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid number %q", line, f)
+			}
+			x[k] = v
+		}
+		return x, nil
+	}
+	in := &tp.Instance{}
+	if in.Supply, err = parse(1, records[0], false); err != nil {
+		return nil, err
+	}
+	if in.Demand, err = parse(2, records[1], false); err != nil {
+		return nil, err
+	}
+	for k, record := range records[2:] {
+		costs, err := parse(k+3, record, true)
+		if err != nil {
+			return nil, err
+		}
+		in.Costs = append(in.Costs, costs)
+	}
+	return in, nil
+}
+
+func writeText(w io.Writer, s *solution) error {
+	bw := bufio.NewWriter(w)
+	if s.Name != "" {
+		fmt.Fprintf(bw, "problem:    %v\n", s.Name)
+	}
+	fmt.Fprintf(bw, "status:     %v\n", s.Status)
+	fmt.Fprintf(bw, "cost:       %v\n", s.Cost)
+	fmt.Fprintf(bw, "iterations: %v\n", s.Iterations)
+	fmt.Fprintf(bw, "flow:\n")
+	for _, r := range s.Flow {
+		fmt.Fprintf(bw, "  %v -> %v: %v (cost %v)\n", r.From, r.To, r.Amount, r.Cost)
+	}
+	for j, x := range s.Shortages {
+		if x != 0 {
+			fmt.Fprintf(bw, "shortage:   consumer %v: %v\n", j, x)
+		}
+	}
+	for i, x := range s.Surpluses {
+		if x != 0 {
+			fmt.Fprintf(bw, "surplus:    producer %v: %v\n", i, x)
+		}
+	}
+	fmt.Fprintf(bw, "duals:\n")
+	fmt.Fprintf(bw, "  u: %v\n", s.U)
+	fmt.Fprintf(bw, "  v: %v\n", s.V)
+	return bw.Flush()
+}
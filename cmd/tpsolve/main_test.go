@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// run tpsolve with the args and stdin, returns the exit code and output
+func runWith(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	// the same problem in all formats
+	csvText := "20, 10\n10, 12, 8\n4, 6, 5\ninf, 2, 3\n"
+	dimacsText := "c two plants and three stores\np min 5 5\nn 1 20\nn 2 10\nn 3 -10\nn 4 -12\nn 5 -8\n" +
+		"a 1 3 0 100 4\na 1 4 0 100 6\na 1 5 0 100 5\na 2 4 0 100 2\na 2 5 0 100 3\n"
+	jsonText := `{"supply": [20, 10], "demand": [10, 12, 8], "costs": [[4, 6, 5], [null, 2, 3]]}`
+	for _, c := range []struct {
+		name string
+		args []string
+		text string
+	}{
+		{"csv", []string{"-format", "csv"}, csvText},
+		{"dimacs", nil, dimacsText},
+		{"json", nil, jsonText},
+		{"network-simplex", []string{"-algorithm", "network-simplex", "-init", "vogel"}, jsonText},
+	} {
+		code, out, errText := runWith(append(c.args, "-output", "json"), c.text)
+		if code != 0 {
+			t.Error(fmt.Sprintf("[%v] exits with %v: %v", c.name, code, errText))
+			return
+		}
+		var s solution
+		if err := json.Unmarshal([]byte(out), &s); err != nil {
+			t.Error(fmt.Sprintf("[%v] failed to read the output %v", c.name, out), err)
+			return
+		}
+		// 10 from 0 to 0, 2 from 0 to 1, 8 from 0 to 2 and 10 from 1 to 1
+		if s.Status.String() != "optimal" || math.Abs(s.Cost-112) > 1e-9 {
+			t.Error(fmt.Sprintf("[%v] solution is %+v, should be optimal with cost 112", c.name, s))
+			return
+		}
+		// the flow is sparse and the duals price it
+		amount := 0.0
+		for _, r := range s.Flow {
+			amount += r.Amount
+			if r.Amount == 0 || math.Abs(s.U[r.From]+s.V[r.To]-r.Cost) > 1e-9 {
+				t.Error(fmt.Sprintf("[%v] route %+v, u %v, v %v", c.name, r, s.U, s.V))
+				return
+			}
+		}
+		if math.Abs(amount-30) > 1e-9 {
+			t.Error(fmt.Sprintf("[%v] ships %v, should be 30", c.name, amount))
+			return
+		}
+	}
+
+	// text output, the format is found from the extension
+	path := filepath.Join("..", "..", "tp", "testdata", "problem-00.json")
+	code, out, errText := runWith([]string{path}, "")
+	if code != 0 || !strings.Contains(out, "status:     optimal") || !strings.Contains(out, "duals:") {
+		t.Error(fmt.Sprintf("%v exits with %v: %v%v", path, code, out, errText))
+		return
+	}
+
+	// shortages are printed
+	code, out, _ = runWith([]string{"-format", "csv"}, "5\n3, 4\n1, 2\n")
+	if code != 0 || !strings.Contains(out, "shortage:   consumer 1: 2") {
+		t.Error(fmt.Sprintf("exits with %v, should print the shortage: %v", code, out))
+		return
+	}
+
+	// invalid flags and inputs
+	for _, c := range []struct {
+		args []string
+		text string
+		code int
+	}{
+		{[]string{"-algorithm", "simplex"}, jsonText, 2},
+		{[]string{"-init", "random"}, jsonText, 2},
+		{[]string{"-pivot", "random"}, jsonText, 2},
+		{[]string{"-output", "xml"}, jsonText, 2},
+		{[]string{"a.json", "b.json"}, jsonText, 2},
+		{[]string{"-format", "xml"}, jsonText, 1},
+		{[]string{"-format", "csv"}, "1\n1\n", 1},
+		{[]string{"-format", "csv"}, "1\n1\nx\n", 1},
+		{[]string{"-format", "csv"}, "1\n1\n1, 2\n", 1},
+		{nil, `{"supply": [1]`, 1},
+		{nil, "p min 2 1\nn 1 10\n", 1},
+		{[]string{"no-such-file.json"}, "", 1},
+		{nil, `{"supply": [1], "demand": [1], "costs": [[null]]}`, 1},
+	} {
+		if code, _, _ := runWith(c.args, c.text); code != c.code {
+			t.Error(fmt.Sprintf("%v with %q exits with %v, should be %v", c.args, c.text, code, c.code))
+			return
+		}
+	}
+}
@@ -0,0 +1,343 @@
+// Package mcf solves the min-cost flow problem on directed graphs with
+// lower bounds and capacities on the arcs, with the successive shortest
+// path method.
+package mcf
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// used to tell if a float64 value (flow, residual capacity, imbalance)
+// is zero or not
+const EPSILON = 1e-9
+
+// ErrInfeasible is returned (wrapped) by Solve() when the arcs (within
+// their bounds) can't carry the supply to the demand.
+var ErrInfeasible = errors.New("problem is infeasible")
+
+// ErrUnbounded is returned (wrapped) by Solve() when there is a cycle of
+// uncapacitated arcs with a negative total cost.
+var ErrUnbounded = errors.New("problem is unbounded")
+
+type arc struct {
+	from, to           int
+	lower, upper, cost float64
+}
+
+// Graph is a min-cost flow problem, nodes and arcs are added with
+// AddNode() and AddArc() and it is solved with Solve().
+type Graph struct {
+	supply []float64
+	arcs   []arc
+}
+
+// Create an empty graph.
+func NewGraph() *Graph {
+	return &Graph{
+		supply: make([]float64, 0),
+		arcs:   make([]arc, 0),
+	}
+}
+
+// Add a node with the given supply, positive for a source, negative for
+// a sink, 0 for a node which only passes the flow through.
+//
+//	returns the node index, starting from 0.
+func (g *Graph) AddNode(supply float64) int {
+	g.supply = append(g.supply, supply)
+	return len(g.supply) - 1
+}
+
+// Set the supply of the given node.
+func (g *Graph) SetSupply(node int, supply float64) {
+	g.supply[node] = supply
+}
+
+// Add an arc from node "from" to node "to", its flow must be within
+// [lower,upper] and costs "cost" per unit. upper is math.Inf(1) for an
+// uncapacitated arc. The nodes and the bounds are checked by Solve().
+//
+//	returns the arc index, starting from 0.
+func (g *Graph) AddArc(from, to int, lower, upper, cost float64) int {
+	g.arcs = append(g.arcs, arc{
+		from:  from,
+		to:    to,
+		lower: lower,
+		upper: upper,
+		cost:  cost,
+	})
+	return len(g.arcs) - 1
+}
+
+// Get the node count.
+func (g *Graph) NodeCount() int {
+	return len(g.supply)
+}
+
+// Get the arc count.
+func (g *Graph) ArcCount() int {
+	return len(g.arcs)
+}
+
+// Solution of a min-cost flow problem.
+type Solution struct {
+	// Flow[k] is the flow on arc k
+	Flow []float64
+
+	// total cost of the flow
+	Cost float64
+
+	// node potentials (dual values), the reduced cost
+	// cost+Potential[from]-Potential[to] of an arc is not negative if
+	// its flow is below the upper bound and not positive if it is above
+	// the lower bound
+	Potential []float64
+}
+
+func (g *Graph) validate() error {
+	n := len(g.supply)
+	if n < 1 {
+		return fmt.Errorf("not enough nodes, need at least 1!")
+	}
+	var sum, total float64
+	for k, x := range g.supply {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return fmt.Errorf("supply[%v]=%v is invalid!", k, x)
+		}
+		sum += x
+		total += math.Abs(x)
+	}
+	if math.Abs(sum) > EPSILON*math.Max(1, total) {
+		return fmt.Errorf("supply and demand are not balanced (%v)!", sum)
+	}
+	for k, a := range g.arcs {
+		if a.from < 0 || a.from >= n || a.to < 0 || a.to >= n {
+			return fmt.Errorf("arc %v (%v,%v) is out of range!", k, a.from, a.to)
+		}
+		if math.IsNaN(a.cost) || math.IsInf(a.cost, 0) {
+			return fmt.Errorf("arc %v (%v,%v) has invalid cost %v!", k, a.from, a.to, a.cost)
+		}
+		if math.IsNaN(a.lower) || math.IsInf(a.lower, 0) || math.IsNaN(a.upper) || math.IsInf(a.upper, -1) || a.upper < a.lower {
+			return fmt.Errorf("arc %v (%v,%v) has invalid bounds [%v,%v]!", k, a.from, a.to, a.lower, a.upper)
+		}
+	}
+	return nil
+}
+
+// residual network, edge 2k is arc k and edge 2k+1 its reverse
+type residual struct {
+	head []int // first edge out of a node, -1 if none
+	next []int // next edge out of the same node
+	to   []int
+	cap  []float64
+	cost []float64
+}
+
+func (r *residual) push(e int, x float64) {
+	r.cap[e] -= x
+	r.cap[e^1] += x
+}
+
+// Solve the problem, the flow on every arc is within its bounds, every
+// node ships out its supply (net) and the total cost is minimal.
+//
+//	returns the optimal Solution{}, or an error wrapping ErrInfeasible
+//	or ErrUnbounded.
+//
+// Lower bounds are shifted out and the arcs with a negative cost and a
+// finite capacity are saturated first, then the flow is sent from the
+// excess nodes to the deficit nodes along the shortest paths (Dijkstra
+// on the reduced costs) until they are balanced.
+func (g *Graph) Solve() (*Solution, error) {
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	n, m := len(g.supply), len(g.arcs)
+	r := &residual{
+		head: make([]int, n),
+		next: make([]int, 2*m),
+		to:   make([]int, 2*m),
+		cap:  make([]float64, 2*m),
+		cost: make([]float64, 2*m),
+	}
+	for v := 0; v < n; v++ {
+		r.head[v] = -1
+	}
+	excess := make([]float64, n)
+	copy(excess, g.supply)
+	for k, a := range g.arcs {
+		for _, e := range []int{2 * k, 2*k + 1} {
+			from, to := a.from, a.to
+			if e&1 == 1 {
+				from, to = to, from
+			}
+			r.to[e] = to
+			r.next[e] = r.head[from]
+			r.head[from] = e
+		}
+		r.cap[2*k] = a.upper - a.lower
+		r.cost[2*k], r.cost[2*k+1] = a.cost, -a.cost
+		excess[a.from] -= a.lower
+		excess[a.to] += a.lower
+		if a.cost < 0 && !math.IsInf(a.upper, 1) {
+			excess[a.from] -= r.cap[2*k]
+			excess[a.to] += r.cap[2*k]
+			r.push(2*k, r.cap[2*k])
+		}
+	}
+
+	pi, err := initialPotentials(r, n)
+	if err != nil {
+		return nil, err
+	}
+	if err = augment(r, pi, excess); err != nil {
+		return nil, err
+	}
+
+	s := &Solution{
+		Flow:      make([]float64, m),
+		Potential: make([]float64, n),
+	}
+	for k, a := range g.arcs {
+		s.Flow[k] = a.lower + r.cap[2*k+1]
+		s.Cost += s.Flow[k] * a.cost
+	}
+	copy(s.Potential, pi)
+	return s, nil
+}
+
+// Potentials on which the reduced cost of every residual edge is not
+// negative, with Bellman-Ford from a virtual node linked to every node.
+// Only the uncapacitated arcs can have a negative cost here, a negative
+// cycle of them makes the problem unbounded.
+func initialPotentials(r *residual, n int) ([]float64, error) {
+	pi := make([]float64, n)
+	for round := 0; round <= n; round++ {
+		changed := false
+		for v := 0; v < n; v++ {
+			for e := r.head[v]; e != -1; e = r.next[e] {
+				if r.cap[e] <= EPSILON {
+					continue
+				}
+				if w := r.to[e]; pi[v]+r.cost[e] < pi[w]-EPSILON {
+					pi[w] = pi[v] + r.cost[e]
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			return pi, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: negative cost cycle of uncapacitated arcs", ErrUnbounded)
+}
+
+// Send the excess to the deficit along the shortest paths, all the
+// excess nodes are sources of the same Dijkstra run which stops at the
+// nearest deficit node. The potentials keep the reduced costs not
+// negative and the edges on the path at 0.
+func augment(r *residual, pi, excess []float64) error {
+	n := len(excess)
+	dist := make([]float64, n)
+	pred := make([]int, n)
+	done := make([]bool, n)
+	for {
+		q := &nodeQueue{}
+		for v := 0; v < n; v++ {
+			dist[v] = math.Inf(1)
+			pred[v] = -1
+			done[v] = false
+			if excess[v] > EPSILON {
+				dist[v] = 0
+				heap.Push(q, nodeDist{v, 0})
+			}
+		}
+		if q.Len() == 0 {
+			break
+		}
+		t := -1
+		for q.Len() > 0 {
+			nd := heap.Pop(q).(nodeDist)
+			v := nd.node
+			if done[v] || nd.dist > dist[v] {
+				continue
+			}
+			done[v] = true
+			if excess[v] < -EPSILON {
+				t = v
+				break
+			}
+			for e := r.head[v]; e != -1; e = r.next[e] {
+				if r.cap[e] <= EPSILON {
+					continue
+				}
+				w := r.to[e]
+				rc := r.cost[e] + pi[v] - pi[w]
+				if rc < 0 {
+					// rounding error
+					rc = 0
+				}
+				if d := dist[v] + rc; d < dist[w] {
+					dist[w] = d
+					pred[w] = e
+					heap.Push(q, nodeDist{w, d})
+				}
+			}
+		}
+		if t == -1 {
+			return fmt.Errorf("%w: %v left at the sources can't reach the sinks", ErrInfeasible, sumOver(excess, EPSILON))
+		}
+		for v := 0; v < n; v++ {
+			if done[v] {
+				pi[v] += dist[v] - dist[t]
+			}
+		}
+
+		// the bottleneck of the path from an excess node to t
+		x := -excess[t]
+		v := t
+		for pred[v] != -1 {
+			x = math.Min(x, r.cap[pred[v]])
+			v = r.to[pred[v]^1]
+		}
+		x = math.Min(x, excess[v])
+		excess[v] -= x
+		excess[t] += x
+		for v = t; pred[v] != -1; v = r.to[pred[v]^1] {
+			r.push(pred[v], x)
+		}
+	}
+	return nil
+}
+
+func sumOver(x []float64, threshold float64) float64 {
+	sum := float64(0)
+	for _, v := range x {
+		if v > threshold {
+			sum += v
+		}
+	}
+	return sum
+}
+
+type nodeDist struct {
+	node int
+	dist float64
+}
+
+// min-heap of the nodes by their tentative distance
+type nodeQueue []nodeDist
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(nodeDist)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,272 @@
+package mcf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/yizha/go/tp"
+)
+
+// the flow is within the bounds, keeps the balance of every node and
+// the potentials prove it is optimal
+func checkSolution(g *Graph, s *Solution) error {
+	net := make([]float64, g.NodeCount())
+	cost := float64(0)
+	for k, a := range g.arcs {
+		x := s.Flow[k]
+		if x < a.lower-1e-6 || x > a.upper+1e-6 {
+			return fmt.Errorf("arc %v flow %v is out of [%v,%v]", k, x, a.lower, a.upper)
+		}
+		net[a.from] += x
+		net[a.to] -= x
+		cost += x * a.cost
+		rc := a.cost + s.Potential[a.from] - s.Potential[a.to]
+		if (x < a.upper-1e-6 && rc < -1e-6) || (x > a.lower+1e-6 && rc > 1e-6) {
+			return fmt.Errorf("arc %v flow %v in [%v,%v] has reduced cost %v", k, x, a.lower, a.upper, rc)
+		}
+	}
+	for v, x := range g.supply {
+		if math.Abs(net[v]-x) > 1e-6 {
+			return fmt.Errorf("node %v ships %v, supply is %v", v, net[v], x)
+		}
+	}
+	if math.Abs(cost-s.Cost) > 1e-6*math.Max(1, math.Abs(cost)) {
+		return fmt.Errorf("cost %v != %v", s.Cost, cost)
+	}
+	return nil
+}
+
+// balanced transportation problem, some routes are forbidden
+func randomTransportation(r *rand.Rand, sLen, dLen int) ([]float64, []float64, [][]float64) {
+	supply, demand := make([]float64, sLen), make([]float64, dLen)
+	total := float64(0)
+	for i := range supply {
+		supply[i] = float64(1 + r.Intn(100))
+		total += supply[i]
+	}
+	left := total
+	for j := 0; j < dLen-1; j++ {
+		demand[j] = math.Max(1, math.Floor(total/float64(dLen)*(0.5+r.Float64())))
+		left -= demand[j]
+	}
+	demand[dLen-1] = left
+	if left <= 0 {
+		demand[dLen-1] = 1
+		supply[0] += 1 - left
+	}
+	costs := make([][]float64, sLen)
+	for i := range costs {
+		costs[i] = make([]float64, dLen)
+		for j := range costs[i] {
+			costs[i][j] = float64(r.Intn(50))
+			if r.Intn(10) == 0 {
+				costs[i][j] = math.Inf(1)
+			}
+		}
+	}
+	return supply, demand, costs
+}
+
+func TestTransportation(t *testing.T) {
+	r := rand.New(rand.NewSource(15))
+	for k := 0; k < 40; k++ {
+		sLen, dLen := 2+r.Intn(15), 2+r.Intn(15)
+		supply, demand, costs := randomTransportation(r, sLen, dLen)
+		var caps [][]float64
+		if k%2 == 1 {
+			caps = make([][]float64, sLen)
+			for i := range caps {
+				caps[i] = make([]float64, dLen)
+				for j := range caps[i] {
+					caps[i][j] = float64(r.Intn(60))
+				}
+			}
+		}
+		name := fmt.Sprintf("%vx%v capacitated=%v", sLen, dLen, caps != nil)
+
+		g := NewGraph()
+		for _, x := range supply {
+			g.AddNode(x)
+		}
+		for _, x := range demand {
+			g.AddNode(-x)
+		}
+		for i := range costs {
+			for j, c := range costs[i] {
+				if math.IsInf(c, 1) {
+					continue
+				}
+				upper := math.Inf(1)
+				if caps != nil {
+					upper = caps[i][j]
+				}
+				g.AddArc(i, sLen+j, 0, upper, c)
+			}
+		}
+		var p *tp.Problem
+		var err error
+		if caps != nil {
+			p, err = tp.NewCapacitatedProblem(supply, demand, costs, caps, tp.WithMaxIter(0))
+		} else {
+			p, err = tp.NewProblem(supply, demand, costs, tp.WithMaxIter(0))
+		}
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to create [%v] in tp", name), err)
+			return
+		}
+		result, tpErr := p.Solve()
+		s, err := g.Solve()
+		if tpErr != nil {
+			if !errors.Is(tpErr, tp.ErrInfeasible) || !errors.Is(err, ErrInfeasible) {
+				t.Error(fmt.Sprintf("[%v]: expect ErrInfeasible, got %v and %v", name, err, tpErr))
+				return
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve [%v]", name), err)
+			return
+		}
+		if err = checkSolution(g, s); err != nil {
+			t.Error(fmt.Sprintf("bad solution of [%v]", name), err)
+			return
+		}
+		if math.Abs(s.Cost-result.Objective) > 1e-6 {
+			t.Error(fmt.Sprintf("[%v]: cost %v, tp's is %v", name, s.Cost, result.Objective))
+			return
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	// staffing: a shift needs between 2 and 5 regular people, the
+	// overtime arc is cheaper than hiring outside but can only take 1
+	g := NewGraph()
+	pool := g.AddNode(6)
+	shift := g.AddNode(0)
+	done := g.AddNode(-6)
+	regular := g.AddArc(pool, shift, 2, 5, 10)
+	overtime := g.AddArc(pool, shift, 0, 1, 4)
+	g.AddArc(shift, done, 0, math.Inf(1), 0)
+	g.AddArc(pool, done, 0, math.Inf(1), 7)
+	s, err := g.Solve()
+	if err != nil {
+		t.Error("failed to solve the staffing problem", err)
+		return
+	}
+	if err = checkSolution(g, s); err != nil || s.Flow[regular] != 2 || s.Flow[overtime] != 1 || s.Cost != 45 {
+		t.Error(fmt.Sprintf("staffing problem: %+v", s), err)
+		return
+	}
+
+	r := rand.New(rand.NewSource(16))
+	for k := 0; k < 200; k++ {
+		n := 2 + r.Intn(12)
+		g := NewGraph()
+		for v := 0; v < n; v++ {
+			g.AddNode(0)
+		}
+		arcCnt := n + r.Intn(4*n)
+		for a := 0; a < arcCnt; a++ {
+			from, to := r.Intn(n), r.Intn(n)
+			lower := float64(0)
+			if r.Intn(4) == 0 {
+				lower = float64(r.Intn(5))
+			}
+			upper := lower + float64(r.Intn(20))
+			if r.Intn(4) == 0 {
+				upper = math.Inf(1)
+			}
+			cost := float64(r.Intn(30) - 5)
+			if math.IsInf(upper, 1) && cost < 0 {
+				// a negative uncapacitated cycle may make it unbounded
+				cost = -cost
+			}
+			g.AddArc(from, to, lower, upper, cost)
+		}
+		total := float64(0)
+		for v := 0; v < n-1; v++ {
+			x := float64(r.Intn(21) - 10)
+			g.SetSupply(v, x)
+			total += x
+		}
+		g.SetSupply(n-1, -total)
+		name := fmt.Sprintf("random %v nodes %v arcs", n, arcCnt)
+
+		s, err := g.Solve()
+		if errors.Is(err, ErrInfeasible) {
+			continue
+		}
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve [%v]", name), err)
+			return
+		}
+		if err = checkSolution(g, s); err != nil {
+			t.Error(fmt.Sprintf("bad solution of [%v]", name), err)
+			return
+		}
+	}
+
+	// not enough capacity, or a lower bound which can't be met
+	g = NewGraph()
+	g.AddNode(5)
+	g.AddNode(-5)
+	g.AddArc(0, 1, 0, 3, 1)
+	if _, err = g.Solve(); !errors.Is(err, ErrInfeasible) {
+		t.Error("expect ErrInfeasible for not enough capacity, got", err)
+		return
+	}
+	g.AddArc(1, 0, 4, 4, 1)
+	g.AddArc(0, 1, 0, 10, 1)
+	if _, err = g.Solve(); err != nil {
+		t.Error("failed to solve with a lower bound", err)
+		return
+	}
+	g.AddArc(1, 0, 20, 20, 1)
+	if _, err = g.Solve(); !errors.Is(err, ErrInfeasible) {
+		t.Error("expect ErrInfeasible for a lower bound, got", err)
+		return
+	}
+
+	// a negative cycle of uncapacitated arcs
+	g = NewGraph()
+	g.AddNode(1)
+	g.AddNode(-1)
+	g.AddArc(0, 1, 0, math.Inf(1), -2)
+	g.AddArc(1, 0, 0, math.Inf(1), 1)
+	if _, err = g.Solve(); !errors.Is(err, ErrUnbounded) {
+		t.Error("expect ErrUnbounded, got", err)
+		return
+	}
+
+	// invalid inputs
+	for _, c := range []struct {
+		supply []float64
+		arc    arc
+	}{
+		{[]float64{1, -2}, arc{0, 1, 0, 1, 1}},
+		{[]float64{math.NaN(), 0}, arc{0, 1, 0, 1, 1}},
+		{[]float64{1, -1}, arc{0, 2, 0, 1, 1}},
+		{[]float64{1, -1}, arc{0, 1, 0, 1, math.NaN()}},
+		{[]float64{1, -1}, arc{0, 1, 0, 1, math.Inf(1)}},
+		{[]float64{1, -1}, arc{0, 1, 2, 1, 1}},
+		{[]float64{1, -1}, arc{0, 1, math.Inf(-1), 1, 1}},
+	} {
+		g := NewGraph()
+		for _, x := range c.supply {
+			g.AddNode(x)
+		}
+		g.AddArc(c.arc.from, c.arc.to, c.arc.lower, c.arc.upper, c.arc.cost)
+		if _, err := g.Solve(); err == nil {
+			t.Error(fmt.Sprintf("expect error for %v, %+v", c.supply, c.arc))
+			return
+		}
+	}
+	if _, err := NewGraph().Solve(); err == nil {
+		t.Error("expect error for an empty graph")
+		return
+	}
+}
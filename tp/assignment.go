@@ -0,0 +1,116 @@
+package tp
+
+import (
+	"math"
+
+	"github.com/yizha/go/assign"
+)
+
+// tells if all supplies and demands are the same amount (within
+// epsilon), the problem is an assignment problem then
+func isAssignment(s, d []float64, epsilon float64) bool {
+	for _, x := range s {
+		if math.Abs(x-s[0]) > epsilon {
+			return false
+		}
+	}
+	for _, x := range d {
+		if math.Abs(x-s[0]) > epsilon {
+			return false
+		}
+	}
+	return true
+}
+
+// Find the initial solution with the assignment solver. Every matched
+// cell takes the whole amount of its row and column, the rows (columns)
+// left out of the matching ship to (get from) the dummy. Such a solution
+// is extremely degenerate, so it is completed into a basis here, with
+// the cells on which the duals of the matching are tight first. Falls
+// back to the "Least Cost" method if the inputs aren't assignment-shaped
+// or the forbidden routes leave no complete matching.
+func (es *Problem) findAssignmentSolution(s, d []float64) int {
+	sLen, dLen := es.inputSize()
+	if !isAssignment(es.supply[:sLen], es.demand[:dLen], es.epsilon) {
+		es.strategy = InitLeastCost
+		return es.findLeastCostSolution(s, d)
+	}
+	costs := make([][]float64, sLen)
+	for i := 0; i < sLen; i++ {
+		costs[i] = make([]float64, dLen)
+		for j := 0; j < dLen; j++ {
+			costs[i][j] = es.costMatrix[i][j]
+			if es.isForbidden(i, j) {
+				costs[i][j] = es.infinity
+			}
+		}
+	}
+	a, err := assign.Solve(costs)
+	if err != nil {
+		es.strategy = InitLeastCost
+		return es.findLeastCostSolution(s, d)
+	}
+
+	amount := es.supply[0]
+	matched := make([]bool, dLen)
+	for i, j := range a.Match {
+		if j == -1 {
+			// more rows than columns, to the dummy consumer
+			j = es.dLen - 1
+		} else {
+			matched[j] = true
+		}
+		fc := &es.flow[i][j]
+		fc.basic = true
+		fc.value = amount
+	}
+	for j := 0; j < dLen; j++ {
+		if !matched[j] && sLen < dLen {
+			// more columns than rows, from the dummy producer
+			fc := &es.flow[es.sLen-1][j]
+			fc.basic = true
+			fc.value = amount
+		}
+	}
+
+	// the tight cells keep the duals of the matching (as far as they
+	// reach), so the optimization has less to do
+	uf := es.nodeBuf
+	for k := range uf {
+		uf[k] = k
+	}
+	for i := 0; i < es.sLen; i++ {
+		for j := 0; j < es.dLen; j++ {
+			if es.flow[i][j].basic {
+				uf[findRoot(uf, i)] = findRoot(uf, es.sLen+j)
+			}
+		}
+	}
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			fc := &es.flow[i][j]
+			if fc.basic || es.isForbidden(i, j) || math.Abs(costs[i][j]-a.U[i]-a.V[j]) > es.epsilon {
+				continue
+			}
+			ri, rj := findRoot(uf, i), findRoot(uf, es.sLen+j)
+			if ri == rj {
+				continue
+			}
+			uf[ri] = rj
+			fc.basic = true
+			fc.value = 0
+		}
+	}
+
+	// a matching never makes a loop, completeBasis() can't fail
+	es.completeBasis()
+	flowCnt := 0
+	for i := 0; i < es.sLen; i++ {
+		for j := 0; j < es.dLen; j++ {
+			if es.flow[i][j].basic {
+				flowCnt += 1
+			}
+		}
+	}
+	return flowCnt
+}
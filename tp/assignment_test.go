@@ -0,0 +1,118 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// supplies and demands of the same amount, some routes are forbidden
+func randomAssignment(r *rand.Rand, sLen, dLen int, amount float64) *TestProblem {
+	tp := randomProblem(r, sLen, dLen)
+	tp.name = fmt.Sprintf("assignment %vx%v", sLen, dLen)
+	for i := range tp.supply {
+		tp.supply[i] = amount
+		for j := range tp.costs[i] {
+			if r.Intn(10) == 0 {
+				tp.costs[i][j] = math.Inf(1)
+			}
+		}
+	}
+	for j := range tp.demand {
+		tp.demand[j] = amount
+	}
+	return tp
+}
+
+func TestAssignment(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	for k := 0; k < 20; k++ {
+		n := 5 + r.Intn(25)
+		m := n
+		if k%4 == 3 {
+			m = 5 + r.Intn(25)
+		}
+		tp := randomAssignment(r, n, m, float64(1+k%3))
+		for _, algorithm := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+			p, err := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algorithm), WithMaxIter(0))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to create [%v]", tp.name), err)
+				return
+			}
+			if p.GetInitialStrategy() != InitAssignment {
+				t.Error(fmt.Sprintf("[%v] is not detected as an assignment problem", tp.name))
+				return
+			}
+			result, err := p.Solve()
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to solve [%v] with %v", tp.name, algorithm), err)
+				return
+			}
+			q, _ := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algorithm), WithInitialStrategy(InitVogel), WithMaxIter(0))
+			other, err := q.Solve()
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to solve [%v] with %v from %v", tp.name, algorithm, InitVogel), err)
+				return
+			}
+			if !result.Optimal() || math.Abs(result.Objective-other.Objective) > EPSILON {
+				t.Error(fmt.Sprintf("[%v] %v: %+v, from %v: %+v", tp.name, algorithm, result, InitVogel, other))
+				return
+			}
+			if algorithm == AlgoNetworkSimplex {
+				if err = checkTree(p); err != nil {
+					t.Error(fmt.Sprintf("bad basis tree for [%v]", tp.name), err)
+					return
+				}
+			}
+
+			// every row (column) ships its whole amount on one route
+			flow := p.GetFlow()
+			for i := range flow {
+				for j := range flow[i] {
+					if f := flow[i][j]; f > EPSILON && math.Abs(f-tp.supply[0]) > EPSILON {
+						t.Error(fmt.Sprintf("[%v] %v: flow[%v][%v]=%v", tp.name, algorithm, i, j, f))
+						return
+					}
+				}
+			}
+		}
+	}
+
+	// other inputs keep their strategy, or fall back to least cost if
+	// the assignment solver is asked for
+	tp := testData[0]
+	p, _ := NewProblem(tp.supply, tp.demand, tp.costs)
+	if p.GetInitialStrategy() != InitLeastCost {
+		t.Error(fmt.Sprintf("[%v] initial strategy is %v", tp.name, p.GetInitialStrategy()))
+		return
+	}
+	p, _ = NewProblem(tp.supply, tp.demand, tp.costs, WithInitialStrategy(InitAssignment))
+	if _, err := p.Solve(); err != nil || p.GetInitialStrategy() != InitLeastCost {
+		t.Error(fmt.Sprintf("[%v] initial strategy is %v", tp.name, p.GetInitialStrategy()), err)
+		return
+	}
+	tp = randomAssignment(r, 5, 5, 1)
+	for _, strategy := range []InitialStrategy{InitLeastCost, InitNorthwestCorner} {
+		p, _ = NewProblem(tp.supply, tp.demand, tp.costs, WithInitialStrategy(strategy))
+		if p.GetInitialStrategy() != strategy {
+			t.Error(fmt.Sprintf("[%v] initial strategy is %v, should be %v", tp.name, p.GetInitialStrategy(), strategy))
+			return
+		}
+	}
+}
+
+func BenchmarkAssignment(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	tp := randomAssignment(r, 200, 200, 1)
+	for _, strategy := range []InitialStrategy{InitAssignment, InitVogel} {
+		b.Run(strategy.String(), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				p, _ := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(AlgoNetworkSimplex), WithInitialStrategy(strategy), WithMaxIter(0))
+				if _, err := p.Solve(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package tp
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// BatchResult is the result of one problem of a batch.
+type BatchResult struct {
+	// position of the problem in the batch (or in the stream)
+	Index int
+
+	// nil if the problem can't be created or solved, Err tells why
+	Result *Result
+	Err    error
+}
+
+// count of workers to use, GOMAXPROCS if it isn't positive, but no more
+// than the problems if their count is known (n >= 0)
+func batchWorkers(workers, n int) int {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if n >= 0 && workers > n {
+		workers = n
+	}
+	return workers
+}
+
+// reset es to the k-th problem and solve it, problems not started
+// before ctx is done get its error
+func solveBatched(ctx context.Context, es *Problem, k int, in *Instance, opts []Option) BatchResult {
+	if err := ctx.Err(); err != nil {
+		return BatchResult{Index: k, Err: err}
+	}
+	if in == nil {
+		return BatchResult{Index: k, Err: fmt.Errorf("problem #%v is nil!", k)}
+	}
+	if err := in.reset(es, opts...); err != nil {
+		return BatchResult{Index: k, Err: err}
+	}
+	result, err := es.SolveContext(ctx)
+	return BatchResult{Index: k, Result: result, Err: err}
+}
+
+// Solve the problems on a pool of workers, the results are in the same
+// order as the problems.
+//
+//	ctx: the problems being solved when it is done stop like with
+//	     SolveContext(), the ones not started yet get ctx.Err().
+//	workers: count of goroutines solving the problems, GOMAXPROCS if it
+//	         is not positive.
+//	opts: options of every problem, the trace writer and the observer
+//	      (if any) are called from all workers.
+//
+// Every worker resets one Problem to the problems it takes (see
+// Problem.Reset()), the buffers of a problem are reused by the next one.
+func SolveBatch(ctx context.Context, problems []*Instance, workers int, opts ...Option) []BatchResult {
+	results := make([]BatchResult, len(problems))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < batchWorkers(workers, len(problems)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			es := &Problem{}
+			for k := range next {
+				results[k] = solveBatched(ctx, es, k, problems[k], opts)
+			}
+		}()
+	}
+	for k := range problems {
+		next <- k
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// Solve the problems received from the channel on a pool of workers like
+// SolveBatch(), the results are sent to the returned channel in the same
+// order as the problems. It is closed after the result of the last
+// problem, when the problems channel is closed or ctx is done (the
+// problems still in the channel are not taken then), it has to be read
+// until it is closed.
+//
+// At most 2*workers problems are taken before their results are read, so
+// one slow problem doesn't keep many results waiting for it.
+func SolveStream(ctx context.Context, problems <-chan *Instance, workers int, opts ...Option) <-chan BatchResult {
+	workers = batchWorkers(workers, -1)
+	type job struct {
+		k  int
+		in *Instance
+	}
+	jobs := make(chan job)
+	solved := make(chan BatchResult, workers)
+	out := make(chan BatchResult, workers)
+	// a token is taken for every problem and given back when its result
+	// is sent out
+	tokens := make(chan struct{}, 2*workers)
+
+	go func() {
+		defer close(jobs)
+		for k := 0; ; k++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case in, ok := <-problems:
+				if !ok {
+					return
+				}
+				jobs <- job{k, in}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			es := &Problem{}
+			for j := range jobs {
+				solved <- solveBatched(ctx, es, j.k, j.in, opts)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(solved)
+	}()
+
+	// put the results back in order
+	go func() {
+		defer close(out)
+		pending := make(map[int]BatchResult)
+		next := 0
+		for r := range solved {
+			pending[r.Index] = r
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				out <- r
+				<-tokens
+				next++
+			}
+		}
+	}()
+	return out
+}
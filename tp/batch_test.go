@@ -0,0 +1,152 @@
+package tp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// the results match the ones of the problems solved one by one
+func checkBatch(problems []*Instance, results []BatchResult, opts ...Option) error {
+	if len(results) != len(problems) {
+		return fmt.Errorf("%v results for %v problems", len(results), len(problems))
+	}
+	for k, in := range problems {
+		r := results[k]
+		if r.Index != k {
+			return fmt.Errorf("result #%v has index %v", k, r.Index)
+		}
+		if in == nil {
+			if r.Err == nil {
+				return fmt.Errorf("no error for nil problem #%v", k)
+			}
+			continue
+		}
+		var expected *Result
+		p, err := in.NewProblem(opts...)
+		if err == nil {
+			expected, err = p.Solve()
+		}
+		if (err == nil) != (r.Err == nil) || (err == nil && r.Result == nil) {
+			return fmt.Errorf("[%v] batch error: %v, should be %v", in.Name, r.Err, err)
+		}
+		if err != nil {
+			continue
+		}
+		if r.Result.Objective != expected.Objective || !reflect.DeepEqual(r.Result.Flow, expected.Flow) {
+			return fmt.Errorf("[%v] batch result %+v, should be %+v", in.Name, r.Result, expected)
+		}
+	}
+	return nil
+}
+
+func TestSolveBatch(t *testing.T) {
+	r := rand.New(rand.NewSource(24))
+	problems := make([]*Instance, 200)
+	for k := range problems {
+		problems[k] = randomInstance(r)
+	}
+	// a nil one and an invalid one
+	problems[7] = nil
+	problems[8] = &Instance{Name: "invalid", Supply: []float64{1}, Demand: []float64{1, 2}, Costs: [][]float64{{1}}}
+	opts := []Option{WithAlgorithm(AlgoNetworkSimplex), WithMaxIter(0)}
+
+	for _, workers := range []int{0, 1, 4} {
+		results := SolveBatch(context.Background(), problems, workers, opts...)
+		if err := checkBatch(problems, results, opts...); err != nil {
+			t.Error(fmt.Sprintf("SolveBatch() with %v workers", workers), err)
+			return
+		}
+
+		ch := make(chan *Instance)
+		go func() {
+			for _, in := range problems {
+				ch <- in
+			}
+			close(ch)
+		}()
+		results = results[:0]
+		for r := range SolveStream(context.Background(), ch, workers, opts...) {
+			results = append(results, r)
+		}
+		if err := checkBatch(problems, results, opts...); err != nil {
+			t.Error(fmt.Sprintf("SolveStream() with %v workers", workers), err)
+			return
+		}
+	}
+	if results := SolveBatch(context.Background(), nil, 4); len(results) != 0 {
+		t.Error("results of no problem:", results)
+		return
+	}
+
+	// nothing is solved after the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for k, r := range SolveBatch(ctx, problems, 4, opts...) {
+		if r.Index != k || r.Result != nil || r.Err != context.Canceled {
+			t.Error(fmt.Sprintf("result #%v after cancel: %+v", k, r))
+			return
+		}
+	}
+	ch := make(chan *Instance)
+	cnt := 0
+	for r := range SolveStream(ctx, ch, 4, opts...) {
+		if r.Err != context.Canceled {
+			t.Error(fmt.Sprintf("stream result after cancel: %+v", r))
+			return
+		}
+		cnt++
+	}
+	if cnt != 0 {
+		t.Error(fmt.Sprintf("stream takes %v problems after cancel", cnt))
+		return
+	}
+
+	// the stream stops taking problems when the context is done, the
+	// ones it has taken get their results in order
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	ch = make(chan *Instance)
+	go func() {
+		for _, in := range problems {
+			select {
+			case ch <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	cnt = 0
+	for r := range SolveStream(ctx, ch, 2, opts...) {
+		if r.Index != cnt {
+			t.Error(fmt.Sprintf("result #%v has index %v", cnt, r.Index))
+			return
+		}
+		if cnt++; cnt == 10 {
+			cancel()
+		}
+	}
+	if cnt < 10 || cnt > 10+4 {
+		t.Error(fmt.Sprintf("stream gets %v results after cancel at 10", cnt))
+		return
+	}
+}
+
+func BenchmarkSolveBatch(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	problems := make([]*Instance, 256)
+	for k := range problems {
+		tp := randomProblem(r, 20+r.Intn(10), 20+r.Intn(10))
+		problems[k] = &Instance{Supply: tp.supply, Demand: tp.demand, Costs: tp.costs}
+	}
+	for name, workers := range map[string]int{"one-worker": 1, "gomaxprocs": 0} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				SolveBatch(context.Background(), problems, workers, WithMaxIter(0))
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+)
+
+// Create a capacitated transportation problem, the flow on route (i,j)
+// can't be more than capacities[i][j].
+//
+//	supply, demand: positive float64 array/slice.
+//	costs: 2-D matrix, same as NewProblem().
+//	capacities: 2-D matrix of the same size as costs, not negative,
+//	            math.Inf(1) means the route is not capacitated.
+//	opts: optional args, see DefaultOptions() for the default values
+//	      but MaxIter, which is 0 (no limit) by default.
+//
+//	returns the Problem{} struct.
+//
+// It is always solved with the network simplex method. The initial
+// solution (InitArtificial) is the northwest corner one within the
+// capacities, the supply/demand it can't place goes on the big-M routes
+// of an artificial producer/consumer and the optimization moves it off
+// them. Solve() returns an error wrapping ErrInfeasible if the
+// capacities can't carry the supply/demand. If it stops at a limit (or
+// the context is done) with some flow still on the big-M routes there
+// is no feasible plan to return and it returns an error too, it doesn't
+// happen if the initial solution fits in the capacities.
+func NewCapacitatedProblem(supply, demand []float64, costs, capacities [][]float64, opts ...Option) (*Problem, error) {
+	es := &Problem{}
+	if err := es.ResetCapacitated(supply, demand, costs, capacities, opts...); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// Reset the problem to new capacitated inputs, same args as
+// NewCapacitatedProblem() and same as Reset() otherwise.
+func (es *Problem) ResetCapacitated(supply, demand []float64, costs, capacities [][]float64, opts ...Option) error {
+	o := DefaultOptions()
+	o.MaxIter = 0
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(); err != nil {
+		return err
+	}
+	sLen, dLen := len(supply), len(demand)
+	if sLen != len(capacities) {
+		return fmt.Errorf("producer count doesn't match 1st dimension length of capacities!")
+	}
+	for i := 0; i < sLen; i++ {
+		if dLen != len(capacities[i]) {
+			return fmt.Errorf("consumer count doesn't match 2nd dimension length of capacities!")
+		}
+		for j := 0; j < dLen; j++ {
+			if x := capacities[i][j]; math.IsNaN(x) || x < 0 {
+				return fmt.Errorf("capacities[%v][%v]=%v is invalid!", i, j, x)
+			}
+		}
+	}
+	o.Algorithm = AlgoNetworkSimplex
+	o.InitialStrategy = InitArtificial
+	return es.init(supply, demand, costs, capacities, o)
+}
+
+// copy the capacities, the routes of the dummy and artificial producer/
+// consumer are not capacitated
+func (es *Problem) setCapacities(caps [][]float64) {
+	es.capacityCells, es.capacity = reshape(es.capacityCells, es.capacity, es.sLen, es.dLen)
+	for i := 0; i < es.sLen; i++ {
+		for j := 0; j < es.dLen; j++ {
+			if i < es.nRows && j < es.nCols {
+				es.capacity[i][j] = caps[i][j]
+			} else {
+				es.capacity[i][j] = es.infinity
+			}
+		}
+	}
+}
+
+// returns the capacity of route (i,j), +Inf if it is not capacitated
+func (es *Problem) capacityOf(i, j int) float64 {
+	if es.capacity == nil {
+		return es.infinity
+	}
+	return es.capacity[i][j]
+}
+
+// Find the initial solution of a capacitated problem: the northwest
+// corner method ships what the capacities let it on the routes, a route
+// left at its capacity is not basic, and the supply/demand it can't
+// place goes on the artificial routes. Without tight capacities it is a
+// feasible solution, so a stopped optimization has a plan to return.
+// The basic cells make a spanning tree, if the artificial routes which
+// carry something would close a loop all the flow starts on them.
+func (es *Problem) findArtificialSolution(s, d []float64) int {
+	ar, ac := es.sLen-1, es.dLen-1
+	epsilon := es.epsilon
+	shipped := float64(0)
+	i, j := 0, 0
+	for i < ar && j < ac {
+		x := float64(0)
+		if !es.isForbidden(i, j) {
+			x = math.Min(math.Min(s[i], d[j]), es.capacity[i][j])
+		}
+		s[i], d[j] = s[i]-x, d[j]-x
+		shipped += x
+		fc := &es.flow[i][j]
+		fc.value = x
+		if s[i] > epsilon && d[j] > epsilon {
+			// at its capacity (or forbidden), on to the next column
+			// unless it is the last one
+			fc.upper = x > 0
+			if j < ac-1 {
+				j++
+			} else {
+				i++
+			}
+			continue
+		}
+		fc.basic = true
+		if s[i] <= epsilon {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	// the cells on the staircase never make a loop
+	nodeCnt := es.sLen + es.dLen
+	uf := es.nodeBuf
+	for k := 0; k < nodeCnt; k++ {
+		uf[k] = k
+	}
+	for i := 0; i < ar; i++ {
+		for j := 0; j < ac; j++ {
+			if es.flow[i][j].basic {
+				uf[findRoot(uf, i)] = findRoot(uf, es.sLen+j)
+			}
+		}
+	}
+	link := func(i, j int, x float64) bool {
+		ri, rj := findRoot(uf, i), findRoot(uf, es.sLen+j)
+		if ri == rj {
+			return false
+		}
+		uf[ri] = rj
+		fc := &es.flow[i][j]
+		fc.basic = true
+		fc.value = x
+		return true
+	}
+
+	// the rows and columns which are left ship on the other routes, one
+	// which isn't filled up must not close a loop of basic cells
+	for i := 0; i < ar; i++ {
+		for j := 0; j < ac && s[i] > epsilon; j++ {
+			fc := &es.flow[i][j]
+			if d[j] <= epsilon || fc.basic || fc.upper || es.isForbidden(i, j) || es.capacity[i][j] <= epsilon {
+				continue
+			}
+			x := math.Min(math.Min(s[i], d[j]), es.capacity[i][j])
+			if x < es.capacity[i][j] {
+				if !link(i, j, x) {
+					continue
+				}
+			} else {
+				fc.value = x
+				fc.upper = true
+			}
+			s[i], d[j] = s[i]-x, d[j]-x
+			shipped += x
+		}
+	}
+	// what is left goes on the artificial routes, the artificial
+	// producer sends the shipped quatity to the artificial consumer
+	link(ar, ac, shipped)
+	for i := 0; i < ar; i++ {
+		if s[i] > epsilon && !link(i, ac, s[i]) {
+			return es.findAllArtificialSolution()
+		}
+	}
+	for j := 0; j < ac; j++ {
+		if d[j] > epsilon && !link(ar, j, d[j]) {
+			return es.findAllArtificialSolution()
+		}
+	}
+	for i := 0; i < ar; i++ {
+		link(i, ac, 0)
+	}
+	for j := 0; j < ac; j++ {
+		link(ar, j, 0)
+	}
+	return nodeCnt - 1
+}
+
+// Put all the flow on the artificial routes: every producer ships to the
+// artificial consumer and every consumer gets from the artificial
+// producer. It is feasible for any capacities and the basic cells
+// already make a spanning tree.
+func (es *Problem) findAllArtificialSolution() int {
+	clear(es.cells)
+	ar, ac := es.sLen-1, es.dLen-1
+	for i := 0; i < ar; i++ {
+		fc := &es.flow[i][ac]
+		fc.basic = true
+		fc.value = es.supply[i]
+	}
+	for j := 0; j < ac; j++ {
+		fc := &es.flow[ar][j]
+		fc.basic = true
+		fc.value = es.demand[j]
+	}
+	// the artificial producer sends all its quatity to the consumers
+	es.flow[ar][ac].basic = true
+	es.flow[ar][ac].value = 0
+	return es.sLen + es.dLen - 1
+}
+
+// Get the saturated routes, the ones with positive capacity which
+// carry as much as it (within epsilon), with their costs. It is empty
+// if the problem is not capacitated.
+// Should be called after calling Solve().
+func (es *Problem) GetSaturatedRoutes() []Route {
+	routes := make([]Route, 0)
+	if es.capacity == nil {
+		return routes
+	}
+	sLen, dLen := es.inputSize()
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			fc, c := &es.flow[i][j], es.capacity[i][j]
+			if c <= es.epsilon || math.IsInf(c, 1) {
+				continue
+			}
+			if (fc.basic || fc.upper) && fc.value >= c-es.epsilon {
+				routes = append(routes, Route{From: i, To: j, Cost: es.costMatrix[i][j]})
+			}
+		}
+	}
+	return routes
+}
@@ -0,0 +1,304 @@
+package tp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// capacities over the flow of a solution of the same supply/demand, so
+// the problem is feasible, some of them are tight
+func randomCapacities(r *rand.Rand, tp *TestProblem) ([][]float64, error) {
+	costs := make([][]float64, len(tp.supply))
+	for i := range costs {
+		costs[i] = make([]float64, len(tp.demand))
+		for j := range costs[i] {
+			costs[i][j] = float64(r.Intn(50))
+		}
+	}
+	p, err := NewProblem(tp.supply, tp.demand, costs, WithMaxIter(0))
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.Solve(); err != nil {
+		return nil, err
+	}
+	caps := p.GetFlow()
+	for i := range caps {
+		for j := range caps[i] {
+			if r.Intn(3) > 0 {
+				caps[i][j] += float64(r.Intn(30))
+			}
+		}
+	}
+	return caps, nil
+}
+
+// check the flow is within the capacities and the duals prove it is
+// optimal: a route below its capacity has a reduced cost >=0 and a
+// route above 0 has a reduced cost <=0
+func checkCapacitated(p *Problem, tp *TestProblem, caps [][]float64) error {
+	flow := p.GetFlow()
+	u, v := p.GetDuals()
+	sSum, dSum := float64(0), float64(0)
+	for i := range tp.supply {
+		sSum += tp.supply[i]
+	}
+	for j := range tp.demand {
+		dSum += tp.demand[j]
+	}
+	for i := range flow {
+		rowSum := float64(0)
+		for j := range flow[i] {
+			f := flow[i][j]
+			if f < -EPSILON || f > caps[i][j]+EPSILON {
+				return fmt.Errorf("flow[%v][%v]=%v is out of [0,%v]", i, j, f, caps[i][j])
+			}
+			rc := tp.costs[i][j] - u[i] - v[j]
+			if f < caps[i][j]-EPSILON && rc < -1e-6 {
+				return fmt.Errorf("(%v,%v) with flow %v<%v has reduced cost %v", i, j, f, caps[i][j], rc)
+			}
+			if f > EPSILON && rc > 1e-6 {
+				return fmt.Errorf("(%v,%v) with flow %v has reduced cost %v", i, j, f, rc)
+			}
+			rowSum += f
+		}
+		if sSum <= dSum && math.Abs(rowSum-tp.supply[i]) > 1e-6 {
+			return fmt.Errorf("row %v ships %v, supply is %v", i, rowSum, tp.supply[i])
+		}
+	}
+	for j := range tp.demand {
+		colSum := float64(0)
+		for i := range flow {
+			colSum += flow[i][j]
+		}
+		if sSum >= dSum && math.Abs(colSum-tp.demand[j]) > 1e-6 {
+			return fmt.Errorf("column %v gets %v, demand is %v", j, colSum, tp.demand[j])
+		}
+	}
+	return nil
+}
+
+func TestCapacitated(t *testing.T) {
+	// not capacitated at all, same as the plain problem
+	for _, tp := range testData {
+		caps := make([][]float64, len(tp.supply))
+		for i := range caps {
+			caps[i] = make([]float64, len(tp.demand))
+			for j := range caps[i] {
+				caps[i][j] = math.Inf(1)
+			}
+		}
+		p, err := NewCapacitatedProblem(tp.supply, tp.demand, tp.costs, caps, WithMaxIter(0))
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to create the problem %v", tp.id), err)
+			return
+		}
+		if p.GetInitialStrategy() != InitArtificial {
+			t.Error(fmt.Sprintf("problem %v: initial strategy is %v", tp.id, p.GetInitialStrategy()))
+			return
+		}
+		result, err := p.Solve()
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve the problem %v", tp.id), err)
+			return
+		}
+		q, err := solveWith(tp, 0, AlgoNetworkSimplex)
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve the plain problem %v", tp.id), err)
+			return
+		}
+		if c := q.GetCost(); math.Abs(result.Objective-c) > EPSILON {
+			t.Error(fmt.Sprintf("problem %v: cost %v != %v", tp.id, result.Objective, c))
+			return
+		}
+		if err = checkTree(p); err != nil {
+			t.Error(fmt.Sprintf("bad basis tree for problem %v", tp.id), err)
+			return
+		}
+		if routes := p.GetSaturatedRoutes(); len(routes) != 0 {
+			t.Error(fmt.Sprintf("problem %v: saturated routes %v", tp.id, routes))
+			return
+		}
+	}
+
+	r := rand.New(rand.NewSource(8))
+	for k := 0; k < 20; k++ {
+		tp := randomProblem(r, 2+r.Intn(15), 2+r.Intn(15))
+		caps, err := randomCapacities(r, tp)
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to get capacities for [%v]", tp.name), err)
+			return
+		}
+		p, err := NewCapacitatedProblem(tp.supply, tp.demand, tp.costs, caps, WithMaxIter(0))
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to create [%v]", tp.name), err)
+			return
+		}
+		result, err := p.Solve()
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve [%v]", tp.name), err)
+			return
+		}
+		if !result.Optimal() || result.MaxViolation > EPSILON {
+			t.Error(fmt.Sprintf("[%v] is not optimal: %+v", tp.name, result))
+			return
+		}
+		if err = checkTree(p); err != nil {
+			t.Error(fmt.Sprintf("bad basis tree for [%v]", tp.name), err)
+			return
+		}
+		if err = checkCapacitated(p, tp, caps); err != nil {
+			t.Error(fmt.Sprintf("[%v]", tp.name), err)
+			return
+		}
+
+		// capacities only make it cost more
+		q, _ := NewProblem(tp.supply, tp.demand, tp.costs, WithMaxIter(0))
+		if res, err := q.Solve(); err != nil || res.Objective > result.Objective+EPSILON {
+			t.Error(fmt.Sprintf("[%v] costs %v, %v without capacities", tp.name, result.Objective, res), err)
+			return
+		}
+
+		// saturated routes are the ones at their (positive) capacity
+		flow := p.GetFlow()
+		saturated := make(map[[2]int]bool)
+		for _, route := range p.GetSaturatedRoutes() {
+			saturated[[2]int{route.From, route.To}] = true
+			if route.Cost != tp.costs[route.From][route.To] {
+				t.Error(fmt.Sprintf("[%v] saturated route %+v has wrong cost", tp.name, route))
+				return
+			}
+		}
+		for i := range flow {
+			for j := range flow[i] {
+				full := caps[i][j] > EPSILON && flow[i][j] >= caps[i][j]-EPSILON
+				if full != saturated[[2]int{i, j}] {
+					t.Error(fmt.Sprintf("[%v] (%v,%v) flow=%v, capacity=%v, saturated=%v", tp.name, i, j, flow[i][j], caps[i][j], saturated[[2]int{i, j}]))
+					return
+				}
+			}
+		}
+
+		// the flow stays optimal for a cost inside its range
+		s, err := p.GetSensitivity()
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to get sensitivity of [%v]", tp.name), err)
+			return
+		}
+		for i := range tp.costs {
+			for j := range tp.costs[i] {
+				cr := s.Costs[i][j]
+				if !cr.Contains(tp.costs[i][j]) {
+					t.Error(fmt.Sprintf("[%v] cost range %v doesn't contain cost %v at (%v,%v)", tp.name, cr, tp.costs[i][j], i, j))
+					return
+				}
+				for _, upper := range []bool{false, true} {
+					costs := copyCosts(tp.costs)
+					costs[i][j] = insideRange(cr, tp.costs[i][j], upper)
+					q, _ := NewCapacitatedProblem(tp.supply, tp.demand, costs, caps, WithMaxIter(0))
+					result, err := q.Solve()
+					if err != nil {
+						t.Error(fmt.Sprintf("failed to solve [%v] with cost (%v,%v)=%v", tp.name, i, j, costs[i][j]), err)
+						return
+					}
+					if c := flowCost(flow, costs); math.Abs(result.Objective-c) > 1e-6*math.Max(1, math.Abs(c)) {
+						t.Error(fmt.Sprintf("[%v] cost (%v,%v)=%v within %v: optimal cost is %v, old flow costs %v", tp.name, i, j, costs[i][j], cr, result.Objective, c))
+						return
+					}
+				}
+			}
+		}
+	}
+
+	// the capacities can't carry the supply
+	supply := []float64{10, 20}
+	demand := []float64{15, 15}
+	costs := [][]float64{{1, 2}, {3, 1}}
+	caps := [][]float64{{5, 5}, {8, 10}}
+	p, err := NewCapacitatedProblem(supply, demand, costs, caps, WithMaxIter(0))
+	if err != nil {
+		t.Error("failed to create the infeasible problem", err)
+		return
+	}
+	if _, err = p.Solve(); !errors.Is(err, ErrInfeasible) {
+		t.Error(fmt.Sprintf("expect ErrInfeasible, got %v", err))
+		return
+	}
+
+	// infeasible without the forbidden route, which ends up at its
+	// capacity (not basic) with a big-M flow
+	inf := math.Inf(1)
+	for _, rule := range pivotRules {
+		p, err = NewCapacitatedProblem([]float64{7, 13, 9, 1, 4, 5}, []float64{39},
+			[][]float64{{4}, {0}, {inf}, {7}, {6}, {6}}, [][]float64{{13}, {13}, {9}, {inf}, {inf}, {6}},
+			WithPivotRule(rule), WithMaxIter(0))
+		if err != nil {
+			t.Error("failed to create the infeasible problem with a forbidden route", err)
+			return
+		}
+		if result, err := p.Solve(); !errors.Is(err, ErrInfeasible) {
+			t.Error(fmt.Sprintf("%v: expect ErrInfeasible for a forbidden route at its capacity, got %+v", rule, result), err)
+			return
+		}
+	}
+
+	// it starts within the capacities, a limit or a cancelled context
+	// leaves a plan shipping the supply/demand
+	tp := randomProblem(rand.New(rand.NewSource(1)), 100, 100)
+	caps = make([][]float64, len(tp.supply))
+	for i := range caps {
+		caps[i] = make([]float64, len(tp.demand))
+		for j := range caps[i] {
+			caps[i][j] = inf
+		}
+	}
+	if p, _ = NewCapacitatedProblem(tp.supply, tp.demand, tp.costs, caps); p.maxIter != 0 {
+		t.Error(fmt.Sprintf("max iterations of a capacitated problem is %v by default", p.maxIter))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for k, solve := range []func() (*Result, error){
+		func() (*Result, error) {
+			p, _ := NewCapacitatedProblem(tp.supply, tp.demand, tp.costs, caps, WithMaxIter(MAX_ITER))
+			return p.Solve()
+		},
+		func() (*Result, error) {
+			p, _ := NewCapacitatedProblem(tp.supply, tp.demand, tp.costs, caps)
+			return p.SolveContext(ctx)
+		},
+	} {
+		result, err := solve()
+		if err != nil || result.Status != []Status{StatusIterationLimit, StatusCancelled}[k] {
+			t.Error(fmt.Sprintf("stopped capacitated problem #%v: %+v", k, result), err)
+			return
+		}
+		for i := range tp.supply {
+			sum := float64(0)
+			for j := range tp.demand {
+				sum += result.Flow[i][j]
+			}
+			if math.Abs(sum-tp.supply[i]) > 1e-6 {
+				t.Error(fmt.Sprintf("stopped capacitated problem #%v: row %v ships %v, supply is %v", k, i, sum, tp.supply[i]))
+				return
+			}
+		}
+	}
+
+	// invalid capacities
+	for _, caps := range [][][]float64{
+		{{1, 1}},
+		{{1, 1}, {1}},
+		{{1, -1}, {1, 1}},
+		{{1, math.NaN()}, {1, 1}},
+	} {
+		if _, err := NewCapacitatedProblem(supply, demand, costs, caps); err == nil {
+			t.Error(fmt.Sprintf("expect error for capacities %v", caps))
+			return
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package tp
+
+// Add 2*epsilon to the supply of every producer and all of it to the
+// demand of the last consumer (the artificial producer/consumer take it
+// too), so no subset of the producers ships exactly what a subset of
+// the consumers takes and no basic cell is at 0. The supply/demand and
+// the quatity are kept in es.unperturbed*.
+func (es *Problem) perturb() {
+	sLen, dLen := es.sLen, es.dLen
+	es.unperturbed = resize(es.unperturbed, sLen+dLen)
+	copy(es.unperturbed, es.supply)
+	copy(es.unperturbed[sLen:], es.demand)
+	es.unperturbedQuatity = es.quatity
+
+	rows, cols := sLen, dLen
+	if es.capacity != nil {
+		rows, cols = rows-1, cols-1
+	}
+	delta := 2 * es.epsilon
+	for i := 0; i < rows; i++ {
+		es.supply[i] += delta
+	}
+	total := float64(rows) * delta
+	es.demand[cols-1] += total
+	es.quatity += total
+	if es.capacity != nil {
+		es.supply[sLen-1] += total
+		es.demand[dLen-1] += total
+	}
+}
+
+// put the supply/demand back as they were before perturb()
+func (es *Problem) unperturb() {
+	copy(es.supply, es.unperturbed)
+	copy(es.demand, es.unperturbed[es.sLen:])
+	es.quatity = es.unperturbedQuatity
+}
+
+// Find the leaving cell of a network simplex pivot by Bland's rule: the
+// first one (row-major) of the cells on the loop which can move no more
+// than theta (within epsilon). The args are the ends of the entering
+// cell, its direction and theta, same as in pivot(), it returns the
+// node under the leaving cell, if it is on a's side and its direction.
+func (es *Problem) blandLeaving(a, b int, dir, theta float64) (int, bool, float64) {
+	sLen, dLen, t := es.sLen, es.dLen, es.tree
+	leave, leaveOnA, leaveDir, first := -1, false, float64(0), 0
+	x, y := a, b
+	for x != y {
+		node, onA, d := x, true, dir
+		if t.depth[x] >= t.depth[y] {
+			if x < sLen {
+				d = -dir
+			}
+			x = t.parent[x]
+		} else {
+			node, onA, d = y, false, -dir
+			if y < sLen {
+				d = dir
+			}
+			y = t.parent[y]
+		}
+		if es.room(node, d) > theta+es.epsilon {
+			continue
+		}
+		i, j := es.treeCell(node)
+		if idx := i*dLen + j; leave == -1 || idx < first {
+			leave, leaveOnA, leaveDir, first = node, onA, d, idx
+		}
+	}
+	return leave, leaveOnA, leaveDir
+}
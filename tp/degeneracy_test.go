@@ -0,0 +1,272 @@
+package tp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pivotRules = []PivotRule{PivotDantzig, PivotFirstEligible, PivotBland, PivotPerturbation, PivotBlockSearch}
+
+// Word mover's distance between two documents of the given word
+// vectors, every word has the weight of its count in the document
+func wmdProblem(name string, d1, d2 [][]float64) *TestProblem {
+	weights := func(doc [][]float64) ([][]float64, []float64) {
+		var words [][]float64
+		var w []float64
+	next:
+		for _, x := range doc {
+			for k := range words {
+				if fmt.Sprint(words[k]) == fmt.Sprint(x) {
+					w[k] += 1 / float64(len(doc))
+					continue next
+				}
+			}
+			words = append(words, x)
+			w = append(w, 1/float64(len(doc)))
+		}
+		return words, w
+	}
+	words1, supply := weights(d1)
+	words2, demand := weights(d2)
+	costs := make([][]float64, len(words1))
+	for i := range costs {
+		costs[i] = make([]float64, len(words2))
+		for j := range costs[i] {
+			var sum float64
+			for k := range words1[i] {
+				diff := words1[i][k] - words2[j][k]
+				sum += diff * diff
+			}
+			costs[i][j] = math.Sqrt(sum)
+		}
+	}
+	return &TestProblem{name: name, supply: supply, demand: demand, costs: costs}
+}
+
+// a document of n words, the vectors are on a small grid so many of
+// the distances are the same
+func randomDoc(r *rand.Rand, n, dim int) [][]float64 {
+	doc := make([][]float64, n)
+	for k := range doc {
+		doc[k] = make([]float64, dim)
+		for d := range doc[k] {
+			doc[k][d] = float64(r.Intn(3))
+		}
+	}
+	return doc
+}
+
+func TestDegeneracy(t *testing.T) {
+	// the documents of the wmd test, and random ones with the same
+	// weights (a word at most once) or counted ones
+	problems := []*TestProblem{wmdProblem("a test word/the text world",
+		[][]float64{{-0.03, 0.02}, {0.18, 0.24}, {0.09, 0.43}},
+		[][]float64{{-0.07, 0.05}, {0.04, 0.11}, {-0.18, 0.23}})}
+	r := rand.New(rand.NewSource(23))
+	for k := 0; k < 30; k++ {
+		m, n, dim := 2+r.Intn(30), 2+r.Intn(30), 1+r.Intn(3)
+		if k%3 == 0 {
+			n = m
+		}
+		problems = append(problems, wmdProblem(fmt.Sprintf("wmd-%v", k), randomDoc(r, m, dim), randomDoc(r, n, dim)))
+	}
+
+	degenerate := 0
+	for _, tp := range problems {
+		in := &Instance{Name: tp.name, Supply: tp.supply, Demand: tp.demand, Costs: tp.costs}
+		var cost float64
+		for _, init := range []InitialStrategy{InitNorthwestCorner, InitLeastCost} {
+			blandIters := -1
+			for _, algo := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+				for _, rule := range pivotRules {
+					var trace bytes.Buffer
+					name := fmt.Sprintf("[%v] %v/%v/%v", tp.name, init, algo, rule)
+					p, err := in.NewProblem(WithInitialStrategy(init), WithAlgorithm(algo), WithPivotRule(rule),
+						WithMaxIter(0), WithTimeLimit(time.Minute), WithTrace(&trace))
+					if err != nil {
+						t.Error(name, err)
+						return
+					}
+					result, err := p.Solve()
+					if err != nil || !result.Optimal() {
+						t.Error(fmt.Sprintf("%v isn't solved: %+v", name, result), err)
+						return
+					}
+					u, v := p.GetDuals()
+					report, err := Verify(in, result.Flow, u, v, 0)
+					if err == nil {
+						err = checkOptimal(report)
+					}
+					if err != nil {
+						t.Error(name, err)
+						return
+					}
+					if cost == 0 {
+						cost = result.Objective
+					} else if math.Abs(result.Objective-cost) > 1e-9 {
+						t.Error(fmt.Sprintf("%v cost is %v, should be %v", name, result.Objective, cost))
+						return
+					}
+
+					// every pivot moves some flow on the perturbed problem
+					zero := strings.Count(trace.String(), "theta=0\n")
+					if rule == PivotPerturbation && zero > 0 {
+						t.Error(fmt.Sprintf("%v makes %v degenerate pivots", name, zero))
+						return
+					}
+					if rule == PivotDantzig && zero > 0 {
+						degenerate++
+					}
+					// both algorithms make the same pivots by Bland's rule
+					if rule == PivotBland {
+						if blandIters == -1 {
+							blandIters = result.Iterations
+						} else if result.Iterations != blandIters {
+							t.Error(fmt.Sprintf("%v takes %v iterations, should be %v", name, result.Iterations, blandIters))
+							return
+						}
+					}
+				}
+			}
+		}
+
+		// the exact solution with the rules of the generic solver
+		for _, rule := range []PivotRule{PivotDantzig, PivotFirstEligible, PivotBland} {
+			p, result, err := solveGeneric(tp, toRat, RatArithmetic{}, WithPivotRule(rule), WithMaxIter(0))
+			if err == nil && !result.Optimal() {
+				err = fmt.Errorf("status is %v", result.Status)
+			}
+			if err == nil {
+				err = checkExact(tp, p, result)
+			}
+			if err != nil {
+				t.Error(fmt.Sprintf("[%v] rational result with %v", tp.name, rule), err)
+				return
+			}
+			if x, _ := result.Objective.Float64(); math.Abs(x-cost) > 1e-9 {
+				t.Error(fmt.Sprintf("[%v] rational cost with %v is %v, should be %v", tp.name, rule, x, cost))
+				return
+			}
+		}
+	}
+	if degenerate == 0 {
+		t.Error("no degenerate pivot with Dantzig's rule")
+		return
+	}
+
+	// the wmd of two 38-word documents, every word weighs 1/38. Dantzig's
+	// rule stalls on it, almost all of its pivots don't move any flow and
+	// it isn't optimal after the pivots of the perturbation
+	f, err := os.Open(filepath.Join("testdata", "wmd-degenerate.json"))
+	if err != nil {
+		t.Error("failed to open the wmd fixture", err)
+		return
+	}
+	in, err := ReadJSON(f)
+	f.Close()
+	if err != nil {
+		t.Error("failed to read the wmd fixture", err)
+		return
+	}
+	for _, algo := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+		iters := make(map[PivotRule]int)
+		zero := make(map[PivotRule]int)
+		var cost float64
+		for _, rule := range []PivotRule{PivotPerturbation, PivotBland, PivotDantzig} {
+			var trace bytes.Buffer
+			p, _ := in.NewProblem(WithAlgorithm(algo), WithPivotRule(rule), WithInitialStrategy(InitVogel),
+				WithMaxIter(0), WithTrace(&trace))
+			result, err := p.Solve()
+			if err != nil || result.Status != StatusOptimal {
+				t.Error(fmt.Sprintf("[%v] %v/%v isn't solved: %v", in.Name, algo, rule, result.Status), err)
+				return
+			}
+			if rule == PivotPerturbation {
+				cost = result.Objective
+			} else if math.Abs(result.Objective-cost) > 1e-9 {
+				t.Error(fmt.Sprintf("[%v] %v/%v cost is %v, should be %v", in.Name, algo, rule, result.Objective, cost))
+				return
+			}
+			iters[rule] = result.Iterations
+			zero[rule] = strings.Count(trace.String(), "theta=0\n")
+		}
+		if zero[PivotPerturbation] > 0 || zero[PivotDantzig]*10 < iters[PivotDantzig]*9 ||
+			iters[PivotDantzig]*3 < iters[PivotPerturbation]*5 {
+			t.Error(fmt.Sprintf("[%v] %v: pivots %v, degenerate ones %v", in.Name, algo, iters, zero))
+			return
+		}
+		p, _ := in.NewProblem(WithAlgorithm(algo), WithPivotRule(PivotDantzig), WithInitialStrategy(InitVogel),
+			WithMaxIter(iters[PivotPerturbation]))
+		if result, err := p.Solve(); err != nil || result.Status != StatusIterationLimit {
+			t.Error(fmt.Sprintf("[%v] %v: dantzig is %v after %v pivots", in.Name, algo, result.Status, iters[PivotPerturbation]), err)
+			return
+		}
+	}
+
+	// forbidden routes, capacities and penalties
+	for k := 0; k < 40; k++ {
+		in := randomInstance(r)
+		_, infeasible := solveInstance(in)
+		for _, rule := range []PivotRule{PivotBland, PivotPerturbation} {
+			report, err := solveAndVerify(in, WithPivotRule(rule), WithAlgorithm(Algorithm(k%2)), WithMaxIter(0))
+			if errors.Is(err, ErrInfeasible) && errors.Is(infeasible, ErrInfeasible) {
+				// the forbidden routes and capacities can't carry it
+				continue
+			}
+			if err == nil {
+				err = checkOptimal(report)
+			}
+			if err != nil {
+				t.Error(fmt.Sprintf("[%v] %v", in.Name, rule), err)
+				return
+			}
+		}
+	}
+
+	// the demands differ by less than the perturbation, the perturbed
+	// basis ships 1.5e-6 too much from producer 0 to consumer 1
+	tp := &TestProblem{name: "perturbed", supply: []float64{1, 1}, demand: []float64{1 + 1.5e-6, 1 - 1.5e-6},
+		costs: [][]float64{{0, 10}, {10, 0}}}
+	for _, algo := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+		var trace bytes.Buffer
+		p, _ := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algo), WithPivotRule(PivotPerturbation),
+			WithInitialStrategy(InitNorthwestCorner), WithTrace(&trace))
+		result, err := p.Solve()
+		if err != nil || !result.Optimal() || math.Abs(result.Objective-1.5e-5) > 1e-12 || result.Flow[0][1] != 0 {
+			t.Error(fmt.Sprintf("[%v] %v result %+v", tp.name, algo, result), err)
+			return
+		}
+		if !strings.Contains(trace.String(), "solving again with bland") {
+			t.Error(fmt.Sprintf("[%v] %v doesn't solve it again: %v", tp.name, algo, trace.String()))
+			return
+		}
+		// the supply/demand are not changed
+		if p.supply[0] != 1 || p.demand[1] != 1-1.5e-6 {
+			t.Error(fmt.Sprintf("[%v] %v supply/demand are changed to %v, %v", tp.name, algo, p.supply, p.demand))
+			return
+		}
+	}
+
+	// the third of a unit is exact with the rationals
+	third := big.NewRat(1, 3)
+	p, err := NewGenericProblem([]*big.Rat{third, third, third}, []*big.Rat{third, third, third},
+		[][]*big.Rat{{big.NewRat(1, 1), big.NewRat(1, 1), big.NewRat(0, 1)}, {big.NewRat(1, 1), big.NewRat(0, 1), big.NewRat(1, 1)},
+			{big.NewRat(0, 1), big.NewRat(1, 1), big.NewRat(1, 1)}}, RatArithmetic{}, WithPivotRule(PivotBland))
+	if err != nil {
+		t.Error("failed to create the rational problem", err)
+		return
+	}
+	if result, _ := p.Solve(); result.Objective.Sign() != 0 {
+		t.Error(fmt.Sprintf("rational cost is %v, should be 0", result.Objective))
+		return
+	}
+}
@@ -0,0 +1,475 @@
+package tp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Write the instance in the DIMACS min-cost-flow format, producer i is
+// node i+1 and consumer j node len(supply)+j+1 (with a negative supply),
+// every route which isn't forbidden is an arc with a 0 lower bound. An
+// uncapacitated route gets the total quantity as its capacity.
+//
+// DIMACS needs the supply and demand to be balanced, so a dummy node
+// (marked with a "c dummy <node>" comment) takes the difference of
+// unbalanced inputs, its arcs cost the surplus costs (shortage
+// penalties). The penalties its arcs don't carry (all of them for
+// balanced inputs) are kept in "c surplus-costs <costs>" and
+// "c shortage-penalties <penalties>" comments, with "inf" for
+// math.Inf(1), which other readers ignore.
+//
+// Integral values are written as integers. Standard DIMACS readers only
+// take integers, the other values (e.g. 0.25) are written in the
+// shortest decimal form which reads back exactly, it is an extension
+// ReadDIMACS() reads.
+func (in *Instance) WriteDIMACS(w io.Writer) error {
+	sLen, dLen := len(in.Supply), len(in.Demand)
+	if len(in.Costs) != sLen {
+		return fmt.Errorf("producer count doesn't match 1st dimension length of costs!")
+	}
+	var sSum, dSum float64
+	for _, x := range in.Supply {
+		sSum += x
+	}
+	for _, x := range in.Demand {
+		dSum += x
+	}
+	total := math.Max(sSum, dSum)
+	dummy := sLen + dLen + 1
+	capOf := func(i, j int) float64 {
+		if in.Capacities == nil || math.IsInf(in.Capacities[i][j], 1) {
+			return total
+		}
+		return in.Capacities[i][j]
+	}
+
+	lines := make([]string, 0)
+	for i := 0; i < sLen; i++ {
+		if len(in.Costs[i]) != dLen {
+			return fmt.Errorf("consumer count doesn't match 2nd dimension length of costs!")
+		}
+		for j := 0; j < dLen; j++ {
+			if c := in.Costs[i][j]; !math.IsInf(c, 1) {
+				lines = append(lines, fmt.Sprintf("a %v %v 0 %v %v", i+1, sLen+j+1, formatFloat(capOf(i, j)), formatFloat(c)))
+			}
+		}
+	}
+	nodeCnt := sLen + dLen
+	if sSum > dSum {
+		for i := 0; i < sLen; i++ {
+			c := float64(0)
+			if in.SurplusCosts != nil {
+				c = in.SurplusCosts[i]
+			}
+			if !math.IsInf(c, 1) {
+				lines = append(lines, fmt.Sprintf("a %v %v 0 %v %v", i+1, dummy, formatFloat(total), formatFloat(c)))
+			}
+		}
+		nodeCnt += 1
+	} else if sSum < dSum {
+		for j := 0; j < dLen; j++ {
+			c := float64(0)
+			if in.ShortagePenalties != nil {
+				c = in.ShortagePenalties[j]
+			}
+			if !math.IsInf(c, 1) {
+				lines = append(lines, fmt.Sprintf("a %v %v 0 %v %v", dummy, sLen+j+1, formatFloat(total), formatFloat(c)))
+			}
+		}
+		nodeCnt += 1
+	}
+
+	bw := bufio.NewWriter(w)
+	if in.Name != "" {
+		fmt.Fprintf(bw, "c %v\n", in.Name)
+	}
+	if nodeCnt > sLen+dLen {
+		fmt.Fprintf(bw, "c dummy %v\n", dummy)
+	}
+	if in.SurplusCosts != nil && sSum <= dSum {
+		fmt.Fprintf(bw, "c surplus-costs %v\n", formatPenalties(in.SurplusCosts))
+	}
+	if in.ShortagePenalties != nil && sSum >= dSum {
+		fmt.Fprintf(bw, "c shortage-penalties %v\n", formatPenalties(in.ShortagePenalties))
+	}
+	fmt.Fprintf(bw, "p min %v %v\n", nodeCnt, len(lines))
+	for i, x := range in.Supply {
+		fmt.Fprintf(bw, "n %v %v\n", i+1, formatFloat(x))
+	}
+	for j, x := range in.Demand {
+		fmt.Fprintf(bw, "n %v %v\n", sLen+j+1, formatFloat(-x))
+	}
+	if sSum != dSum {
+		fmt.Fprintf(bw, "n %v %v\n", dummy, formatFloat(dSum-sSum))
+	}
+	for _, line := range lines {
+		fmt.Fprintln(bw, line)
+	}
+	return bw.Flush()
+}
+
+// integers without an exponent, the other values in the shortest form
+// which reads back exactly
+func formatFloat(x float64) string {
+	if x == math.Trunc(x) && math.Abs(x) < 1e21 {
+		return strconv.FormatFloat(x, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+// penalties separated by spaces, "inf" for math.Inf(1)
+func formatPenalties(x []float64) string {
+	fields := make([]string, len(x))
+	for k, c := range x {
+		if math.IsInf(c, 1) {
+			fields[k] = "inf"
+		} else {
+			fields[k] = formatFloat(c)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// read the penalties written by formatPenalties()
+func parsePenalties(fields []string) ([]float64, error) {
+	x := make([]float64, len(fields))
+	for k, f := range fields {
+		if f == "inf" {
+			x[k] = math.Inf(1)
+			continue
+		}
+		v, err := parseFloats(fields[k : k+1])
+		if err != nil {
+			return nil, err
+		}
+		x[k] = v[0]
+	}
+	return x, nil
+}
+
+func parseFloats(fields []string) ([]float64, error) {
+	x := make([]float64, len(fields))
+	for k, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid number %v", f)
+		}
+		x[k] = v
+	}
+	return x, nil
+}
+
+// Read an instance in the DIMACS min-cost-flow format, it must be a
+// transportation problem: the nodes with a positive supply are the
+// producers and the ones with a negative supply the consumers (both in
+// the order of the node ids), every arc goes from a producer to a
+// consumer with a 0 lower bound. A capacity which can't limit the flow
+// (not less than the supply and the demand of its route) is read as
+// math.Inf(1), the instance has no capacities if none of them can. The
+// dummy node written by WriteDIMACS() is read back as the difference of
+// supply and demand, the costs of its arcs as the surplus costs
+// (shortage penalties) unless they are all 0, and so are the penalties
+// of the "c surplus-costs"/"c shortage-penalties" comments.
+func ReadDIMACS(r io.Reader) (*Instance, error) {
+	in := &Instance{}
+	nodeCnt, arcCnt := -1, 0
+	dummy := -1
+	var supply, surplusCosts, shortagePenalties []float64
+	type arc struct {
+		from, to  int
+		cap, cost float64
+	}
+	arcs := make([]arc, 0)
+	sc := bufio.NewScanner(r)
+	ln := 0
+	for sc.Scan() {
+		ln += 1
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] != "c" && fields[0] != "p" && nodeCnt < 0 {
+			return nil, fmt.Errorf("line %v: problem line is missing!", ln)
+		}
+		switch fields[0] {
+		case "c":
+			if len(fields) == 3 && fields[1] == "dummy" {
+				n, err := strconv.Atoi(fields[2])
+				if err != nil {
+					return nil, fmt.Errorf("line %v: invalid dummy node %v!", ln, fields[2])
+				}
+				dummy = n
+			} else if len(fields) > 1 && (fields[1] == "surplus-costs" || fields[1] == "shortage-penalties") {
+				x, err := parsePenalties(fields[2:])
+				if err != nil {
+					return nil, fmt.Errorf("line %v: %v!", ln, err)
+				}
+				if fields[1] == "surplus-costs" {
+					surplusCosts = x
+				} else {
+					shortagePenalties = x
+				}
+			} else if in.Name == "" && len(fields) > 1 {
+				in.Name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(sc.Text()), "c"))
+			}
+		case "p":
+			if nodeCnt >= 0 {
+				return nil, fmt.Errorf("line %v: duplicate problem line!", ln)
+			}
+			if len(fields) != 4 || fields[1] != "min" {
+				return nil, fmt.Errorf("line %v: invalid problem line, should be \"p min <nodes> <arcs>\"!", ln)
+			}
+			var err1, err2 error
+			nodeCnt, err1 = strconv.Atoi(fields[2])
+			arcCnt, err2 = strconv.Atoi(fields[3])
+			if err1 != nil || err2 != nil || nodeCnt < 0 || arcCnt < 0 {
+				return nil, fmt.Errorf("line %v: invalid node/arc count!", ln)
+			}
+			supply = make([]float64, nodeCnt)
+		case "n":
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("line %v: invalid node line, should be \"n <id> <supply>\"!", ln)
+			}
+			id, err := strconv.Atoi(fields[1])
+			if err != nil || id < 1 || id > nodeCnt {
+				return nil, fmt.Errorf("line %v: invalid node id %v!", ln, fields[1])
+			}
+			x, err := parseFloats(fields[2:])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v!", ln, err)
+			}
+			supply[id-1] = x[0]
+		case "a":
+			if len(fields) != 6 {
+				return nil, fmt.Errorf("line %v: invalid arc line, should be \"a <from> <to> <low> <cap> <cost>\"!", ln)
+			}
+			from, err1 := strconv.Atoi(fields[1])
+			to, err2 := strconv.Atoi(fields[2])
+			if err1 != nil || err2 != nil || from < 1 || from > nodeCnt || to < 1 || to > nodeCnt {
+				return nil, fmt.Errorf("line %v: invalid arc (%v,%v)!", ln, fields[1], fields[2])
+			}
+			x, err := parseFloats(fields[3:])
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v!", ln, err)
+			}
+			if x[0] != 0 {
+				return nil, fmt.Errorf("line %v: lower bound %v is not supported!", ln, x[0])
+			}
+			arcs = append(arcs, arc{from - 1, to - 1, x[1], x[2]})
+		default:
+			return nil, fmt.Errorf("line %v: unknown line type %v!", ln, fields[0])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if nodeCnt < 0 {
+		return nil, fmt.Errorf("problem line is missing!")
+	}
+	if len(arcs) != arcCnt {
+		return nil, fmt.Errorf("%v arcs are read, should be %v!", len(arcs), arcCnt)
+	}
+	if dummy > nodeCnt {
+		return nil, fmt.Errorf("invalid dummy node %v!", dummy)
+	}
+	dummy -= 1 // -2 if there is none
+
+	// producer/consumer index of the nodes
+	index := make([]int, nodeCnt)
+	for v, x := range supply {
+		if v == dummy {
+			continue
+		}
+		if x > 0 {
+			index[v] = len(in.Supply)
+			in.Supply = append(in.Supply, x)
+		} else if x < 0 {
+			index[v] = len(in.Demand)
+			in.Demand = append(in.Demand, -x)
+		} else {
+			return nil, fmt.Errorf("node %v has no supply/demand!", v+1)
+		}
+	}
+	sLen, dLen := len(in.Supply), len(in.Demand)
+	in.Costs = make([][]float64, sLen)
+	caps := make([][]float64, sLen)
+	for i := 0; i < sLen; i++ {
+		in.Costs[i] = make([]float64, dLen)
+		caps[i] = make([]float64, dLen)
+		for j := 0; j < dLen; j++ {
+			in.Costs[i][j] = math.Inf(1)
+			caps[i][j] = math.Inf(1)
+		}
+	}
+	capacitated := false
+	var penalties []float64
+	if dummy >= 0 {
+		if supply[dummy] < 0 {
+			penalties = make([]float64, sLen)
+		} else {
+			penalties = make([]float64, dLen)
+		}
+		for k := range penalties {
+			penalties[k] = math.Inf(1)
+		}
+	}
+	for _, a := range arcs {
+		if a.from == dummy && supply[a.to] < 0 && supply[dummy] > 0 {
+			penalties[index[a.to]] = a.cost
+			continue
+		}
+		if a.to == dummy && supply[a.from] > 0 && supply[dummy] < 0 {
+			penalties[index[a.from]] = a.cost
+			continue
+		}
+		if a.from == dummy || a.to == dummy || supply[a.from] <= 0 || supply[a.to] >= 0 {
+			return nil, fmt.Errorf("arc (%v,%v) doesn't go from a producer to a consumer!", a.from+1, a.to+1)
+		}
+		i, j := index[a.from], index[a.to]
+		if !math.IsInf(in.Costs[i][j], 1) {
+			return nil, fmt.Errorf("duplicate arc (%v,%v)!", a.from+1, a.to+1)
+		}
+		if a.cap < 0 {
+			return nil, fmt.Errorf("arc (%v,%v) has negative capacity %v!", a.from+1, a.to+1, a.cap)
+		}
+		in.Costs[i][j] = a.cost
+		if a.cap < math.Min(in.Supply[i], in.Demand[j]) {
+			caps[i][j] = a.cap
+			capacitated = true
+		}
+	}
+	if capacitated {
+		in.Capacities = caps
+	}
+	if surplusCosts != nil {
+		if len(surplusCosts) != sLen {
+			return nil, fmt.Errorf("%v surplus costs for %v producers!", len(surplusCosts), sLen)
+		}
+		in.SurplusCosts = surplusCosts
+	}
+	if shortagePenalties != nil {
+		if len(shortagePenalties) != dLen {
+			return nil, fmt.Errorf("%v shortage penalties for %v consumers!", len(shortagePenalties), dLen)
+		}
+		in.ShortagePenalties = shortagePenalties
+	}
+	for _, c := range penalties {
+		if c != 0 {
+			if supply[dummy] < 0 {
+				in.SurplusCosts = penalties
+			} else {
+				in.ShortagePenalties = penalties
+			}
+			break
+		}
+	}
+	return in, nil
+}
+
+// Write the result in the DIMACS min-cost-flow solution format, a
+// "s <objective>" line and a "f <from> <to> <flow>" line for every
+// route with flow (the nodes are numbered as in WriteDIMACS()). The
+// status is written in a "c status <status>" comment.
+func WriteResultDIMACS(w io.Writer, result *Result) error {
+	sLen, dLen := len(result.Flow), 0
+	if sLen > 0 {
+		dLen = len(result.Flow[0])
+	}
+	dummy := sLen + dLen + 1
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "c status %v\n", result.Status)
+	fmt.Fprintf(bw, "s %v\n", formatFloat(result.Objective))
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			if x := result.Flow[i][j]; x != 0 {
+				fmt.Fprintf(bw, "f %v %v %v\n", i+1, sLen+j+1, formatFloat(x))
+			}
+		}
+	}
+	for i, x := range result.Surpluses {
+		if x != 0 {
+			fmt.Fprintf(bw, "f %v %v %v\n", i+1, dummy, formatFloat(x))
+		}
+	}
+	for j, x := range result.Shortages {
+		if x != 0 {
+			fmt.Fprintf(bw, "f %v %v %v\n", dummy, sLen+j+1, formatFloat(x))
+		}
+	}
+	return bw.Flush()
+}
+
+// Read a result of the given instance in the DIMACS min-cost-flow
+// solution format, the status is StatusOptimal unless a "c status
+// <status>" comment says otherwise.
+func ReadResultDIMACS(r io.Reader, in *Instance) (*Result, error) {
+	sLen, dLen := len(in.Supply), len(in.Demand)
+	dummy := sLen + dLen + 1
+	result := &Result{
+		Status:    StatusOptimal,
+		Flow:      make([][]float64, sLen),
+		Surpluses: make([]float64, sLen),
+		Shortages: make([]float64, dLen),
+	}
+	for i := 0; i < sLen; i++ {
+		result.Flow[i] = make([]float64, dLen)
+	}
+	hasCost := false
+	sc := bufio.NewScanner(r)
+	ln := 0
+	for sc.Scan() {
+		ln += 1
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "c":
+			if len(fields) == 3 && fields[1] == "status" {
+				if err := result.Status.UnmarshalText([]byte(fields[2])); err != nil {
+					return nil, fmt.Errorf("line %v: %v!", ln, err)
+				}
+			}
+		case "s":
+			x, err := parseFloats(fields[1:])
+			if err != nil || len(x) != 1 {
+				return nil, fmt.Errorf("line %v: invalid solution line, should be \"s <objective>\"!", ln)
+			}
+			result.Objective = x[0]
+			hasCost = true
+		case "f":
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("line %v: invalid flow line, should be \"f <from> <to> <flow>\"!", ln)
+			}
+			from, err1 := strconv.Atoi(fields[1])
+			to, err2 := strconv.Atoi(fields[2])
+			x, err3 := parseFloats(fields[3:])
+			if err1 != nil || err2 != nil || err3 != nil {
+				return nil, fmt.Errorf("line %v: invalid flow line!", ln)
+			}
+			switch {
+			case from >= 1 && from <= sLen && to > sLen && to <= sLen+dLen:
+				result.Flow[from-1][to-sLen-1] = x[0]
+			case from >= 1 && from <= sLen && to == dummy:
+				result.Surpluses[from-1] = x[0]
+			case from == dummy && to > sLen && to <= sLen+dLen:
+				result.Shortages[to-sLen-1] = x[0]
+			default:
+				return nil, fmt.Errorf("line %v: invalid route (%v,%v)!", ln, from, to)
+			}
+		default:
+			return nil, fmt.Errorf("line %v: unknown line type %v!", ln, fields[0])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !hasCost {
+		return nil, fmt.Errorf("solution line is missing!")
+	}
+	return result, nil
+}
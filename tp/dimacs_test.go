@@ -0,0 +1,155 @@
+package tp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDIMACS(t *testing.T) {
+	// a hand written file, the lines are in any order after "p"
+	text := `c two plants and three stores
+c
+p min 5 5
+
+a 1 3 0 100 4
+a 1 4 0 5 6
+n 1 20
+n 2 10
+a 2 4 0 100 2
+a 2 5 0 100 3
+n 3 -10
+n 4 -12
+n 5 -8
+a 1 5 0 100 5
+`
+	in, err := ReadDIMACS(strings.NewReader(text))
+	if err != nil {
+		t.Error("failed to read", err)
+		return
+	}
+	inf := math.Inf(1)
+	expected := &Instance{
+		Name:       "two plants and three stores",
+		Supply:     []float64{20, 10},
+		Demand:     []float64{10, 12, 8},
+		Costs:      [][]float64{{4, 6, 5}, {inf, 2, 3}},
+		Capacities: [][]float64{{inf, 5, inf}, {inf, inf, inf}},
+	}
+	if !reflect.DeepEqual(in, expected) {
+		t.Error(fmt.Sprintf("read %+v, should be %+v", in, expected))
+		return
+	}
+
+	r := rand.New(rand.NewSource(19))
+	for k := 0; k < 30; k++ {
+		in := randomInstance(r)
+		var buf bytes.Buffer
+		if err := in.WriteDIMACS(&buf); err != nil {
+			t.Error(fmt.Sprintf("failed to write [%v]", in.Name), err)
+			return
+		}
+		text := buf.String()
+		read, err := ReadDIMACS(&buf)
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to read [%v]: %v", in.Name, text), err)
+			return
+		}
+
+		// the capacities which can't limit the flow are left out, it has
+		// the same solution
+		if read.Name != in.Name || !reflect.DeepEqual(read.Supply, in.Supply) || !reflect.DeepEqual(read.Demand, in.Demand) || !reflect.DeepEqual(read.Costs, in.Costs) ||
+			!reflect.DeepEqual(read.SurplusCosts, in.SurplusCosts) || !reflect.DeepEqual(read.ShortagePenalties, in.ShortagePenalties) {
+			t.Error(fmt.Sprintf("[%v] is read as %+v from %v", in.Name, read, text))
+			return
+		}
+		expected, expectedErr := solveInstance(in)
+		objective, err := solveInstance(read)
+		if (err == nil) != (expectedErr == nil) || math.Abs(objective-expected) > EPSILON {
+			t.Error(fmt.Sprintf("[%v] read from %v: cost %v (%v), should be %v (%v)", in.Name, text, objective, err, expected, expectedErr))
+			return
+		}
+
+		// the result is read back as it is written
+		if err != nil {
+			continue
+		}
+		p, _ := read.NewProblem(WithMaxIter(0))
+		result, _ := p.Solve()
+		buf.Reset()
+		if err = WriteResultDIMACS(&buf, result); err != nil {
+			t.Error(fmt.Sprintf("failed to write the result of [%v]", in.Name), err)
+			return
+		}
+		text = buf.String()
+		readResult, err := ReadResultDIMACS(&buf, read)
+		if err != nil || readResult.Status != result.Status || readResult.Objective != result.Objective ||
+			!reflect.DeepEqual(readResult.Flow, result.Flow) || !reflect.DeepEqual(readResult.Surpluses, result.Surpluses) ||
+			!reflect.DeepEqual(readResult.Shortages, result.Shortages) {
+			t.Error(fmt.Sprintf("result %+v of [%v] is read as %+v from %v", result, in.Name, readResult, text), err)
+			return
+		}
+	}
+
+	// integers are written without an exponent, the penalties of balanced
+	// inputs are kept in comments
+	balanced := &Instance{
+		Supply:            []float64{1e6, 2e6},
+		Demand:            []float64{3e6},
+		Costs:             [][]float64{{1e6}, {2}},
+		SurplusCosts:      []float64{5, inf},
+		ShortagePenalties: []float64{0.25},
+	}
+	var buf bytes.Buffer
+	if err := balanced.WriteDIMACS(&buf); err != nil {
+		t.Error("failed to write the balanced instance", err)
+		return
+	}
+	text = buf.String()
+	for _, line := range []string{"n 1 1000000\n", "a 1 3 0 3000000 1000000\n", "c surplus-costs 5 inf\n", "c shortage-penalties 0.25\n"} {
+		if !strings.Contains(text, line) {
+			t.Error(fmt.Sprintf("%q is not in %v", line, text))
+			return
+		}
+	}
+	if read, err := ReadDIMACS(&buf); err != nil || !reflect.DeepEqual(read, balanced) {
+		t.Error(fmt.Sprintf("balanced instance is read as %+v from %v", read, text), err)
+		return
+	}
+
+	// invalid inputs
+	for _, text := range []string{
+		"c surplus-costs 1 x\np min 2 1\nn 1 10\nn 2 -10\na 1 2 0 10 1\n",
+		"c shortage-penalties 1 2\np min 2 1\nn 1 10\nn 2 -10\na 1 2 0 10 1\n",
+		"n 1 10\np min 2 1\n",
+		"p max 2 1\n",
+		"p min 2 1\np min 2 1\n",
+		"p min 2 1\nn 1 10\nn 2 -10\n",
+		"p min 2 1\nn 1 10\nn 2 -10\na 1 2 1 10 1\n",
+		"p min 2 1\nn 1 10\nn 2 -10\na 2 1 0 10 1\n",
+		"p min 2 1\nn 1 10\nn 3 -10\na 1 2 0 10 1\n",
+		"p min 3 1\nn 1 10\nn 2 -10\na 1 2 0 10 1\n",
+		"p min 2 1\nn 1 10\nn 2 -10\na 1 2 0 10 x\n",
+		"p min 2 2\nn 1 10\nn 2 -10\na 1 2 0 10 1\na 1 2 0 10 1\n",
+		"p min 2 1\nn 1 10\nn 2 -10\nx 1 2 0 10 1\n",
+	} {
+		if _, err := ReadDIMACS(strings.NewReader(text)); err == nil {
+			t.Error(fmt.Sprintf("expect error for %q", text))
+			return
+		}
+	}
+	for _, text := range []string{
+		"f 1 3 10\n",
+		"s 1\nf 1 9 10\n",
+		"c status done\ns 1\n",
+	} {
+		if _, err := ReadResultDIMACS(strings.NewReader(text), expected); err == nil {
+			t.Error(fmt.Sprintf("expect error for %q", text))
+			return
+		}
+	}
+}
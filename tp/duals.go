@@ -0,0 +1,70 @@
+package tp
+
+import (
+	"fmt"
+)
+
+// Get the dual values (potentials) of the producers (u) and the
+// consumers (v), the dummy producer/consumer added for unbalanced
+// inputs is left out. They satisfy u[i]+v[j]=costs[i][j] on every
+// basic cell and, when the solution is optimal, u[i]+v[j]<=costs[i][j]
+// (within epsilon) on the others. u[0] is always 0.
+// Should be called after a successful Solve(), they are all 0 before
+// it and, unlike GetReducedCosts(), there is no check that the last
+// Solve() was optimal or that the costs/supply/demand are not set
+// since it: the duals are the ones of the last basis then.
+func (es *Problem) GetDuals() ([]float64, []float64) {
+	sLen, dLen := es.inputSize()
+	u := make([]float64, sLen)
+	v := make([]float64, dLen)
+	if es.result == nil {
+		return u, v
+	}
+	copy(u, es.u)
+	copy(v, es.v)
+	return u, v
+}
+
+// error if the basis and the duals are not the ones of an optimal
+// solution of the current inputs
+func (es *Problem) checkSolved() error {
+	if es.result == nil {
+		return fmt.Errorf("problem is not solved yet!")
+	}
+	if es.changed {
+		return fmt.Errorf("problem is changed since it was solved!")
+	}
+	if !es.result.Optimal() {
+		return fmt.Errorf("solution is not optimal: %v", es.result.Status)
+	}
+	return nil
+}
+
+// Get the reduced cost (costs[i][j]-u[i]-v[j]) matrix, the dummy
+// producer/consumer added for unbalanced inputs is left out. It is 0
+// on basic cells and not negative (within epsilon) on the others but
+// the routes at their capacity where it is not positive, it is +Inf
+// on forbidden routes.
+// Returns error if the last Solve() didn't reach the optimal solution
+// or the costs/supply/demand are set since it.
+func (es *Problem) GetReducedCosts() ([][]float64, error) {
+	if err := es.checkSolved(); err != nil {
+		return nil, err
+	}
+	sLen, dLen := es.inputSize()
+	rc := make([][]float64, sLen)
+	for i := 0; i < sLen; i++ {
+		rc[i] = make([]float64, dLen)
+		for j := 0; j < dLen; j++ {
+			if es.isForbidden(i, j) {
+				rc[i][j] = es.infinity
+				continue
+			}
+			if es.flow[i][j].basic {
+				continue
+			}
+			rc[i][j] = es.costMatrix[i][j] - es.u[i] - es.v[j]
+		}
+	}
+	return rc, nil
+}
@@ -0,0 +1,75 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDuals(t *testing.T) {
+	for _, tp := range testData {
+		for _, algorithm := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+			p, err := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algorithm))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to create the problem %v", tp.id), err)
+				return
+			}
+			if _, err = p.GetReducedCosts(); err == nil {
+				t.Error("GetReducedCosts() doesn't fail before Solve()")
+				return
+			}
+			u, v := p.GetDuals()
+			for _, x := range append(u, v...) {
+				if x != 0 {
+					t.Error(fmt.Sprintf("problem %v: duals %v/%v before Solve()", tp.id, u, v))
+					return
+				}
+			}
+			if _, err = p.Solve(); err != nil {
+				t.Error(fmt.Sprintf("failed to solve the problem %v", tp.id), err)
+				return
+			}
+			u, v = p.GetDuals()
+			if len(u) != len(tp.supply) || len(v) != len(tp.demand) {
+				t.Error(fmt.Sprintf("problem %v: got %v/%v duals", tp.id, len(u), len(v)))
+				return
+			}
+			rc, err := p.GetReducedCosts()
+			if err != nil {
+				t.Error(fmt.Sprintf("problem %v: failed to get reduced costs", tp.id), err)
+				return
+			}
+			flow := p.GetFlow()
+			for i := range u {
+				for j := range v {
+					if d := tp.costs[i][j] - u[i] - v[j]; math.Abs(d-rc[i][j]) > EPSILON {
+						t.Error(fmt.Sprintf("problem %v: reduced cost at (%v,%v) is %v, should be %v", tp.id, i, j, rc[i][j], d))
+						return
+					}
+					if rc[i][j] < -EPSILON {
+						t.Error(fmt.Sprintf("problem %v: negative reduced cost %v at (%v,%v)", tp.id, rc[i][j], i, j))
+						return
+					}
+					// complementary slackness
+					if flow[i][j] > EPSILON && math.Abs(rc[i][j]) > EPSILON {
+						t.Error(fmt.Sprintf("problem %v: (%v,%v) has flow and reduced cost %v", tp.id, i, j, rc[i][j]))
+						return
+					}
+				}
+			}
+		}
+	}
+
+	// not available for a sub-optimal solution
+	tp := randomProblem(rand.New(rand.NewSource(1)), 20, 20)
+	p, _ := NewProblem(tp.supply, tp.demand, tp.costs, WithMaxIter(1))
+	if result, err := p.Solve(); err != nil || result.Optimal() {
+		t.Error("problem should stop at the first iteration", err)
+		return
+	}
+	if _, err := p.GetReducedCosts(); err == nil {
+		t.Error("GetReducedCosts() doesn't fail for a sub-optimal solution")
+		return
+	}
+}
@@ -0,0 +1,124 @@
+package tp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInfeasible is returned (wrapped) by Solve() when the allowed routes
+// (within their capacities) cannot carry the supply/demand.
+var ErrInfeasible = errors.New("problem is infeasible")
+
+// Route from a producer to a consumer.
+type Route struct {
+	// producer (supply) index
+	From int
+
+	// consumer (demand) index
+	To int
+
+	// cost to transport one unit on this route
+	Cost float64
+}
+
+// Create a transportation problem from a sparse list of routes, any
+// producer/consumer pair without a route is forbidden.
+//
+//	supply, demand: positive float64 array/slice.
+//	routes: allowed routes, at most one for a producer/consumer pair.
+//	opts: optional args, see DefaultOptions() for the default values.
+//
+//	returns the Problem{} struct.
+func NewSparseProblem(supply, demand []float64, routes []Route, opts ...Option) (*Problem, error) {
+	sLen, dLen := len(supply), len(demand)
+	costs := make([][]float64, sLen)
+	for i := 0; i < sLen; i++ {
+		costs[i] = make([]float64, dLen)
+		for j := 0; j < dLen; j++ {
+			costs[i][j] = math.Inf(1)
+		}
+	}
+	for _, r := range routes {
+		if r.From < 0 || r.From >= sLen || r.To < 0 || r.To >= dLen {
+			return nil, fmt.Errorf("route (%v,%v) is out of range!", r.From, r.To)
+		}
+		if math.IsInf(r.Cost, 0) || math.IsNaN(r.Cost) {
+			return nil, fmt.Errorf("route (%v,%v) has invalid cost %v!", r.From, r.To, r.Cost)
+		}
+		if !math.IsInf(costs[r.From][r.To], 1) {
+			return nil, fmt.Errorf("duplicate route (%v,%v)!", r.From, r.To)
+		}
+		costs[r.From][r.To] = r.Cost
+	}
+	return NewProblem(supply, demand, costs, opts...)
+}
+
+// tells if (i,j) is a forbidden route
+func (es *Problem) isForbidden(i, j int) bool {
+	return es.forbidden != nil && es.forbidden[i][j]
+}
+
+// Mark the cells with +Inf cost as forbidden and give them a big-M cost
+// so the u,v arithmetic still works. M is big enough that an optimal
+// solution doesn't use a forbidden cell if there is a feasible solution
+// without it: moving flow off a forbidden cell along a loop saves M and
+// costs at most max+(L/2-1)*(max-min) on the other cells, where L is
+// the loop length which is at most 2*min(sLen,dLen).
+func (es *Problem) forbidRoutes() {
+	sLen, dLen := es.sLen, es.dLen
+	minCost, maxCost := math.Inf(1), math.Inf(-1)
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			c := es.costMatrix[i][j]
+			if math.IsInf(c, 1) {
+				if es.forbidden == nil {
+					es.forbiddenCells, es.forbidden = reshape(es.forbiddenCells, es.forbidden, sLen, dLen)
+				}
+				es.forbidden[i][j] = true
+				continue
+			}
+			minCost = math.Min(minCost, c)
+			maxCost = math.Max(maxCost, c)
+		}
+	}
+	if es.forbidden == nil {
+		return
+	}
+	bigM := float64(1)
+	if !math.IsInf(maxCost, -1) {
+		bigM = maxCost + float64(sLen+dLen)*(maxCost-minCost) + math.Max(1, math.Abs(maxCost))
+	}
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			if es.forbidden[i][j] {
+				es.costMatrix[i][j] = bigM
+			}
+		}
+	}
+}
+
+// Check the solution doesn't use any forbidden route. If it does, the
+// problem is infeasible when the solution is optimal, otherwise the
+// optimization stopped before it could get rid of them.
+func (es *Problem) checkForbidden(status Status) error {
+	if es.forbidden == nil {
+		return nil
+	}
+	cnt, total := 0, float64(0)
+	for i := 0; i < es.sLen; i++ {
+		for j := 0; j < es.dLen; j++ {
+			if fc := &es.flow[i][j]; es.forbidden[i][j] && (fc.basic || fc.upper) && fc.value > es.epsilon {
+				cnt += 1
+				total += fc.value
+			}
+		}
+	}
+	if cnt == 0 {
+		return nil
+	}
+	if status == StatusOptimal {
+		return fmt.Errorf("%w: the allowed routes are short of %v", ErrInfeasible, total)
+	}
+	return fmt.Errorf("optimization stopped (%v) with %v on %v forbidden routes!", status, total, cnt)
+}
@@ -0,0 +1,112 @@
+package tp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestForbiddenRoutes(t *testing.T) {
+	inf := math.Inf(1)
+
+	// the cheapest routes of fixture 0 are forbidden
+	tp := testData[0]
+	costs := copyCosts(tp.costs)
+	costs[0][1] = inf
+	costs[1][0] = inf
+	costs[2][3] = inf
+	for _, algorithm := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+		p, err := NewProblem(tp.supply, tp.demand, costs, WithAlgorithm(algorithm), WithMaxIter(0))
+		if err != nil {
+			t.Error("failed to create the problem:", err)
+			return
+		}
+		result, err := p.Solve()
+		if err != nil {
+			t.Error("failed to solve the problem:", err)
+			return
+		}
+		flow := p.GetFlow()
+		if flow[0][1] != 0 || flow[1][0] != 0 || flow[2][3] != 0 {
+			t.Error(fmt.Sprintf("%v: forbidden routes are used: %v", algorithm, flow))
+			return
+		}
+		// brute-force check with a big finite cost
+		big := copyCosts(costs)
+		big[0][1], big[1][0], big[2][3] = 1e4, 1e4, 1e4
+		q, _ := NewProblem(tp.supply, tp.demand, big, WithMaxIter(0))
+		expected, err := q.Solve()
+		if err != nil {
+			t.Error("failed to solve the problem:", err)
+			return
+		}
+		if math.Abs(result.Objective-expected.Objective) > EPSILON {
+			t.Error(fmt.Sprintf("%v: cost is %v, should be %v", algorithm, result.Objective, expected.Objective))
+			return
+		}
+		rc, err := p.GetReducedCosts()
+		if err != nil || !math.IsInf(rc[0][1], 1) {
+			t.Error(fmt.Sprintf("%v: reduced cost of a forbidden route is %v", algorithm, rc[0][1]), err)
+			return
+		}
+	}
+
+	// same problem from a sparse route list
+	routes := make([]Route, 0)
+	for i := range costs {
+		for j := range costs[i] {
+			if !math.IsInf(costs[i][j], 1) {
+				routes = append(routes, Route{From: i, To: j, Cost: costs[i][j]})
+			}
+		}
+	}
+	p, err := NewSparseProblem(tp.supply, tp.demand, routes, WithMaxIter(0))
+	if err != nil {
+		t.Error("failed to create the sparse problem:", err)
+		return
+	}
+	if _, err = p.Solve(); err != nil {
+		t.Error("failed to solve the sparse problem:", err)
+		return
+	}
+	if _, err = NewSparseProblem(tp.supply, tp.demand, append(routes, routes[0])); err == nil {
+		t.Error("duplicate route is accepted")
+		return
+	}
+	if _, err = NewSparseProblem(tp.supply, tp.demand, []Route{Route{From: 3, To: 0, Cost: 1}}); err == nil {
+		t.Error("out of range route is accepted")
+		return
+	}
+
+	// consumer 0 (demand 250) can only get from producer 0 (supply 200)
+	supply := []float64{200, 400, 500}
+	demand := []float64{250, 350, 300, 200}
+	costs = copyCosts(tp.costs)
+	costs[1][0] = inf
+	costs[2][0] = inf
+	for _, algorithm := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+		p, err := NewProblem(supply, demand, costs, WithAlgorithm(algorithm), WithMaxIter(0))
+		if err != nil {
+			t.Error("failed to create the problem:", err)
+			return
+		}
+		if _, err = p.Solve(); !errors.Is(err, ErrInfeasible) {
+			t.Error(fmt.Sprintf("%v: infeasible problem returns %v", algorithm, err))
+			return
+		}
+	}
+
+	// unmet demand goes to the dummy producer, so it is feasible when
+	// supply is less than demand
+	supply = []float64{200, 400, 400}
+	p, err = NewProblem(supply, demand, costs, WithMaxIter(0))
+	if err != nil {
+		t.Error("failed to create the problem:", err)
+		return
+	}
+	if _, err = p.Solve(); err != nil {
+		t.Error("failed to solve the unbalanced problem:", err)
+		return
+	}
+}
@@ -0,0 +1,555 @@
+package tp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+// Arithmetic is the arithmetic on T used by GenericProblem, so it can be
+// solved with integers, float32 or exact rationals (*big.Rat) instead of
+// float64.
+type Arithmetic[T any] interface {
+	// 0 of T
+	Zero() T
+
+	// x+y, x-y and x*y, they must not change x or y
+	Add(x, y T) T
+	Sub(x, y T) T
+	Mul(x, y T) T
+
+	// -1, 0 or 1 for a negative, zero or positive x, the arithmetic
+	// decides which values are 0 (e.g. within an epsilon for floats)
+	Sign(x T) int
+}
+
+// Number is the built-in numbers which NumberArithmetic supports.
+type Number interface {
+	~int | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+// NumberArithmetic is the Arithmetic of a built-in number, values
+// within Epsilon of 0 are 0. Epsilon should be 0 for integers (exact,
+// but the costs and quantities must be small enough for their products
+// and sums not to overflow) and positive for floats.
+type NumberArithmetic[T Number] struct {
+	Epsilon T
+}
+
+func (a NumberArithmetic[T]) Zero() T      { return 0 }
+func (a NumberArithmetic[T]) Add(x, y T) T { return x + y }
+func (a NumberArithmetic[T]) Sub(x, y T) T { return x - y }
+func (a NumberArithmetic[T]) Mul(x, y T) T { return x * y }
+func (a NumberArithmetic[T]) Sign(x T) int {
+	if x > a.Epsilon {
+		return 1
+	} else if x < -a.Epsilon {
+		return -1
+	}
+	return 0
+}
+
+// RatArithmetic is the exact Arithmetic of *big.Rat, every operation
+// returns a new value.
+type RatArithmetic struct{}
+
+func (RatArithmetic) Zero() *big.Rat             { return new(big.Rat) }
+func (RatArithmetic) Add(x, y *big.Rat) *big.Rat { return new(big.Rat).Add(x, y) }
+func (RatArithmetic) Sub(x, y *big.Rat) *big.Rat { return new(big.Rat).Sub(x, y) }
+func (RatArithmetic) Mul(x, y *big.Rat) *big.Rat { return new(big.Rat).Mul(x, y) }
+func (RatArithmetic) Sign(x *big.Rat) int        { return x.Sign() }
+
+// GenericResult is the Result of a GenericProblem.
+type GenericResult[T any] struct {
+	// tells if the solution is optimal or why the optimization stopped
+	Status Status
+
+	// count of iterations (pivots) run to optimize the initial solution
+	Iterations int
+
+	// total cost of the solution
+	Objective T
+
+	// the flow (transport plan) matrix
+	Flow [][]T
+
+	// supply of every producer which isn't shipped and demand of every
+	// consumer which isn't served (unbalanced inputs)
+	Surpluses []T
+	Shortages []T
+}
+
+// Optimal tells if the solution is optimal.
+func (r *GenericResult[T]) Optimal() bool {
+	return r.Status == StatusOptimal
+}
+
+// GenericProblem is a transportation problem on T, solved with the
+// transportation simplex method in the arithmetic of T. With integers
+// or *big.Rat the solution and its duals are exact, no epsilon is used.
+//
+// It is an exact-arithmetic utility apart from Problem, not a generic
+// version of it: every pivot scans all the reduced costs and searches
+// the basis tree, so it is for exact plans and optimality proofs of
+// small and medium problems, Problem is faster on big ones (float32
+// included). Unbalanced inputs are completed with a 0-cost dummy
+// producer/consumer, the initial solution is found with the northwest
+// corner method and optimized with the U,V method. There are no
+// forbidden routes, capacities, penalties, warm starts or observers.
+// The options it takes are MaxIter, TimeLimit, Trace, PivotDantzig,
+// PivotFirstEligible and PivotBland, AlgoMODI and InitNorthwestCorner
+// (or InitAuto); any other one is an error, e.g. an epsilon (the
+// arithmetic decides what is 0) or PivotPerturbation (an exact amount
+// can't be perturbed).
+type GenericProblem[T any] struct {
+	a              Arithmetic[T]
+	sLen, dLen     int // count of the inputs
+	nRows, nCols   int // with the dummy producer/consumer
+	supply, demand []T
+	costs          [][]T
+	flow           [][]T
+	basic          [][]bool
+	basis          [][2]int // the basic cells, nRows+nCols-1 of them
+	u, v           []T
+	maxIter        int
+	timeLimit      time.Duration
+	pivotRule      PivotRule
+	trace          io.Writer
+	iterCnt        int
+	adj            [][]int // basic cells of every node, kept by pivot()
+	parent         []int   // tree search, the node it is reached from
+	parentCell     []int   // and the index of the basic cell between them
+	order          []int   // the nodes in the order they are reached
+	path           []int   // basic cells of the loop of the entering cell
+}
+
+// Create a transportation problem on T.
+//
+//	supply, demand: positive values.
+//	costs: 2-D matrix, len(supply) x len(demand).
+//	a: the arithmetic of T, e.g. NumberArithmetic[int64]{} or
+//	   RatArithmetic{}.
+//	opts: optional args, see GenericProblem for the ones it uses.
+//
+//	returns the GenericProblem{} struct.
+func NewGenericProblem[T any](supply, demand []T, costs [][]T, a Arithmetic[T], opts ...Option) (*GenericProblem[T], error) {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if err := o.validateGeneric(); err != nil {
+		return nil, err
+	}
+	sLen, dLen := len(supply), len(demand)
+	if sLen == 0 || dLen == 0 {
+		return nil, fmt.Errorf("supply/demand is empty!")
+	}
+	if sLen != len(costs) {
+		return nil, fmt.Errorf("producer count doesn't match 1st dimension length of costs!")
+	}
+	for i := 0; i < sLen; i++ {
+		if dLen != len(costs[i]) {
+			return nil, fmt.Errorf("consumer count doesn't match 2nd dimension length of costs!")
+		}
+	}
+	sSum, dSum := a.Zero(), a.Zero()
+	for i, s := range supply {
+		if a.Sign(s) <= 0 {
+			return nil, fmt.Errorf("supply[%v]=%v is not positive!", i, s)
+		}
+		sSum = a.Add(sSum, s)
+	}
+	for j, d := range demand {
+		if a.Sign(d) <= 0 {
+			return nil, fmt.Errorf("demand[%v]=%v is not positive!", j, d)
+		}
+		dSum = a.Add(dSum, d)
+	}
+
+	es := &GenericProblem[T]{
+		a:         a,
+		sLen:      sLen,
+		dLen:      dLen,
+		nRows:     sLen,
+		nCols:     dLen,
+		supply:    append([]T{}, supply...),
+		demand:    append([]T{}, demand...),
+		maxIter:   o.MaxIter,
+		timeLimit: o.TimeLimit,
+		pivotRule: o.PivotRule,
+		trace:     o.Trace,
+	}
+	switch diff := a.Sub(sSum, dSum); a.Sign(diff) {
+	case 1:
+		es.nCols++
+		es.demand = append(es.demand, diff)
+	case -1:
+		es.nRows++
+		es.supply = append(es.supply, a.Sub(dSum, sSum))
+	}
+	es.costs = make([][]T, es.nRows)
+	for i := 0; i < es.nRows; i++ {
+		es.costs[i] = make([]T, es.nCols)
+		for j := 0; j < es.nCols; j++ {
+			if i < sLen && j < dLen {
+				es.costs[i][j] = costs[i][j]
+			} else {
+				es.costs[i][j] = a.Zero()
+			}
+		}
+	}
+	return es, nil
+}
+
+// the options GenericProblem doesn't take, see its doc
+func (o *Options) validateGeneric() error {
+	if o.Epsilon != EPSILON {
+		return fmt.Errorf("epsilon is not used by the generic solver, the arithmetic tells what is 0!")
+	}
+	if o.Algorithm != AlgoMODI {
+		return fmt.Errorf("algorithm %v is not supported by the generic solver!", o.Algorithm)
+	}
+	if o.InitialStrategy != InitAuto && o.InitialStrategy != InitNorthwestCorner {
+		return fmt.Errorf("initial strategy %v is not supported by the generic solver!", o.InitialStrategy)
+	}
+	if o.PivotRule != PivotDantzig && o.PivotRule != PivotFirstEligible && o.PivotRule != PivotBland {
+		return fmt.Errorf("pivot rule %v is not supported by the generic solver!", o.PivotRule)
+	}
+	if o.Observer != nil || o.SurplusCosts != nil || o.ShortagePenalties != nil {
+		return fmt.Errorf("observer and penalties are not supported by the generic solver!")
+	}
+	return nil
+}
+
+func (es *GenericProblem[T]) tracef(format string, args ...interface{}) {
+	if es.trace != nil {
+		fmt.Fprintf(es.trace, format+"\n", args...)
+	}
+}
+
+// find the initial solution with the northwest corner method, when a
+// row and a column run out together only one of them is left so the
+// next cell is a 0-value basic one and the basis is always a tree
+func (es *GenericProblem[T]) initialize() {
+	a := es.a
+	es.flow = make([][]T, es.nRows)
+	es.basic = make([][]bool, es.nRows)
+	for i := 0; i < es.nRows; i++ {
+		es.flow[i] = make([]T, es.nCols)
+		for j := range es.flow[i] {
+			es.flow[i][j] = a.Zero()
+		}
+		es.basic[i] = make([]bool, es.nCols)
+	}
+	es.basis = es.basis[:0]
+	n := es.nRows + es.nCols
+	if es.adj == nil {
+		es.adj = make([][]int, n)
+		es.parent = make([]int, n)
+		es.parentCell = make([]int, n)
+		es.order = make([]int, 0, n)
+		es.u = make([]T, es.nRows)
+		es.v = make([]T, es.nCols)
+	}
+	for k := range es.adj {
+		es.adj[k] = es.adj[k][:0]
+	}
+	s := append([]T{}, es.supply...)
+	d := append([]T{}, es.demand...)
+	i, j := 0, 0
+	for {
+		x := s[i]
+		if a.Sign(a.Sub(d[j], x)) < 0 {
+			x = d[j]
+		}
+		es.flow[i][j] = x
+		es.basic[i][j] = true
+		es.basis = append(es.basis, [2]int{i, j})
+		es.link(len(es.basis) - 1)
+		s[i], d[j] = a.Sub(s[i], x), a.Sub(d[j], x)
+		if i == es.nRows-1 && j == es.nCols-1 {
+			break
+		}
+		if j == es.nCols-1 || (i < es.nRows-1 && a.Sign(s[i]) <= 0) {
+			i++
+		} else {
+			j++
+		}
+	}
+}
+
+// add the basic cell k to the lists of its row and column (rows are
+// nodes 0..nRows-1, the columns follow them)
+func (es *GenericProblem[T]) link(k int) {
+	c := es.basis[k]
+	es.adj[c[0]] = append(es.adj[c[0]], k)
+	es.adj[es.nRows+c[1]] = append(es.adj[es.nRows+c[1]], k)
+}
+
+// remove the basic cell k from the lists of its row and column
+func (es *GenericProblem[T]) unlink(k int) {
+	c := es.basis[k]
+	for _, node := range [2]int{c[0], es.nRows + c[1]} {
+		cells := es.adj[node]
+		for x := range cells {
+			if cells[x] == k {
+				cells[x] = cells[len(cells)-1]
+				es.adj[node] = cells[:len(cells)-1]
+				break
+			}
+		}
+	}
+}
+
+// search the basis tree from the given node, parent/parentCell tell how
+// every node is reached, returns the nodes in the order they are reached
+func (es *GenericProblem[T]) searchTree(root int) []int {
+	for k := range es.parent {
+		es.parent[k] = -2
+	}
+	es.parent[root] = -1
+	order := append(es.order[:0], root)
+	for q := 0; q < len(order); q++ {
+		node := order[q]
+		for _, k := range es.adj[node] {
+			next := es.basis[k][0]
+			if node < es.nRows {
+				next = es.nRows + es.basis[k][1]
+			}
+			if es.parent[next] != -2 {
+				continue
+			}
+			es.parent[next] = node
+			es.parentCell[next] = k
+			order = append(order, next)
+		}
+	}
+	es.order = order
+	return order
+}
+
+// compute u, v with u[0]=0 and u[i]+v[j]=costs[i][j] on the basic cells
+func (es *GenericProblem[T]) computeUV() {
+	// the parents are reached first
+	order := es.searchTree(0)
+	es.u[0] = es.a.Zero()
+	for _, node := range order[1:] {
+		c := es.basis[es.parentCell[node]]
+		if node < es.nRows {
+			es.u[node] = es.a.Sub(es.costs[c[0]][c[1]], es.v[c[1]])
+		} else {
+			es.v[node-es.nRows] = es.a.Sub(es.costs[c[0]][c[1]], es.u[c[0]])
+		}
+	}
+}
+
+// find the entering cell, the one with the most negative reduced cost
+// (or the first negative one for PivotFirstEligible and PivotBland),
+// false if there is none and the solution is optimal
+func (es *GenericProblem[T]) findEntering() (int, int, bool) {
+	a := es.a
+	row, col := -1, -1
+	var best T
+	for i := 0; i < es.nRows; i++ {
+		for j := 0; j < es.nCols; j++ {
+			if es.basic[i][j] {
+				continue
+			}
+			rc := a.Sub(a.Sub(es.costs[i][j], es.u[i]), es.v[j])
+			if a.Sign(rc) >= 0 {
+				continue
+			}
+			if row < 0 || a.Sign(a.Sub(rc, best)) < 0 {
+				row, col, best = i, j, rc
+				if es.pivotRule == PivotFirstEligible || es.pivotRule == PivotBland {
+					return row, col, true
+				}
+			}
+		}
+	}
+	return row, col, row >= 0
+}
+
+// move the flow around the loop of the entering cell and replace the
+// leaving cell with it in the basis
+func (es *GenericProblem[T]) pivot(row, col int) T {
+	a := es.a
+	// the path from the column back to the row in the tree, the cells
+	// on it are -, +, -, ... starting from the column
+	es.searchTree(row)
+	path := es.path[:0]
+	for node := es.nRows + col; node != row; node = es.parent[node] {
+		path = append(path, es.parentCell[node])
+	}
+	es.path = path
+	leaving := -1
+	var theta T
+	for k := 0; k < len(path); k += 2 {
+		c := es.basis[path[k]]
+		if x := es.flow[c[0]][c[1]]; leaving < 0 || a.Sign(a.Sub(x, theta)) < 0 {
+			leaving, theta = path[k], x
+		}
+	}
+	if es.pivotRule == PivotBland {
+		// the first one row-major of the cells reaching 0
+		for k := 0; k < len(path); k += 2 {
+			c, l := es.basis[path[k]], es.basis[leaving]
+			if a.Sign(a.Sub(es.flow[c[0]][c[1]], theta)) == 0 && (c[0] < l[0] || (c[0] == l[0] && c[1] < l[1])) {
+				leaving = path[k]
+			}
+		}
+	}
+	for k, idx := range path {
+		c := es.basis[idx]
+		if k%2 == 0 {
+			es.flow[c[0]][c[1]] = a.Sub(es.flow[c[0]][c[1]], theta)
+		} else {
+			es.flow[c[0]][c[1]] = a.Add(es.flow[c[0]][c[1]], theta)
+		}
+	}
+	es.flow[row][col] = a.Add(es.flow[row][col], theta)
+	c := es.basis[leaving]
+	es.flow[c[0]][c[1]] = a.Zero()
+	es.basic[c[0]][c[1]] = false
+	es.basic[row][col] = true
+	es.unlink(leaving)
+	es.basis[leaving] = [2]int{row, col}
+	es.link(leaving)
+	return theta
+}
+
+// Solve the problem from the northwest corner solution.
+func (es *GenericProblem[T]) Solve() (*GenericResult[T], error) {
+	return es.SolveContext(context.Background())
+}
+
+// Same as Solve() but stops the optimization when the context is done,
+// like Problem.SolveContext().
+func (es *GenericProblem[T]) SolveContext(ctx context.Context) (*GenericResult[T], error) {
+	es.initialize()
+	es.iterCnt = 0
+	es.tracef("initial solution (northwest-corner): cost=%v", es.GetCost())
+	start := time.Now()
+	status := StatusOptimal
+	for {
+		es.computeUV()
+		row, col, ok := es.findEntering()
+		if !ok {
+			es.tracef("optimal after %v iterations, cost=%v", es.iterCnt, es.GetCost())
+			break
+		}
+		theta := es.pivot(row, col)
+		es.iterCnt++
+		if es.trace != nil {
+			es.tracef("iteration #%v: entering (%v,%v), theta=%v", es.iterCnt, row, col, theta)
+		}
+		if limit, reached := es.limitReached(ctx, start); reached {
+			es.tracef("stopped (%v) after %v iterations, cost=%v", limit, es.iterCnt, es.GetCost())
+			status = limit
+			es.computeUV()
+			break
+		}
+	}
+
+	result := &GenericResult[T]{
+		Status:     status,
+		Iterations: es.iterCnt,
+		Objective:  es.GetCost(),
+		Flow:       es.GetFlow(),
+		Surpluses:  make([]T, es.sLen),
+		Shortages:  make([]T, es.dLen),
+	}
+	for i := 0; i < es.sLen; i++ {
+		result.Surpluses[i] = es.a.Zero()
+		if es.nCols > es.dLen {
+			result.Surpluses[i] = es.flow[i][es.dLen]
+		}
+	}
+	for j := 0; j < es.dLen; j++ {
+		result.Shortages[j] = es.a.Zero()
+		if es.nRows > es.sLen {
+			result.Shortages[j] = es.flow[es.sLen][j]
+		}
+	}
+	return result, nil
+}
+
+// same as Problem.limitReached()
+func (es *GenericProblem[T]) limitReached(ctx context.Context, start time.Time) (Status, bool) {
+	if es.maxIter > 0 && es.iterCnt >= es.maxIter {
+		return StatusIterationLimit, true
+	}
+	if es.timeLimit > 0 && time.Since(start) >= es.timeLimit {
+		return StatusTimeLimit, true
+	}
+	select {
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return StatusTimeLimit, true
+		}
+		return StatusCancelled, true
+	default:
+	}
+	return StatusOptimal, false
+}
+
+// n zeros of T
+func (es *GenericProblem[T]) zeros(n int) []T {
+	x := make([]T, n)
+	for k := range x {
+		x[k] = es.a.Zero()
+	}
+	return x
+}
+
+// Get the total cost of the current solution, the dummy routes cost 0.
+// It is 0 before Solve() is called.
+func (es *GenericProblem[T]) GetCost() T {
+	cost := es.a.Zero()
+	if es.flow == nil {
+		return cost
+	}
+	for i := 0; i < es.sLen; i++ {
+		for j := 0; j < es.dLen; j++ {
+			if es.basic[i][j] {
+				cost = es.a.Add(cost, es.a.Mul(es.costs[i][j], es.flow[i][j]))
+			}
+		}
+	}
+	return cost
+}
+
+// Get the flow matrix, the dummy producer/consumer is left out. It is
+// all 0 before Solve() is called.
+func (es *GenericProblem[T]) GetFlow() [][]T {
+	flow := make([][]T, es.sLen)
+	for i := 0; i < es.sLen; i++ {
+		if es.flow == nil {
+			flow[i] = es.zeros(es.dLen)
+		} else {
+			flow[i] = append([]T{}, es.flow[i][:es.dLen]...)
+		}
+	}
+	return flow
+}
+
+// Get the dual values of the producers (u) and the consumers (v), same
+// as Problem.GetDuals(). They are exact with an exact arithmetic.
+// Should be called after calling Solve(), they are all 0 before it.
+func (es *GenericProblem[T]) GetDuals() ([]T, []T) {
+	if es.u == nil {
+		return es.zeros(es.sLen), es.zeros(es.dLen)
+	}
+	return append([]T{}, es.u[:es.sLen]...), append([]T{}, es.v[:es.dLen]...)
+}
+
+// Get the count of iterations (pivots) run to optimize the initial
+// solution, should be called after calling Solve().
+func (es *GenericProblem[T]) GetIterationCount() int {
+	return es.iterCnt
+}
@@ -0,0 +1,251 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// convert the float64 values with f, false if one can't be converted
+func convert[T any](x []float64, f func(float64) (T, bool)) ([]T, bool) {
+	y := make([]T, len(x))
+	for k := range x {
+		var ok bool
+		if y[k], ok = f(x[k]); !ok {
+			return nil, false
+		}
+	}
+	return y, true
+}
+
+func convertMatrix[T any](x [][]float64, f func(float64) (T, bool)) ([][]T, bool) {
+	y := make([][]T, len(x))
+	for k := range x {
+		var ok bool
+		if y[k], ok = convert(x[k], f); !ok {
+			return nil, false
+		}
+	}
+	return y, true
+}
+
+func toInt64(x float64) (int64, bool)     { return int64(x), x == math.Trunc(x) }
+func toFloat32(x float64) (float32, bool) { return float32(x), true }
+func toRat(x float64) (*big.Rat, bool)    { return new(big.Rat).SetFloat64(x), true }
+
+// solve tp with the arithmetic, returns nil if its values can't be
+// converted to T
+func solveGeneric[T any](tp *TestProblem, f func(float64) (T, bool), a Arithmetic[T], opts ...Option) (*GenericProblem[T], *GenericResult[T], error) {
+	supply, ok1 := convert(tp.supply, f)
+	demand, ok2 := convert(tp.demand, f)
+	costs, ok3 := convertMatrix(tp.costs, f)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, nil, nil
+	}
+	p, err := NewGenericProblem(supply, demand, costs, a, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := p.Solve()
+	return p, result, err
+}
+
+// the rational solution is exactly optimal: the flow is not negative and
+// matches the supply/demand (the smaller side when unbalanced), and the
+// reduced costs of the duals are not negative and 0 where there is flow
+func checkExact(tp *TestProblem, p *GenericProblem[*big.Rat], result *GenericResult[*big.Rat]) error {
+	rat := func(x float64) *big.Rat { return new(big.Rat).SetFloat64(x) }
+	sSum, dSum := new(big.Rat), new(big.Rat)
+	for _, s := range tp.supply {
+		sSum.Add(sSum, rat(s))
+	}
+	for _, d := range tp.demand {
+		dSum.Add(dSum, rat(d))
+	}
+	u, v := p.GetDuals()
+	// the duals of the dummy consumer/producer
+	vd, ud := new(big.Rat), new(big.Rat)
+	if p.nCols > p.dLen {
+		vd = p.v[p.dLen]
+	}
+	if p.nRows > p.sLen {
+		ud = p.u[p.sLen]
+	}
+	cost := new(big.Rat)
+	for i := range tp.supply {
+		row := new(big.Rat).Add(new(big.Rat), result.Surpluses[i])
+		for j := range tp.demand {
+			x := result.Flow[i][j]
+			if x.Sign() < 0 {
+				return fmt.Errorf("flow[%v][%v]=%v is negative", i, j, x)
+			}
+			row.Add(row, x)
+			rc := new(big.Rat).Sub(rat(tp.costs[i][j]), u[i])
+			rc.Sub(rc, v[j])
+			if rc.Sign() < 0 || (x.Sign() > 0 && rc.Sign() != 0) {
+				return fmt.Errorf("reduced cost of (%v,%v) is %v with flow %v", i, j, rc, x)
+			}
+			cost.Add(cost, new(big.Rat).Mul(x, rat(tp.costs[i][j])))
+		}
+		if row.Cmp(rat(tp.supply[i])) != 0 {
+			return fmt.Errorf("producer %v ships %v, should be %v", i, row, tp.supply[i])
+		}
+		if sSum.Cmp(dSum) > 0 && new(big.Rat).Add(u[i], vd).Sign() > 0 {
+			return fmt.Errorf("reduced cost of the surplus of %v is negative", i)
+		}
+	}
+	for j := range tp.demand {
+		col := new(big.Rat).Add(new(big.Rat), result.Shortages[j])
+		for i := range tp.supply {
+			col.Add(col, result.Flow[i][j])
+		}
+		if col.Cmp(rat(tp.demand[j])) != 0 {
+			return fmt.Errorf("consumer %v gets %v, should be %v", j, col, tp.demand[j])
+		}
+		if dSum.Cmp(sSum) > 0 && new(big.Rat).Add(v[j], ud).Sign() > 0 {
+			return fmt.Errorf("reduced cost of the shortage of %v is negative", j)
+		}
+	}
+	if cost.Cmp(result.Objective) != 0 {
+		return fmt.Errorf("cost of the flow is %v, the objective is %v", cost, result.Objective)
+	}
+	return nil
+}
+
+func TestGeneric(t *testing.T) {
+	problems := append([]*TestProblem{}, testData...)
+	r := rand.New(rand.NewSource(21))
+	for k := 0; k < 40; k++ {
+		problems = append(problems, randomProblem(r, 1+r.Intn(10), 1+r.Intn(10)))
+	}
+	for _, tp := range problems {
+		p, err := solveWith(tp, 0, AlgoNetworkSimplex)
+		if err != nil {
+			t.Error(fmt.Sprintf("[%v] failed to solve", tp.name), err)
+			return
+		}
+		expected := p.GetCost()
+
+		// float64 gets the same cost as Problem, and its solution is
+		// certified by Verify()
+		p64, r64, err := solveGeneric(tp, func(x float64) (float64, bool) { return x, true }, NumberArithmetic[float64]{Epsilon: EPSILON}, WithMaxIter(0))
+		if err != nil || !r64.Optimal() || math.Abs(r64.Objective-expected) > 1e-6*math.Max(1, expected) {
+			t.Error(fmt.Sprintf("[%v] float64 result %+v, cost should be %v", tp.name, r64, expected), err)
+			return
+		}
+		u, v := p64.GetDuals()
+		report, err := Verify(&Instance{Supply: tp.supply, Demand: tp.demand, Costs: tp.costs}, r64.Flow, u, v, 0)
+		if err != nil || !report.Optimal() {
+			t.Error(fmt.Sprintf("[%v] float64 result isn't verified: %v", tp.name, report), err)
+			return
+		}
+
+		// float32 within its precision
+		_, r32, err := solveGeneric(tp, toFloat32, NumberArithmetic[float32]{Epsilon: 1e-4}, WithMaxIter(0))
+		if err != nil || !r32.Optimal() || math.Abs(float64(r32.Objective)-expected) > 1e-4*math.Max(1, expected) {
+			t.Error(fmt.Sprintf("[%v] float32 result %+v, cost should be %v", tp.name, r32, expected), err)
+			return
+		}
+
+		// int64 exactly, the fixtures with fractions are left out
+		_, rInt, err := solveGeneric(tp, toInt64, NumberArithmetic[int64]{}, WithMaxIter(0))
+		if err != nil || (rInt != nil && (!rInt.Optimal() || float64(rInt.Objective) != expected)) {
+			t.Error(fmt.Sprintf("[%v] int64 result %+v, cost should be %v", tp.name, rInt, expected), err)
+			return
+		}
+
+		// rationals exactly, with the optimality proven exactly
+		pRat, rRat, err := solveGeneric(tp, toRat, RatArithmetic{}, WithMaxIter(0))
+		if err == nil && !rRat.Optimal() {
+			err = fmt.Errorf("status is %v", rRat.Status)
+		}
+		if err == nil {
+			err = checkExact(tp, pRat, rRat)
+		}
+		if err != nil {
+			t.Error(fmt.Sprintf("[%v] rational result", tp.name), err)
+			return
+		}
+		if x, _ := rRat.Objective.Float64(); math.Abs(x-expected) > 1e-6*math.Max(1, expected) {
+			t.Error(fmt.Sprintf("[%v] rational cost %v, should be %v", tp.name, rRat.Objective, expected))
+			return
+		}
+	}
+
+	// exact where float64 rounds: thirds shipped at tenths
+	third := big.NewRat(1, 3)
+	p, err := NewGenericProblem([]*big.Rat{third, third, third}, []*big.Rat{big.NewRat(1, 1)},
+		[][]*big.Rat{{big.NewRat(1, 10)}, {big.NewRat(2, 10)}, {big.NewRat(3, 10)}}, RatArithmetic{})
+	if err != nil {
+		t.Error("failed to create the rational problem", err)
+		return
+	}
+	// all 0 before it is solved
+	u, v := p.GetDuals()
+	if flow := p.GetFlow(); p.GetCost().Sign() != 0 || len(flow) != 3 || flow[2][0].Sign() != 0 || len(u) != 3 || u[0].Sign() != 0 || len(v) != 1 || v[0].Sign() != 0 {
+		t.Error(fmt.Sprintf("before Solve(): cost %v, flow %v, duals %v, %v", p.GetCost(), flow, u, v))
+		return
+	}
+	result, _ := p.Solve()
+	if result.Objective.Cmp(big.NewRat(1, 5)) != 0 {
+		t.Error(fmt.Sprintf("rational cost is %v, should be exactly 1/5", result.Objective))
+		return
+	}
+
+	// the iteration limit stops it
+	tp := testData[4]
+	_, rInt, _ := solveGeneric(tp, toInt64, NumberArithmetic[int64]{}, WithMaxIter(1))
+	if rInt.Status != StatusIterationLimit || rInt.Iterations != 1 {
+		t.Error(fmt.Sprintf("result with max 1 iteration: %+v", rInt))
+		return
+	}
+
+	// the basis tree is kept by the pivots, solving again allocates the
+	// matrices and the result but nothing per pivot
+	tp = randomProblem(rand.New(rand.NewSource(5)), 30, 40)
+	p64, r64, _ := solveGeneric(tp, toInt64, NumberArithmetic[int64]{}, WithMaxIter(0))
+	allocs := testing.AllocsPerRun(3, func() { p64.Solve() })
+	if bound := float64(4 * len(tp.supply)); allocs > bound || r64.Iterations < 100 {
+		t.Error(fmt.Sprintf("solving again allocates %v times (more than %v) for %v pivots", allocs, bound, r64.Iterations))
+		return
+	}
+
+	// invalid inputs
+	a := NumberArithmetic[int64]{}
+	for _, c := range []struct {
+		supply, demand []int64
+		costs          [][]int64
+	}{
+		{[]int64{}, []int64{1}, [][]int64{}},
+		{[]int64{1}, []int64{1}, [][]int64{{1}, {1}}},
+		{[]int64{1}, []int64{1}, [][]int64{{1, 2}}},
+		{[]int64{0}, []int64{1}, [][]int64{{1}}},
+		{[]int64{1}, []int64{-1}, [][]int64{{1}}},
+	} {
+		if _, err := NewGenericProblem(c.supply, c.demand, c.costs, a); err == nil {
+			t.Error(fmt.Sprintf("expect error for %v, %v, %v", c.supply, c.demand, c.costs))
+			return
+		}
+	}
+	if _, err := NewGenericProblem([]int64{1}, []int64{1}, [][]int64{{1}}, a, WithMaxIter(-1)); err == nil {
+		t.Error("expect error for invalid options")
+		return
+	}
+
+	// the options of Problem only are not ignored
+	for k, opt := range []Option{
+		WithEpsilon(1e-9),
+		WithAlgorithm(AlgoNetworkSimplex),
+		WithInitialStrategy(InitVogel),
+		WithPivotRule(PivotPerturbation),
+		WithPivotRule(PivotBlockSearch),
+		WithSurplusCosts([]float64{1}),
+	} {
+		if _, err := NewGenericProblem([]int64{1}, []int64{1}, [][]int64{{1}}, a, opt); err == nil {
+			t.Error(fmt.Sprintf("expect error for unsupported option #%v", k))
+			return
+		}
+	}
+}
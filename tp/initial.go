@@ -0,0 +1,295 @@
+package tp
+
+import (
+	"math"
+)
+
+// Strategy used to find the initial feasible solution.
+type InitialStrategy int
+
+const (
+	// the "Least Cost" (minimal cost) method
+	InitLeastCost InitialStrategy = iota
+
+	// Vogel's approximation method
+	InitVogel
+
+	// the "Northwest Corner" method
+	InitNorthwestCorner
+
+	// the "Row Minimum" method
+	InitRowMinimum
+
+	// the northwest corner method within the capacities, what it can't
+	// place goes on the big-M routes of an artificial producer/consumer.
+	// It is what capacitated problems start from and can't be selected
+	// with WithInitialStrategy()
+	InitArtificial
+
+	// the optimal matching of the assignment solver, only for inputs
+	// where all supplies and demands are the same amount, "Least Cost"
+	// is used for the others
+	InitAssignment
+
+	// InitAssignment if all supplies and demands are the same amount,
+	// InitLeastCost otherwise
+	InitAuto
+)
+
+func (s InitialStrategy) String() string {
+	switch s {
+	case InitLeastCost:
+		return "least-cost"
+	case InitVogel:
+		return "vogel"
+	case InitNorthwestCorner:
+		return "northwest-corner"
+	case InitRowMinimum:
+		return "row-minimum"
+	case InitArtificial:
+		return "artificial"
+	case InitAssignment:
+		return "assignment"
+	case InitAuto:
+		return "auto"
+	default:
+		return "unknown"
+	}
+}
+
+func (s InitialStrategy) valid() bool {
+	return s >= InitLeastCost && s <= InitRowMinimum || s == InitAssignment || s == InitAuto
+}
+
+// find the initial solution with the selected strategy, returns the
+// count of basic cells in it
+func (es *Problem) findFeasibleSolution() int {
+
+	// work on copies so the inputs are kept as they are
+	s, d := es.supplyLeft, es.demandLeft
+	copy(s, es.supply)
+	copy(d, es.demand)
+
+	flowCnt := 0
+	switch es.strategy {
+	case InitVogel:
+		flowCnt = es.findVogelSolution(s, d)
+	case InitNorthwestCorner:
+		flowCnt = es.findNorthwestCornerSolution(s, d)
+	case InitRowMinimum:
+		flowCnt = es.findRowMinimumSolution(s, d)
+	case InitArtificial:
+		flowCnt = es.findArtificialSolution(s, d)
+	case InitAssignment:
+		flowCnt = es.findAssignmentSolution(s, d)
+	default:
+		flowCnt = es.findLeastCostSolution(s, d)
+	}
+
+	return flowCnt
+}
+
+// move as much as possible from supply s[i] to demand d[j] and set
+// (i,j) as a basic cell, returns the quatity moved
+func (es *Problem) allocate(s, d []float64, i, j int) float64 {
+	epsilon := es.epsilon
+	diff := s[i] - d[j]
+	q := float64(0)
+	if diff > epsilon { // s > d
+		q = d[j]
+		s[i] = diff
+		d[j] = 0
+	} else if diff < -epsilon { // s < d
+		q = s[i]
+		s[i] = 0
+		d[j] = -diff
+	} else { // s == d
+		q = s[i]
+		s[i] = 0
+		d[j] = 0
+	}
+	fc := &es.flow[i][j]
+	fc.basic = true
+	fc.value = q
+	return q
+}
+
+// find the initial solution with the "Minimal Cost" method
+func (es *Problem) findLeastCostSolution(s, d []float64) int {
+	sLen, dLen, epsilon, infinity := es.sLen, es.dLen, es.epsilon, es.infinity
+
+	quatity := es.quatity
+	flowCnt := 0
+
+	for {
+		// the least-cost (selected) row/column index
+		si, sj := -1, -1
+		var minCost = infinity
+		// loop to find the least cost row/column
+		for i := 0; i < sLen; i++ {
+			// skip row if supply is "0"
+			if s[i] <= 0 {
+				continue
+			}
+			for j := 0; j < dLen; j++ {
+				// skip column if demand is "0"
+				if d[j] <= 0 {
+					continue
+				}
+				cost := es.costMatrix[i][j]
+				if cost < minCost {
+					si, sj, minCost = i, j, cost
+				} else if cost == minCost {
+					// for same cost cell, choose the one which
+					// transports more
+					sq := math.Min(s[si], d[sj])
+					q := math.Min(s[i], d[j])
+					if q > sq {
+						si, sj = i, j
+					}
+				}
+			}
+		}
+		if si < 0 {
+			break
+		}
+		// substract the selected quatity from supply/demand and
+		// remove it from total quatity
+		quatity = quatity - es.allocate(s, d, si, sj)
+		flowCnt += 1
+
+		if quatity <= epsilon {
+			break
+		}
+	}
+	return flowCnt
+}
+
+// find the initial solution with the "Northwest Corner" method
+func (es *Problem) findNorthwestCornerSolution(s, d []float64) int {
+	sLen, dLen := es.sLen, es.dLen
+	flowCnt := 0
+	i, j := 0, 0
+	for i < sLen && j < dLen {
+		es.allocate(s, d, i, j)
+		flowCnt += 1
+		// move to the next row/column once it is exhausted,
+		// both if they are exhausted at the same time
+		if s[i] <= 0 {
+			i += 1
+		}
+		if d[j] <= 0 {
+			j += 1
+		}
+	}
+	return flowCnt
+}
+
+// find the initial solution with the "Row Minimum" method
+func (es *Problem) findRowMinimumSolution(s, d []float64) int {
+	sLen, dLen, infinity := es.sLen, es.dLen, es.infinity
+	flowCnt := 0
+	for i := 0; i < sLen; i++ {
+		for s[i] > 0 {
+			// the least cost column which still has demand
+			sj := -1
+			minCost := infinity
+			for j := 0; j < dLen; j++ {
+				if d[j] <= 0 {
+					continue
+				}
+				if cost := es.costMatrix[i][j]; cost < minCost {
+					sj, minCost = j, cost
+				}
+			}
+			if sj < 0 {
+				break
+			}
+			es.allocate(s, d, i, sj)
+			flowCnt += 1
+		}
+	}
+	return flowCnt
+}
+
+// find the initial solution with Vogel's approximation method
+func (es *Problem) findVogelSolution(s, d []float64) int {
+	sLen, dLen, epsilon, infinity := es.sLen, es.dLen, es.epsilon, es.infinity
+
+	quatity := es.quatity
+	flowCnt := 0
+
+	for {
+		// the cell to allocate, it is the least cost cell in the
+		// row/column with the biggest penalty, the penalty is the
+		// difference between the two least costs in a row/column
+		si, sj := -1, -1
+		pMax, pMin := float64(-1), infinity
+		for i := 0; i < sLen; i++ {
+			if s[i] <= 0 {
+				continue
+			}
+			min1, min2, mj := infinity, infinity, -1
+			for j := 0; j < dLen; j++ {
+				if d[j] <= 0 {
+					continue
+				}
+				cost := es.costMatrix[i][j]
+				if cost < min1 {
+					min1, min2, mj = cost, min1, j
+				} else if cost < min2 {
+					min2 = cost
+				}
+			}
+			if mj < 0 {
+				continue
+			}
+			// only one column left, the least cost is the penalty
+			p := min1
+			if min2 < infinity {
+				p = min2 - min1
+			}
+			// break ties with the lower cost
+			if p > pMax || (p == pMax && min1 < pMin) {
+				si, sj, pMax, pMin = i, mj, p, min1
+			}
+		}
+		for j := 0; j < dLen; j++ {
+			if d[j] <= 0 {
+				continue
+			}
+			min1, min2, mi := infinity, infinity, -1
+			for i := 0; i < sLen; i++ {
+				if s[i] <= 0 {
+					continue
+				}
+				cost := es.costMatrix[i][j]
+				if cost < min1 {
+					min1, min2, mi = cost, min1, i
+				} else if cost < min2 {
+					min2 = cost
+				}
+			}
+			if mi < 0 {
+				continue
+			}
+			p := min1
+			if min2 < infinity {
+				p = min2 - min1
+			}
+			if p > pMax || (p == pMax && min1 < pMin) {
+				si, sj, pMax, pMin = mi, j, p, min1
+			}
+		}
+		if si < 0 {
+			break
+		}
+		quatity = quatity - es.allocate(s, d, si, sj)
+		flowCnt += 1
+
+		if quatity <= epsilon {
+			break
+		}
+	}
+	return flowCnt
+}
@@ -0,0 +1,90 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+var strategies = []InitialStrategy{
+	InitLeastCost,
+	InitVogel,
+	InitNorthwestCorner,
+	InitRowMinimum,
+}
+
+// check the initial solution ships exactly the (adjusted) supply and
+// demand with non-negative flow
+func checkFeasible(p *Problem) error {
+	for i := 0; i < p.sLen; i++ {
+		sum := float64(0)
+		for j := 0; j < p.dLen; j++ {
+			fc := p.flow[i][j]
+			if fc.value < 0 {
+				return fmt.Errorf("negative flow %v at (%v,%v)", fc.value, i, j)
+			}
+			if !fc.basic && fc.value != 0 {
+				return fmt.Errorf("non-basic cell (%v,%v) has flow %v", i, j, fc.value)
+			}
+			sum += fc.value
+		}
+		if math.Abs(sum-p.supply[i]) > EPSILON {
+			return fmt.Errorf("row %v ships %v, supply is %v", i, sum, p.supply[i])
+		}
+	}
+	for j := 0; j < p.dLen; j++ {
+		sum := float64(0)
+		for i := 0; i < p.sLen; i++ {
+			sum += p.flow[i][j].value
+		}
+		if math.Abs(sum-p.demand[j]) > EPSILON {
+			return fmt.Errorf("column %v gets %v, demand is %v", j, sum, p.demand[j])
+		}
+	}
+	return nil
+}
+
+func TestInitialStrategies(t *testing.T) {
+	problems := make([]*TestProblem, 0)
+	problems = append(problems, testData...)
+	r := rand.New(rand.NewSource(2))
+	for k := 0; k < 10; k++ {
+		problems = append(problems, randomProblem(r, 2+r.Intn(30), 2+r.Intn(30)))
+	}
+	for _, tp := range problems {
+		var cost float64
+		for k := 0; k < 2*len(strategies); k++ {
+			strategy, algorithm := strategies[k/2], Algorithm(k%2)
+			p, err := CreateProblem(tp.supply, tp.demand, tp.costs, 0, EPSILON, float64(AlgoNetworkSimplex), float64(strategy))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to create the problem [%v]", tp.name), err)
+				return
+			}
+			p.findFeasibleSolution()
+			if err = checkFeasible(p); err != nil {
+				t.Error(fmt.Sprintf("[%v] %v initial solution is infeasible:", tp.name, strategy), err)
+				return
+			}
+			p, err = CreateProblem(tp.supply, tp.demand, tp.costs, 0, EPSILON, float64(algorithm), float64(strategy))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to create the problem [%v]", tp.name), err)
+				return
+			}
+			if _, err = p.Solve(); err != nil {
+				t.Error(fmt.Sprintf("failed to solve the problem [%v] from %v", tp.name, strategy), err)
+				return
+			}
+			if p.GetInitialStrategy() != strategy {
+				t.Error(fmt.Sprintf("[%v] initial strategy is %v, should be %v", tp.name, p.GetInitialStrategy(), strategy))
+				return
+			}
+			if k == 0 {
+				cost = p.GetCost()
+			} else if c := p.GetCost(); math.Abs(c-cost) > EPSILON*math.Max(1, cost) {
+				t.Error(fmt.Sprintf("[%v] cost from %v is %v, should be %v", tp.name, strategy, c, cost))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package tp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Instance holds the inputs of a transportation problem so it can be
+// written to a file and read back (to replay a production problem or
+// share a failing one).
+//
+// The JSON format is an object with these fields, math.Inf(1) (a
+// forbidden route, an uncapacitated route, or an infinite penalty) is
+// written as null:
+//
+//	name:               optional name of the problem
+//	supply:             [s0, s1, ...]
+//	demand:             [d0, d1, ...]
+//	costs:              [[c00, c01, ...], [c10, c11, ...], ...]
+//	capacities:         optional, same shape as costs
+//	surplus_costs:      optional, one for each producer
+//	shortage_penalties: optional, one for each consumer
+type Instance struct {
+	Name              string
+	Supply            []float64
+	Demand            []float64
+	Costs             [][]float64
+	Capacities        [][]float64
+	SurplusCosts      []float64
+	ShortagePenalties []float64
+}
+
+// Create the problem of the instance, NewCapacitatedProblem() if it has
+// capacities, NewProblem() otherwise. The surplus costs and shortage
+// penalties of the instance come before the given opts.
+func (in *Instance) NewProblem(opts ...Option) (*Problem, error) {
+	es := &Problem{}
+	if err := in.reset(es, opts...); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// reset the problem to the instance, same as NewProblem() but in the
+// memory of es
+func (in *Instance) reset(es *Problem, opts ...Option) error {
+	all := make([]Option, 0, len(opts)+2)
+	if in.SurplusCosts != nil {
+		all = append(all, WithSurplusCosts(in.SurplusCosts))
+	}
+	if in.ShortagePenalties != nil {
+		all = append(all, WithShortagePenalties(in.ShortagePenalties))
+	}
+	all = append(all, opts...)
+	if in.Capacities != nil {
+		return es.ResetCapacitated(in.Supply, in.Demand, in.Costs, in.Capacities, all...)
+	}
+	return es.Reset(in.Supply, in.Demand, in.Costs, all...)
+}
+
+// float64 which is null in JSON if it is math.Inf(1)
+type nullableFloat float64
+
+func (x nullableFloat) MarshalJSON() ([]byte, error) {
+	if math.IsInf(float64(x), 1) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(float64(x))
+}
+
+func (x *nullableFloat) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*x = nullableFloat(math.Inf(1))
+		return nil
+	}
+	return json.Unmarshal(b, (*float64)(x))
+}
+
+func toNullable(x []float64) []nullableFloat {
+	if x == nil {
+		return nil
+	}
+	y := make([]nullableFloat, len(x))
+	for k := range x {
+		y[k] = nullableFloat(x[k])
+	}
+	return y
+}
+
+func fromNullable(x []nullableFloat) []float64 {
+	if x == nil {
+		return nil
+	}
+	y := make([]float64, len(x))
+	for k := range x {
+		y[k] = float64(x[k])
+	}
+	return y
+}
+
+func toNullableMatrix(x [][]float64) [][]nullableFloat {
+	if x == nil {
+		return nil
+	}
+	y := make([][]nullableFloat, len(x))
+	for k := range x {
+		y[k] = toNullable(x[k])
+	}
+	return y
+}
+
+func fromNullableMatrix(x [][]nullableFloat) [][]float64 {
+	if x == nil {
+		return nil
+	}
+	y := make([][]float64, len(x))
+	for k := range x {
+		y[k] = fromNullable(x[k])
+	}
+	return y
+}
+
+type jsonInstance struct {
+	Name              string            `json:"name,omitempty"`
+	Supply            []float64         `json:"supply"`
+	Demand            []float64         `json:"demand"`
+	Costs             [][]nullableFloat `json:"costs"`
+	Capacities        [][]nullableFloat `json:"capacities,omitempty"`
+	SurplusCosts      []nullableFloat   `json:"surplus_costs,omitempty"`
+	ShortagePenalties []nullableFloat   `json:"shortage_penalties,omitempty"`
+}
+
+func (in *Instance) toJSON() *jsonInstance {
+	return &jsonInstance{
+		Name:              in.Name,
+		Supply:            in.Supply,
+		Demand:            in.Demand,
+		Costs:             toNullableMatrix(in.Costs),
+		Capacities:        toNullableMatrix(in.Capacities),
+		SurplusCosts:      toNullable(in.SurplusCosts),
+		ShortagePenalties: toNullable(in.ShortagePenalties),
+	}
+}
+
+func (in *Instance) MarshalJSON() ([]byte, error) {
+	return json.Marshal(in.toJSON())
+}
+
+func (in *Instance) UnmarshalJSON(b []byte) error {
+	var j jsonInstance
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*in = Instance{
+		Name:              j.Name,
+		Supply:            j.Supply,
+		Demand:            j.Demand,
+		Costs:             fromNullableMatrix(j.Costs),
+		Capacities:        fromNullableMatrix(j.Capacities),
+		SurplusCosts:      fromNullable(j.SurplusCosts),
+		ShortagePenalties: fromNullable(j.ShortagePenalties),
+	}
+	return nil
+}
+
+// Write the instance in the JSON format.
+func (in *Instance) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(in.toJSON())
+}
+
+// Read an instance in the JSON format, the inputs are checked when the
+// problem is created.
+func ReadJSON(r io.Reader) (*Instance, error) {
+	in := &Instance{}
+	if err := json.NewDecoder(r).Decode(in); err != nil {
+		return nil, fmt.Errorf("failed to read the instance: %w", err)
+	}
+	return in, nil
+}
+
+// Write the result in the JSON format, its fields with the snake_case
+// names (status is the text of Status).
+func WriteResultJSON(w io.Writer, result *Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// Read a result in the JSON format.
+func ReadResultJSON(r io.Reader) (*Result, error) {
+	result := &Result{}
+	if err := json.NewDecoder(r).Decode(result); err != nil {
+		return nil, fmt.Errorf("failed to read the result: %w", err)
+	}
+	return result, nil
+}
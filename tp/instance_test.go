@@ -0,0 +1,165 @@
+package tp
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the test problems to testdata")
+
+// random instance with forbidden routes, capacities and penalties
+func randomInstance(r *rand.Rand) *Instance {
+	tp := randomProblem(r, 2+r.Intn(8), 2+r.Intn(8))
+	in := &Instance{
+		Name:   tp.name,
+		Supply: tp.supply,
+		Demand: tp.demand,
+		Costs:  tp.costs,
+	}
+	for i := range in.Costs {
+		for j := range in.Costs[i] {
+			if r.Intn(8) == 0 {
+				in.Costs[i][j] = math.Inf(1)
+			}
+		}
+	}
+	if r.Intn(2) == 0 {
+		in.Capacities, _ = randomCapacities(r, tp)
+	}
+	if r.Intn(2) == 0 {
+		in.SurplusCosts = randomPenalties(r, len(in.Supply))
+		in.ShortagePenalties = randomPenalties(r, len(in.Demand))
+	}
+	return in
+}
+
+// the objective of the instance, or the error solving it
+func solveInstance(in *Instance) (float64, error) {
+	p, err := in.NewProblem(WithMaxIter(0))
+	if err != nil {
+		return 0, err
+	}
+	result, err := p.Solve()
+	if err != nil {
+		return 0, err
+	}
+	return result.Objective, nil
+}
+
+func TestInstanceJSON(t *testing.T) {
+	// the test problems are in testdata, run "go test -update" to write
+	// them after changing testData
+	for _, tp := range testData {
+		in := &Instance{Name: tp.name, Supply: tp.supply, Demand: tp.demand, Costs: tp.costs}
+		path := filepath.Join("testdata", fmt.Sprintf("problem-%02d.json", tp.id))
+		if *update {
+			f, err := os.Create(path)
+			if err != nil {
+				t.Error("failed to create", path, err)
+				return
+			}
+			err = in.WriteJSON(f)
+			f.Close()
+			if err != nil {
+				t.Error("failed to write", path, err)
+				return
+			}
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Error("failed to open", path, err)
+			return
+		}
+		read, err := ReadJSON(f)
+		f.Close()
+		if err != nil {
+			t.Error("failed to read", path, err)
+			return
+		}
+		if !reflect.DeepEqual(in, read) {
+			t.Error(fmt.Sprintf("%v is %+v, should be %+v", path, read, in))
+			return
+		}
+	}
+
+	r := rand.New(rand.NewSource(18))
+	for k := 0; k < 30; k++ {
+		in := randomInstance(r)
+		var buf bytes.Buffer
+		if err := in.WriteJSON(&buf); err != nil {
+			t.Error(fmt.Sprintf("failed to write [%v]", in.Name), err)
+			return
+		}
+		text := buf.String()
+		read, err := ReadJSON(&buf)
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to read [%v]: %v", in.Name, text), err)
+			return
+		}
+		if !reflect.DeepEqual(in, read) {
+			t.Error(fmt.Sprintf("[%v] is read as %+v from %v", in.Name, read, text))
+			return
+		}
+
+		// the result is read back as it is written
+		p, err := read.NewProblem(WithMaxIter(0))
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to create [%v]", in.Name), err)
+			return
+		}
+		result, err := p.Solve()
+		if err != nil {
+			continue
+		}
+		buf.Reset()
+		if err = WriteResultJSON(&buf, result); err != nil {
+			t.Error(fmt.Sprintf("failed to write the result of [%v]", in.Name), err)
+			return
+		}
+		text = buf.String()
+		readResult, err := ReadResultJSON(&buf)
+		if err != nil || !reflect.DeepEqual(result, readResult) {
+			t.Error(fmt.Sprintf("result %+v of [%v] is read as %+v from %v", result, in.Name, readResult, text), err)
+			return
+		}
+	}
+
+	// forbidden routes are null, the status is its text
+	in := &Instance{Supply: []float64{1}, Demand: []float64{1, 1}, Costs: [][]float64{{math.Inf(1), 2}}}
+	var buf bytes.Buffer
+	in.WriteJSON(&buf)
+	if !strings.Contains(buf.String(), "null") {
+		t.Error("forbidden route isn't written as null:", buf.String())
+		return
+	}
+	buf.Reset()
+	WriteResultJSON(&buf, &Result{Status: StatusTimeLimit})
+	if !strings.Contains(buf.String(), `"time-limit"`) {
+		t.Error("status isn't written as text:", buf.String())
+		return
+	}
+
+	// invalid inputs
+	for _, text := range []string{
+		`{"supply": [1], "demand": [1], "costs": [[1]]`,
+		`{"supply": [1], "demand": [1], "costs": [["a"]]}`,
+		`{"sup`,
+	} {
+		if _, err := ReadJSON(strings.NewReader(text)); err == nil {
+			t.Error("expect error for", text)
+			return
+		}
+	}
+	if _, err := ReadResultJSON(strings.NewReader(`{"status": "done"}`)); err == nil {
+		t.Error("expect error for an unknown status")
+		return
+	}
+}
@@ -0,0 +1,479 @@
+package tp
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Spanning tree of the basis used by the network simplex method.
+//
+// Producers (rows) are nodes [0, sLen) and consumers (columns) are
+// nodes [sLen, sLen+dLen). Every basic cell (i,j) is a tree edge
+// between node i and node sLen+j. The tree is rooted at node 0 (row 0)
+// so the potentials keep the same u[0]=0 convention as computeUV().
+type basisTree struct {
+	// parent node, -1 for the root
+	parent []int
+
+	// distance to the root
+	depth []int
+
+	// next/previous node in the preorder (depth-first) traversal,
+	// -1 at both ends of the thread
+	thread, rthread []int
+
+	// last node of the subtree in the preorder traversal
+	last []int
+
+	// scratch slices used when re-rooting a subtree
+	path, pieces []int
+
+	// scratch slices of buildTree()
+	visited            []bool
+	stack, mark, order []int
+}
+
+func newBasisTree(nodeCnt int) *basisTree {
+	return &basisTree{
+		parent:  make([]int, nodeCnt),
+		depth:   make([]int, nodeCnt),
+		thread:  make([]int, nodeCnt),
+		rthread: make([]int, nodeCnt),
+		last:    make([]int, nodeCnt),
+		path:    make([]int, 0, nodeCnt),
+		pieces:  make([]int, 0, 4*nodeCnt),
+		visited: make([]bool, nodeCnt),
+		stack:   make([]int, 0, nodeCnt),
+		mark:    make([]int, nodeCnt),
+		order:   make([]int, 0, nodeCnt),
+	}
+}
+
+// resize the tree for the given node count, its slices are reused if
+// they are big enough
+func (t *basisTree) resize(nodeCnt int) {
+	t.parent = resize(t.parent, nodeCnt)
+	t.depth = resize(t.depth, nodeCnt)
+	t.thread = resize(t.thread, nodeCnt)
+	t.rthread = resize(t.rthread, nodeCnt)
+	t.last = resize(t.last, nodeCnt)
+	t.path = t.path[:0]
+	t.pieces = t.pieces[:0]
+	t.visited = resize(t.visited, nodeCnt)
+	t.stack = t.stack[:0]
+	t.mark = resize(t.mark, nodeCnt)
+	t.order = t.order[:0]
+}
+
+// returns the basic cell linking the given (non-root) node to its parent
+func (es *Problem) treeCell(node int) (int, int) {
+	p := es.tree.parent[node]
+	if node < es.sLen {
+		return node, p - es.sLen
+	}
+	return p, node - es.sLen
+}
+
+// find the root of the given node in the union-find forest
+func findRoot(uf []int, x int) int {
+	for uf[x] != x {
+		uf[x] = uf[uf[x]]
+		x = uf[x]
+	}
+	return x
+}
+
+// Add 0-value basic cells until the basic cells form a spanning tree.
+// The cells are tried row-major, a union-find forest tells if a cell
+// would close a loop so no loop has to be searched for it.
+func (es *Problem) completeBasis() error {
+	sLen, dLen := es.sLen, es.dLen
+	nodeCnt := sLen + dLen
+	uf := es.nodeBuf
+	for i := 0; i < nodeCnt; i++ {
+		uf[i] = i
+	}
+	edgeCnt := 0
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			if !es.flow[i][j].basic {
+				continue
+			}
+			ri, rj := findRoot(uf, i), findRoot(uf, sLen+j)
+			if ri == rj {
+				return fmt.Errorf("[completeBasis()] basic cell (%v,%v) forms a loop.", i, j)
+			}
+			uf[ri] = rj
+			edgeCnt += 1
+		}
+	}
+	for i := 0; i < sLen && edgeCnt < nodeCnt-1; i++ {
+		for j := 0; j < dLen && edgeCnt < nodeCnt-1; j++ {
+			fc := &es.flow[i][j]
+			if fc.basic {
+				continue
+			}
+			ri, rj := findRoot(uf, i), findRoot(uf, sLen+j)
+			if ri == rj {
+				continue
+			}
+			uf[ri] = rj
+			fc.basic = true
+			fc.value = 0
+			edgeCnt += 1
+		}
+	}
+	if edgeCnt != nodeCnt-1 {
+		return fmt.Errorf("[completeBasis()] got %v basic cells, while it should be %v!", edgeCnt, nodeCnt-1)
+	}
+	return nil
+}
+
+// Build the spanning tree from the basic cells and compute the
+// potentials (u,v) along it.
+func (es *Problem) buildTree() error {
+	sLen, dLen := es.sLen, es.dLen
+	nodeCnt := sLen + dLen
+	if es.tree == nil {
+		es.tree = newBasisTree(nodeCnt)
+	}
+	t := es.tree
+	for i := 0; i < nodeCnt; i++ {
+		t.parent[i] = -1
+		t.thread[i] = -1
+		t.rthread[i] = -1
+		t.last[i] = -1
+	}
+
+	// depth-first traversal from the root, mark[x] is the position of
+	// node x in the preorder
+	visited, mark := t.visited, t.mark
+	clear(visited)
+	stack, order := t.stack[:0], t.order[:0]
+	stack = append(stack, 0)
+	visited[0] = true
+	t.depth[0] = 0
+	es.u[0] = 0
+	for len(stack) > 0 {
+		x := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		order = append(order, x)
+		mark[x] = len(order) - 1
+		if x < sLen {
+			for j := dLen - 1; j >= 0; j-- {
+				y := sLen + j
+				if !es.flow[x][j].basic || visited[y] {
+					continue
+				}
+				visited[y] = true
+				t.parent[y] = x
+				t.depth[y] = t.depth[x] + 1
+				es.v[j] = es.costMatrix[x][j] - es.u[x]
+				stack = append(stack, y)
+			}
+		} else {
+			j := x - sLen
+			for i := sLen - 1; i >= 0; i-- {
+				if !es.flow[i][j].basic || visited[i] {
+					continue
+				}
+				visited[i] = true
+				t.parent[i] = x
+				t.depth[i] = t.depth[x] + 1
+				es.u[i] = es.costMatrix[i][j] - es.v[j]
+				stack = append(stack, i)
+			}
+		}
+	}
+	t.stack, t.order = stack, order
+	if len(order) != nodeCnt {
+		return fmt.Errorf("[buildTree()] basic cells span %v/%v nodes.", len(order), nodeCnt)
+	}
+	for k := 0; k < nodeCnt; k++ {
+		x := order[k]
+		if k > 0 {
+			t.rthread[x] = order[k-1]
+		}
+		if k < nodeCnt-1 {
+			t.thread[x] = order[k+1]
+		}
+	}
+	// a node's subtree is a contiguous range in the preorder, walk it
+	// backwards so children are done before their parents
+	for k := nodeCnt - 1; k >= 0; k-- {
+		x := order[k]
+		if t.last[x] == -1 {
+			t.last[x] = x
+		}
+		if p := t.parent[x]; p != -1 && (t.last[p] == -1 || mark[t.last[x]] > mark[t.last[p]]) {
+			t.last[p] = t.last[x]
+		}
+	}
+	return nil
+}
+
+// Apply one network simplex pivot with the entering cell (es.row, es.col),
+// returns the flow moved along the loop and the leaving cell, -1,-1 if
+// the entering cell goes to its other bound instead.
+func (es *Problem) pivot() (float64, int, int) {
+	sLen, t := es.sLen, es.tree
+	ei, ej := es.row, es.col
+	a, b := ei, sLen+ej
+	ec := &es.flow[ei][ej]
+
+	// the entering cell goes up from 0, or down from its capacity if it
+	// is at its upper bound
+	dir := float64(1)
+	if ec.upper {
+		dir = -1
+	}
+
+	// walk up from both ends of the entering cell to their common
+	// ancestor, the cells on the path from b to a take -dir*theta/
+	// +dir*theta in turns starting from -dir*theta, on a's side that is
+	// the cells hanging under a row node, on b's side the ones under a
+	// column. The entering cell itself can't move more than its capacity.
+	theta, leave, leaveOnA, leaveDir := es.capacityOf(ei, ej), -1, false, float64(0)
+	x, y := a, b
+	for x != y {
+		if t.depth[x] >= t.depth[y] {
+			d := dir
+			if x < sLen {
+				d = -dir
+			}
+			if r := es.room(x, d); r < theta {
+				theta, leave, leaveOnA, leaveDir = r, x, true, d
+			}
+			x = t.parent[x]
+		} else {
+			d := -dir
+			if y < sLen {
+				d = dir
+			}
+			if r := es.room(y, d); r < theta {
+				theta, leave, leaveOnA, leaveDir = r, y, false, d
+			}
+			y = t.parent[y]
+		}
+	}
+	apex := x
+	if leave != -1 && es.pivotRule == PivotBland {
+		leave, leaveOnA, leaveDir = es.blandLeaving(a, b, dir, theta)
+	}
+	if es.observer != nil {
+		es.observeTreeLoop(a, b, apex)
+	}
+
+	// update the flow along the loop
+	for x = a; x != apex; x = t.parent[x] {
+		ci, cj := es.treeCell(x)
+		if x < sLen {
+			es.flow[ci][cj].value -= dir * theta
+		} else {
+			es.flow[ci][cj].value += dir * theta
+		}
+	}
+	for y = b; y != apex; y = t.parent[y] {
+		ci, cj := es.treeCell(y)
+		if y >= sLen {
+			es.flow[ci][cj].value -= dir * theta
+		} else {
+			es.flow[ci][cj].value += dir * theta
+		}
+	}
+	if leave == -1 {
+		// the entering cell goes to its other bound and stays non-basic,
+		// the tree doesn't change
+		ec.upper = !ec.upper
+		ec.value = 0
+		if ec.upper {
+			ec.value = theta
+		}
+		return theta, -1, -1
+	}
+	li, lj := es.treeCell(leave)
+	lc := &es.flow[li][lj]
+	lc.basic = false
+	lc.upper = leaveDir > 0
+	lc.value = 0
+	if lc.upper {
+		lc.value = es.capacityOf(li, lj)
+	}
+	ec.basic = true
+	ec.upper = false
+	ec.value += dir * theta
+
+	// the subtree under the leaving cell gets re-hung under the
+	// entering cell, q is the end of the entering cell in it
+	q, p := b, a
+	if leaveOnA {
+		q, p = a, b
+	}
+	// shift rows by +s and columns by -s in the subtree, this keeps
+	// u+v=c on the cells in it and makes it hold on the entering cell
+	s := es.costMatrix[ei][ej] - es.u[ei] - es.v[ej]
+	if q >= sLen {
+		s = -s
+	}
+	es.rehang(leave, q, p, s)
+	return theta, li, lj
+}
+
+// how much the tree cell linking the given node to its parent can move
+// in the given direction, down to 0 or up to its capacity
+func (es *Problem) room(node int, dir float64) float64 {
+	i, j := es.treeCell(node)
+	if dir < 0 {
+		return es.flow[i][j].value
+	}
+	return es.capacityOf(i, j) - es.flow[i][j].value
+}
+
+// Detach the subtree rooted at 'top' and hang it under node p by the
+// edge (q,p), q becomes the new root of the subtree. The potentials of
+// the subtree nodes are shifted by s (rows) and -s (columns).
+func (es *Problem) rehang(top, q, p int, s float64) {
+	sLen, t := es.sLen, es.tree
+
+	// 1) path from q up to top
+	path := t.path[:0]
+	for x := q; ; x = t.parent[x] {
+		path = append(path, x)
+		if x == top {
+			break
+		}
+	}
+	t.path = path
+
+	// 2) the new preorder of the subtree is made of pieces, one for each
+	// node on the path: the first piece is the old subtree of q, piece k
+	// is the old subtree of path[k] without the old subtree of path[k-1]
+	// which takes up to two ranges of the old thread. Read them before
+	// touching any link.
+	pieces := t.pieces[:0]
+	pieces = append(pieces, q, t.last[q], -1, -1)
+	for k := 1; k < len(path); k++ {
+		x, c := path[k], path[k-1]
+		start2, end2 := -1, -1
+		if t.last[c] != t.last[x] {
+			start2, end2 = t.thread[t.last[c]], t.last[x]
+		}
+		pieces = append(pieces, x, t.rthread[c], start2, end2)
+	}
+	t.pieces = pieces
+
+	// 3) cut the old subtree out of the thread
+	oldLast := t.last[top]
+	before, after := t.rthread[top], t.thread[oldLast]
+	t.thread[before] = after
+	if after != -1 {
+		t.rthread[after] = before
+	}
+	for x := t.parent[top]; x != -1 && t.last[x] == oldLast; x = t.parent[x] {
+		t.last[x] = before
+	}
+
+	// 4) link the pieces together
+	end := -1
+	for k := 0; k < len(pieces); k += 4 {
+		start1, end1, start2, end2 := pieces[k], pieces[k+1], pieces[k+2], pieces[k+3]
+		if end != -1 {
+			t.thread[end] = start1
+			t.rthread[start1] = end
+		}
+		end = end1
+		if start2 != -1 {
+			t.thread[end1] = start2
+			t.rthread[start2] = end1
+			end = end2
+		}
+	}
+
+	// 5) hang the new subtree right after p in the thread
+	next := t.thread[p]
+	t.thread[p] = q
+	t.rthread[q] = p
+	t.thread[end] = next
+	if next != -1 {
+		t.rthread[next] = end
+	}
+	for x := p; x != -1 && t.last[x] == p; x = t.parent[x] {
+		t.last[x] = end
+	}
+
+	// 6) fix parents along the reversed path, the last node and depth of
+	// the subtree nodes, and shift their potentials
+	t.parent[q] = p
+	for k := 1; k < len(path); k++ {
+		t.parent[path[k]] = path[k-1]
+	}
+	for _, x := range path {
+		t.last[x] = end
+	}
+	for x := q; ; x = t.thread[x] {
+		t.depth[x] = t.depth[t.parent[x]] + 1
+		if x < sLen {
+			es.u[x] += s
+		} else {
+			es.v[x-sLen] -= s
+		}
+		if x == end {
+			break
+		}
+	}
+}
+
+// Solve the problem with the network simplex method. It starts from the
+// same initial solution as the U,V method (or the previous basis if warm
+// is true, the tree is already built then), but keeps the basis as a
+// spanning tree so each pivot only walks the loop and the subtree which
+// gets moved, instead of rescanning the whole grid.
+func (es *Problem) solveNetworkSimplex(ctx context.Context, warm bool) (Status, error) {
+	if warm {
+		if es.trace != nil {
+			es.tracef("warm start from the previous basis, cost=%v", es.GetCost())
+		}
+	} else {
+		flowCnt := es.findFeasibleSolution()
+		if es.trace != nil {
+			es.tracef("initial solution (%v): %v basic cells, cost=%v", es.strategy, flowCnt, es.GetCost())
+		}
+		if err := es.completeBasis(); err != nil {
+			return 0, err
+		}
+		if err := es.buildTree(); err != nil {
+			return 0, err
+		}
+	}
+	es.observeBasis(warm)
+
+	start := time.Now()
+	status := StatusOptimal
+	for {
+		es.observePotentials()
+		if es.isOptimal() {
+			if es.trace != nil {
+				es.tracef("optimal after %v iterations, cost=%v", es.iterCnt, es.GetCost())
+			}
+			break
+		}
+		violation := es.violation(es.row, es.col)
+		theta, leaveRow, leaveCol := es.pivot()
+		es.iterCnt += 1
+		es.observePivot(violation, theta, leaveRow, leaveCol)
+		// the args would be allocated on every pivot even without a trace
+		if es.trace != nil {
+			es.tracef("iteration #%v: entering (%v,%v), theta=%v", es.iterCnt, es.row, es.col, theta)
+		}
+		if limit, reached := es.limitReached(ctx, start); reached {
+			if es.trace != nil {
+				es.tracef("stopped (%v) after %v iterations, cost=%v", limit, es.iterCnt, es.GetCost())
+			}
+			status = limit
+			es.observePotentials()
+			break
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,170 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// check the basis tree links against the parent pointers
+func checkTree(p *Problem) error {
+	t := p.tree
+	nodeCnt := p.sLen + p.dLen
+	pos := make([]int, nodeCnt)
+	x, k := 0, 0
+	for ; x != -1; x = t.thread[x] {
+		if k >= nodeCnt {
+			return fmt.Errorf("thread has a loop")
+		}
+		pos[x] = k
+		if k > 0 && t.parent[x] == -1 {
+			return fmt.Errorf("node %v has no parent", x)
+		}
+		if k > 0 && t.depth[x] != t.depth[t.parent[x]]+1 {
+			return fmt.Errorf("node %v has wrong depth %v", x, t.depth[x])
+		}
+		if n := t.thread[x]; n != -1 && t.rthread[n] != x {
+			return fmt.Errorf("rthread[%v]=%v, should be %v", n, t.rthread[n], x)
+		}
+		k += 1
+	}
+	if k != nodeCnt {
+		return fmt.Errorf("thread covers %v/%v nodes", k, nodeCnt)
+	}
+	for x = 1; x < nodeCnt; x++ {
+		// a node must be within its parent's subtree range
+		par := t.parent[x]
+		if pos[x] <= pos[par] || pos[t.last[x]] > pos[t.last[par]] || pos[t.last[x]] < pos[x] {
+			return fmt.Errorf("node %v is out of its parent %v's subtree", x, par)
+		}
+		i, j := p.treeCell(x)
+		if !p.flow[i][j].basic {
+			return fmt.Errorf("tree cell (%v,%v) is not basic", i, j)
+		}
+		if math.Abs(p.u[i]+p.v[j]-p.costMatrix[i][j]) > 1e-6 {
+			return fmt.Errorf("u[%v]+v[%v]!=c[%v][%v]", i, j, i, j)
+		}
+	}
+	return nil
+}
+
+func randomProblem(r *rand.Rand, sLen, dLen int) *TestProblem {
+	tp := &TestProblem{
+		id:     -1,
+		name:   fmt.Sprintf("random %vx%v", sLen, dLen),
+		supply: make([]float64, sLen),
+		demand: make([]float64, dLen),
+		costs:  make([][]float64, sLen),
+	}
+	for i := 0; i < sLen; i++ {
+		tp.supply[i] = float64(1 + r.Intn(100))
+		tp.costs[i] = make([]float64, dLen)
+		for j := 0; j < dLen; j++ {
+			tp.costs[i][j] = float64(r.Intn(50))
+		}
+	}
+	for j := 0; j < dLen; j++ {
+		tp.demand[j] = float64(1 + r.Intn(100))
+	}
+	return tp
+}
+
+func solveWith(tp *TestProblem, maxIter int, algorithm Algorithm) (*Problem, error) {
+	p, err := CreateProblem(tp.supply, tp.demand, tp.costs, float64(maxIter), EPSILON, float64(algorithm))
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.Solve(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func TestNetworkSimplex(t *testing.T) {
+	for _, tp := range testData {
+		p1, err := solveWith(tp, MAX_ITER, AlgoMODI)
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve the problem %v with U,V method", tp.id), err)
+			return
+		}
+		p2, err := solveWith(tp, MAX_ITER, AlgoNetworkSimplex)
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve the problem %v with network simplex", tp.id), err)
+			return
+		}
+		if err = checkTree(p2); err != nil {
+			t.Error(fmt.Sprintf("bad basis tree for problem %v", tp.id), err)
+			return
+		}
+		if c1, c2 := p1.GetCost(), p2.GetCost(); math.Abs(c1-c2) > EPSILON {
+			t.Error(fmt.Sprintf("problem %v: cost %v != %v", tp.id, c1, c2))
+			return
+		}
+		f1, f2 := p1.GetFlow(), p2.GetFlow()
+		for i := range f1 {
+			for j := range f1[i] {
+				if math.Abs(f1[i][j]-f2[i][j]) > EPSILON {
+					t.Error(fmt.Sprintf("problem %v: flow[%v][%v] %v != %v", tp.id, i, j, f1[i][j], f2[i][j]))
+					return
+				}
+			}
+		}
+	}
+
+	// bigger problems, both methods run to optimality
+	r := rand.New(rand.NewSource(1))
+	for k := 0; k < 20; k++ {
+		tp := randomProblem(r, 5+r.Intn(40), 5+r.Intn(40))
+		p1, err := solveWith(tp, 0, AlgoMODI)
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve [%v] with U,V method", tp.name), err)
+			return
+		}
+		p2, err := solveWith(tp, 0, AlgoNetworkSimplex)
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve [%v] with network simplex", tp.name), err)
+			return
+		}
+		if err = checkTree(p2); err != nil {
+			t.Error(fmt.Sprintf("bad basis tree for [%v]", tp.name), err)
+			return
+		}
+		if c1, c2 := p1.GetCost(), p2.GetCost(); math.Abs(c1-c2) > EPSILON*math.Max(1, c1) {
+			t.Error(fmt.Sprintf("[%v]: cost %v != %v", tp.name, c1, c2))
+			return
+		}
+	}
+}
+
+func BenchmarkNetworkSimplex(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	tp := randomProblem(r, 300, 300)
+	for n := 0; n < b.N; n++ {
+		if _, err := solveWith(tp, 0, AlgoNetworkSimplex); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMODI(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	tp := randomProblem(r, 300, 300)
+	for n := 0; n < b.N; n++ {
+		if _, err := solveWith(tp, 0, AlgoMODI); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNetworkSimplexBlockSearch(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	tp := randomProblem(r, 300, 300)
+	for n := 0; n < b.N; n++ {
+		p, _ := NewProblem(tp.supply, tp.demand, tp.costs, WithMaxIter(0),
+			WithAlgorithm(AlgoNetworkSimplex), WithPivotRule(PivotBlockSearch))
+		if _, err := p.Solve(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package tp
+
+// Cell of the basis or of a pivot loop with its flow (or of the flow of
+// Transport1D()). The rows/columns after the inputs are the dummy (and
+// artificial) producer/consumer.
+type Cell struct {
+	Row, Col int
+	Flow     float64
+}
+
+// BasisEvent tells the basis the optimization starts from.
+type BasisEvent struct {
+	// the strategy which found it, it is the previous basis if Warm
+	Strategy InitialStrategy
+	Warm     bool
+
+	// the basic cells, the cells at their capacity are not in it
+	Cells []Cell
+	Cost  float64
+}
+
+// PivotEvent tells what a pivot did.
+type PivotEvent struct {
+	// count of the pivots so far, this one included
+	Iteration int
+
+	// the entering cell
+	Row, Col int
+
+	// the loop with the flow before the pivot, the entering cell first
+	// and then the others taking -theta/+theta in turns (the other way
+	// round if the entering cell comes down from its capacity)
+	Loop []Cell
+
+	// flow moved along the loop
+	Theta float64
+
+	// the cell which left the basis, -1,-1 if the entering cell went to
+	// its other bound instead
+	LeavingRow, LeavingCol int
+
+	// cost after the pivot
+	Cost float64
+}
+
+// DoneEvent tells how the optimization ended, Err is the error of the
+// solver if it failed (the other fields are zero then).
+type DoneEvent struct {
+	Status     Status
+	Iterations int
+	Cost       float64
+	Err        error
+}
+
+// Observer watches the solver, see WithObserver(). The slices it gets
+// are owned by the solver and only valid during the call.
+type Observer interface {
+	// the initial (or warm start) basis is ready
+	Basis(e BasisEvent)
+
+	// a pivot is done
+	Pivot(e PivotEvent)
+
+	// the potentials of the current basis, before every optimality check
+	// and for the last basis if the optimization stops at a limit
+	Potentials(u, v []float64)
+
+	// Solve() ends
+	Done(e DoneEvent)
+}
+
+// tell the observer about the basis the optimization starts from, its
+// cost is kept to keep track of the cost of the pivots
+func (es *Problem) observeBasis(warm bool) {
+	if es.observer == nil {
+		return
+	}
+	cells := es.observedLoop[:0]
+	for i := 0; i < es.sLen; i++ {
+		for j := 0; j < es.dLen; j++ {
+			if fc := &es.flow[i][j]; fc.basic {
+				cells = append(cells, Cell{Row: i, Col: j, Flow: fc.value})
+			}
+		}
+	}
+	es.observedLoop = cells
+	es.observedCost = es.GetCost()
+	es.observer.Basis(BasisEvent{Strategy: es.strategy, Warm: warm, Cells: cells, Cost: es.observedCost})
+}
+
+func (es *Problem) observePotentials() {
+	if es.observer != nil {
+		es.observer.Potentials(es.u, es.v)
+	}
+}
+
+// keep the loop found by findLoop() with the flow before the pivot
+func (es *Problem) observeLoop() {
+	cells := es.observedLoop[:0]
+	for c := es.loop; c != nil; c = c.next {
+		cells = append(cells, Cell{Row: c.row, Col: c.col, Flow: es.flow[c.row][c.col].value})
+	}
+	es.observedLoop = cells
+}
+
+// keep the loop of the entering cell (a,b) in the tree, it goes up from
+// b to the apex and down from it to a, with the flow before the pivot
+func (es *Problem) observeTreeLoop(a, b, apex int) {
+	t := es.tree
+	cells := append(es.observedLoop[:0], Cell{Row: es.row, Col: es.col, Flow: es.flow[es.row][es.col].value})
+	for y := b; y != apex; y = t.parent[y] {
+		i, j := es.treeCell(y)
+		cells = append(cells, Cell{Row: i, Col: j, Flow: es.flow[i][j].value})
+	}
+	k := len(cells)
+	for x := a; x != apex; x = t.parent[x] {
+		i, j := es.treeCell(x)
+		cells = append(cells, Cell{Row: i, Col: j, Flow: es.flow[i][j].value})
+	}
+	for l, r := k, len(cells)-1; l < r; l, r = l+1, r-1 {
+		cells[l], cells[r] = cells[r], cells[l]
+	}
+	es.observedLoop = cells
+}
+
+// tell the observer about the pivot which is just done, violation is the
+// one of the entering cell before it, the cost goes down by theta times
+// it
+func (es *Problem) observePivot(violation, theta float64, leaveRow, leaveCol int) {
+	if es.observer == nil {
+		return
+	}
+	es.observedCost -= theta * violation
+	es.observer.Pivot(PivotEvent{
+		Iteration:  es.iterCnt,
+		Row:        es.row,
+		Col:        es.col,
+		Loop:       es.observedLoop,
+		Theta:      theta,
+		LeavingRow: leaveRow,
+		LeavingCol: leaveCol,
+		Cost:       es.observedCost,
+	})
+}
@@ -0,0 +1,128 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// records what the solver tells, the slices are copied
+type recorder struct {
+	bases      []BasisEvent
+	pivots     []PivotEvent
+	potentials int
+	done       []DoneEvent
+}
+
+func (r *recorder) Basis(e BasisEvent) {
+	e.Cells = append([]Cell{}, e.Cells...)
+	r.bases = append(r.bases, e)
+}
+
+func (r *recorder) Pivot(e PivotEvent) {
+	e.Loop = append([]Cell{}, e.Loop...)
+	r.pivots = append(r.pivots, e)
+}
+
+func (r *recorder) Potentials(u, v []float64) { r.potentials++ }
+func (r *recorder) Done(e DoneEvent)          { r.done = append(r.done, e) }
+
+// the cells make a loop, every one shares its row or column with the
+// next one in turns, and theta is the min flow of the ones losing it
+func checkLoop(e PivotEvent) error {
+	loop := e.Loop
+	if len(loop) < 4 || len(loop)%2 != 0 || loop[0].Row != e.Row || loop[0].Col != e.Col {
+		return fmt.Errorf("loop %v of entering (%v,%v)", loop, e.Row, e.Col)
+	}
+	sameRow := loop[0].Row == loop[1].Row
+	theta, leaving := math.Inf(1), false
+	for k := range loop {
+		c, next := loop[k], loop[(k+1)%len(loop)]
+		if (c.Row == next.Row) != sameRow || (c.Col == next.Col) == sameRow {
+			return fmt.Errorf("cell %v and %v of loop %v", c, next, loop)
+		}
+		sameRow = !sameRow
+		if k%2 == 1 {
+			theta = math.Min(theta, c.Flow)
+		}
+		leaving = leaving || (c.Row == e.LeavingRow && c.Col == e.LeavingCol)
+	}
+	if math.Abs(theta-e.Theta) > 1e-9 || !leaving {
+		return fmt.Errorf("theta %v leaving (%v,%v), should be %v of loop %v", e.Theta, e.LeavingRow, e.LeavingCol, theta, loop)
+	}
+	return nil
+}
+
+func TestObserver(t *testing.T) {
+	for _, tp := range testData {
+		for _, algo := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+			name := fmt.Sprintf("[%v] %v", tp.name, algo)
+			r := &recorder{}
+			p, _ := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algo), WithObserver(r),
+				WithInitialStrategy(InitNorthwestCorner), WithMaxIter(0))
+			result, err := p.Solve()
+			if err != nil {
+				t.Error(name, err)
+				return
+			}
+			if len(r.bases) != 1 || len(r.bases[0].Cells) != p.sLen+p.dLen-1 || r.bases[0].Warm ||
+				r.bases[0].Strategy != InitNorthwestCorner {
+				t.Error(fmt.Sprintf("%v bases: %+v", name, r.bases))
+				return
+			}
+			if len(r.pivots) != result.Iterations || r.potentials != result.Iterations+1 {
+				t.Error(fmt.Sprintf("%v %v pivots and %v potentials, %v iterations", name, len(r.pivots), r.potentials, result.Iterations))
+				return
+			}
+			for k, e := range r.pivots {
+				if e.Iteration != k+1 {
+					t.Error(fmt.Sprintf("%v pivot #%v is iteration %v", name, k+1, e.Iteration))
+					return
+				}
+				if err := checkLoop(e); err != nil {
+					t.Error(fmt.Sprintf("%v pivot #%v", name, k+1), err)
+					return
+				}
+			}
+			cost := r.bases[0].Cost
+			if len(r.pivots) > 0 {
+				cost = r.pivots[len(r.pivots)-1].Cost
+			}
+			if math.Abs(cost-p.GetCost()) > 1e-9*math.Max(1, cost) {
+				t.Error(fmt.Sprintf("%v cost after the last pivot is %v, should be %v", name, cost, p.GetCost()))
+				return
+			}
+			if len(r.done) != 1 || r.done[0].Status != StatusOptimal || r.done[0].Iterations != result.Iterations ||
+				r.done[0].Cost != result.Objective || r.done[0].Err != nil {
+				t.Error(fmt.Sprintf("%v done: %+v", name, r.done))
+				return
+			}
+		}
+	}
+
+	// a warm start, the iteration limit and an error
+	tp := testData[4]
+	r := &recorder{}
+	p, _ := NewProblem(tp.supply, tp.demand, tp.costs, WithObserver(r), WithMaxIter(0))
+	p.Solve()
+	p.SetCost(0, 0, tp.costs[0][0]+1)
+	p.Solve()
+	if len(r.bases) != 2 || !r.bases[1].Warm || len(r.done) != 2 {
+		t.Error(fmt.Sprintf("warm start bases: %+v, done: %+v", r.bases, r.done))
+		return
+	}
+	r = &recorder{}
+	p, _ = NewProblem(tp.supply, tp.demand, tp.costs, WithObserver(r), WithInitialStrategy(InitNorthwestCorner), WithMaxIter(1))
+	p.Solve()
+	if len(r.pivots) != 1 || r.potentials != 2 || len(r.done) != 1 || r.done[0].Status != StatusIterationLimit {
+		t.Error(fmt.Sprintf("with max 1 iteration pivots: %+v, potentials: %v, done: %+v", r.pivots, r.potentials, r.done))
+		return
+	}
+	inf := math.Inf(1)
+	r = &recorder{}
+	p, _ = NewProblem([]float64{1, 1}, []float64{1, 1}, [][]float64{{1, inf}, {1, inf}}, WithObserver(r))
+	if _, err := p.Solve(); err == nil || len(r.done) != 1 || r.done[0].Err != err {
+		t.Error(fmt.Sprintf("infeasible problem done: %+v", r.done), err)
+		return
+	}
+}
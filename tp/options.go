@@ -0,0 +1,288 @@
+package tp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Rule to pick the entering cell among the ones which violate the
+// optimality condition (u+v>c), and the leaving one when several reach
+// their bound together (a degenerate pivot).
+type PivotRule int
+
+const (
+	// the cell with the biggest violation (u+v-c), "Dantzig's rule"
+	PivotDantzig PivotRule = iota
+
+	// the first violating cell found, the search continues from the
+	// previous entering cell instead of the top-left corner
+	PivotFirstEligible
+
+	// Bland's rule: the first violating cell from the top-left corner
+	// enters and the first cell (row-major) of the ones reaching their
+	// bound leaves. It never cycles on degenerate pivots but usually
+	// takes more of them.
+	PivotBland
+
+	// Dantzig's rule on a perturbed problem: every supply gets a small
+	// extra amount (2*epsilon) and the last consumer gets all of it, so
+	// no basic cell is at 0 and every pivot moves some flow. The basis
+	// found is optimal for the original problem too, its flow is then
+	// computed from the original supply/demand. If that isn't feasible
+	// (the amounts differ by less than the perturbation) it is solved
+	// again with Bland's rule.
+	PivotPerturbation
+
+	// block search pricing: the cells are scanned in blocks of about
+	// sqrt(rows*cols) cells going on from where the previous search
+	// stopped, the cell with the biggest violation of the first block
+	// which has any enters. A pivot doesn't scan the whole grid, only
+	// the last search before the optimum does.
+	PivotBlockSearch
+)
+
+func (r PivotRule) String() string {
+	switch r {
+	case PivotDantzig:
+		return "dantzig"
+	case PivotFirstEligible:
+		return "first-eligible"
+	case PivotBland:
+		return "bland"
+	case PivotPerturbation:
+		return "perturbation"
+	case PivotBlockSearch:
+		return "block-search"
+	default:
+		return "unknown"
+	}
+}
+
+func (r PivotRule) valid() bool {
+	return r >= PivotDantzig && r <= PivotBlockSearch
+}
+
+// Options of the solver.
+type Options struct {
+	// max iterations to run when optimizing the solution, 0 means
+	// no limit
+	MaxIter int
+
+	// used to tell if a float64 value is zero or not
+	Epsilon float64
+
+	// method to optimize the solution with
+	Algorithm Algorithm
+
+	// method to find the initial solution with
+	InitialStrategy InitialStrategy
+
+	// rule to pick the entering cell
+	PivotRule PivotRule
+
+	// max wall-clock time to spend on optimizing the solution, 0
+	// means no limit
+	TimeLimit time.Duration
+
+	// if not nil, the solver writes what it does to it
+	Trace io.Writer
+
+	// if not nil, it is called on every step of the solver
+	Observer Observer
+
+	// cost per unit of supply[i] which isn't shipped (disposal,
+	// storage), the cost of producer i's route to the dummy consumer
+	// when supply is more than demand. nil means 0 for all producers,
+	// math.Inf(1) means producer i must ship its whole supply.
+	SurplusCosts []float64
+
+	// penalty per unit of demand[j] which isn't served, the cost of the
+	// route from the dummy producer to consumer j when demand is more
+	// than supply. nil means 0 for all consumers, math.Inf(1) means
+	// consumer j must be served in full.
+	ShortagePenalties []float64
+}
+
+// DefaultOptions returns an Options instance with default values:
+//
+//	MaxIter:           MAX_ITER (100)
+//	Epsilon:           EPSILON (1e-6)
+//	Algorithm:         AlgoMODI
+//	InitialStrategy:   InitAuto
+//	PivotRule:         PivotDantzig
+//	TimeLimit:         0 (no limit)
+//	Trace:             nil
+//	Observer:          nil
+//	SurplusCosts:      nil (0)
+//	ShortagePenalties: nil (0)
+func DefaultOptions() *Options {
+	return &Options{
+		MaxIter:         MAX_ITER,
+		Epsilon:         EPSILON,
+		Algorithm:       AlgoMODI,
+		InitialStrategy: InitAuto,
+		PivotRule:       PivotDantzig,
+		TimeLimit:       0,
+		Trace:           nil,
+		Observer:        nil,
+	}
+}
+
+func (o *Options) validate() error {
+	if o.MaxIter < 0 {
+		return fmt.Errorf("Given max iterations is negative: %v", o.MaxIter)
+	}
+	if o.Epsilon <= 0 {
+		return fmt.Errorf("Given epsilon is not positive: %v", o.Epsilon)
+	}
+	if o.Epsilon > float64(1e-3) {
+		return fmt.Errorf("Given epsilon is too big (>1e-3): %v", o.Epsilon)
+	}
+	if !o.Algorithm.valid() {
+		return fmt.Errorf("Unknown algorithm: %v", int(o.Algorithm))
+	}
+	if !o.InitialStrategy.valid() {
+		return fmt.Errorf("Unknown initial strategy: %v", int(o.InitialStrategy))
+	}
+	if !o.PivotRule.valid() {
+		return fmt.Errorf("Unknown pivot rule: %v", int(o.PivotRule))
+	}
+	if o.TimeLimit < 0 {
+		return fmt.Errorf("Given time limit is negative: %v", o.TimeLimit)
+	}
+	for i, c := range o.SurplusCosts {
+		if math.IsNaN(c) || math.IsInf(c, -1) {
+			return fmt.Errorf("Given surplus cost [%v] is invalid: %v", i, c)
+		}
+	}
+	for j, c := range o.ShortagePenalties {
+		if math.IsNaN(c) || math.IsInf(c, -1) {
+			return fmt.Errorf("Given shortage penalty [%v] is invalid: %v", j, c)
+		}
+	}
+	return nil
+}
+
+// Option sets an optional arg of the solver.
+type Option func(*Options)
+
+// WithMaxIter sets the max iterations to run when optimizing the
+// solution, 0 means no limit.
+func WithMaxIter(n int) Option {
+	return func(o *Options) {
+		o.MaxIter = n
+	}
+}
+
+// WithEpsilon sets the value used to tell if a float64 value is zero
+// or not, it must be in (0, 1e-3].
+func WithEpsilon(epsilon float64) Option {
+	return func(o *Options) {
+		o.Epsilon = epsilon
+	}
+}
+
+// WithAlgorithm sets the method to optimize the solution with.
+func WithAlgorithm(a Algorithm) Option {
+	return func(o *Options) {
+		o.Algorithm = a
+	}
+}
+
+// WithInitialStrategy sets the method to find the initial solution with.
+func WithInitialStrategy(s InitialStrategy) Option {
+	return func(o *Options) {
+		o.InitialStrategy = s
+	}
+}
+
+// WithPivotRule sets the rule to pick the entering (and leaving) cell.
+func WithPivotRule(r PivotRule) Option {
+	return func(o *Options) {
+		o.PivotRule = r
+	}
+}
+
+// WithTimeLimit sets the max wall-clock time to spend on optimizing the
+// solution, 0 means no limit.
+func WithTimeLimit(d time.Duration) Option {
+	return func(o *Options) {
+		o.TimeLimit = d
+	}
+}
+
+// WithTrace makes the solver write what it does to the given writer.
+func WithTrace(w io.Writer) Option {
+	return func(o *Options) {
+		o.Trace = w
+	}
+}
+
+// WithObserver makes the solver call the given observer on the initial
+// basis, every pivot, the potentials and the end of the optimization.
+func WithObserver(ob Observer) Option {
+	return func(o *Options) {
+		o.Observer = ob
+	}
+}
+
+// WithSurplusCosts sets the cost per unit of every producer's supply
+// which isn't shipped, one for each producer.
+func WithSurplusCosts(costs []float64) Option {
+	return func(o *Options) {
+		o.SurplusCosts = costs
+	}
+}
+
+// WithShortagePenalties sets the penalty per unit of every consumer's
+// demand which isn't served, one for each consumer.
+func WithShortagePenalties(penalties []float64) Option {
+	return func(o *Options) {
+		o.ShortagePenalties = penalties
+	}
+}
+
+// Create a transportation problem from the given args.
+//
+//	supply, demand: positive float64 array/slice.
+//	costs: 2-D matrix, row size should match supply length, column
+//	       size should match demand length. math.Inf(1) marks a
+//	       forbidden route.
+//	opts: optional args, see DefaultOptions() for the default values.
+//
+//	returns the Problem{} struct.
+func NewProblem(supply, demand []float64, costs [][]float64, opts ...Option) (*Problem, error) {
+	es := &Problem{}
+	if err := es.Reset(supply, demand, costs, opts...); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// Reset the problem to new inputs, same args as NewProblem(). It reuses
+// the memory of the problem (costs, flow, basis, ...) when the new one
+// isn't bigger, so solving many problems of a similar size one after
+// another with the same Problem doesn't allocate much. The next Solve()
+// starts from scratch, results returned before are not changed.
+// Returns error if the inputs are invalid, the problem is not changed
+// then.
+func (es *Problem) Reset(supply, demand []float64, costs [][]float64, opts ...Option) error {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(); err != nil {
+		return err
+	}
+	return es.init(supply, demand, costs, nil, o)
+}
+
+// write a line to the trace writer if there is one
+func (es *Problem) tracef(format string, args ...interface{}) {
+	if es.trace == nil {
+		return
+	}
+	fmt.Fprintf(es.trace, format+"\n", args...)
+}
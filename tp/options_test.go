@@ -0,0 +1,83 @@
+package tp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	tp := testData[4]
+
+	// invalid options
+	invalid := [][]Option{
+		[]Option{WithMaxIter(-1)},
+		[]Option{WithEpsilon(0)},
+		[]Option{WithEpsilon(0.01)},
+		[]Option{WithAlgorithm(Algorithm(9))},
+		[]Option{WithInitialStrategy(InitialStrategy(9))},
+		[]Option{WithPivotRule(PivotRule(9))},
+		[]Option{WithTimeLimit(-time.Second)},
+	}
+	for k, opts := range invalid {
+		if _, err := NewProblem(tp.supply, tp.demand, tp.costs, opts...); err == nil {
+			t.Error(fmt.Sprintf("invalid options #%v are accepted", k))
+			return
+		}
+	}
+
+	// the compatibility wrapper maps the positional args
+	p, err := CreateProblem(tp.supply, tp.demand, tp.costs, 7, 1e-5, float64(AlgoNetworkSimplex), float64(InitVogel))
+	if err != nil {
+		t.Error("failed to create the problem:", err)
+		return
+	}
+	if p.maxIter != 7 || p.epsilon != 1e-5 || p.algorithm != AlgoNetworkSimplex || p.strategy != InitVogel {
+		t.Error(fmt.Sprintf("positional args are not mapped: %v, %v, %v, %v", p.maxIter, p.epsilon, p.algorithm, p.strategy))
+		return
+	}
+	// it used to take a non-positive epsilon, it is the default one now
+	for _, epsilon := range []float64{0, -1} {
+		if p, err = CreateProblem(tp.supply, tp.demand, tp.costs, 7, epsilon); err != nil || p.epsilon != EPSILON {
+			t.Error(fmt.Sprintf("epsilon %v is mapped to %v", epsilon, p), err)
+			return
+		}
+	}
+	if _, err = CreateProblem(tp.supply, tp.demand, tp.costs, 7, 0.01); err == nil {
+		t.Error("expect error for epsilon 0.01")
+		return
+	}
+
+	// every pivot rule reaches the same cost
+	var cost float64
+	for k := 0; k < 2*len(pivotRules); k++ {
+		var trace bytes.Buffer
+		p, err := NewProblem(tp.supply, tp.demand, tp.costs,
+			WithMaxIter(0),
+			WithAlgorithm(Algorithm(k%2)),
+			WithPivotRule(pivotRules[k/2]),
+			WithTimeLimit(time.Minute),
+			WithTrace(&trace))
+		if err != nil {
+			t.Error("failed to create the problem:", err)
+			return
+		}
+		if _, err = p.Solve(); err != nil {
+			t.Error(fmt.Sprintf("failed to solve the problem with %v/%v:", p.algorithm, p.pivotRule), err)
+			return
+		}
+		if k == 0 {
+			cost = p.GetCost()
+		} else if c := p.GetCost(); math.Abs(c-cost) > EPSILON {
+			t.Error(fmt.Sprintf("cost with %v/%v is %v, should be %v", p.algorithm, p.pivotRule, c, cost))
+			return
+		}
+		if !strings.Contains(trace.String(), "optimal after") {
+			t.Error("unexpected trace:", trace.String())
+			return
+		}
+	}
+}
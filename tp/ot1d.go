@@ -0,0 +1,126 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Solve the transportation problem between producers and consumers on a
+// line where moving a unit from x to y costs |x-y|^p. For p >= 1 the
+// optimal plan ships in the order of the positions (the northwest corner
+// of the sorted producers/consumers), it takes O(n log n) instead of
+// the simplex method.
+//
+//	x, a: positions and weights (not negative) of the producers.
+//	y, b: positions and weights of the consumers, the sum of b must be
+//	      the same as the sum of a (within EPSILON of it).
+//	p: exponent of the cost, at least 1 (the order isn't optimal for a
+//	   concave cost).
+//
+//	returns the cost and the flow, the cells are the producer/consumer
+//	pairs with flow (at most len(x)+len(y)-1 of them) in the order of
+//	the positions.
+func Transport1D(x, a, y, b []float64, p float64) (float64, []Cell, error) {
+	flow := make([]Cell, 0, len(x)+len(y)-1)
+	cost, err := transport1D(x, a, y, b, p, &flow)
+	if err != nil {
+		return 0, nil, err
+	}
+	return cost, flow, nil
+}
+
+// Same as Transport1D() but returns the cost (the Wasserstein distance
+// to the power p) only.
+func Wasserstein1D(x, a, y, b []float64, p float64) (float64, error) {
+	return transport1D(x, a, y, b, p, nil)
+}
+
+// check the inputs of a producer/consumer side, returns the sum of the
+// weights
+func check1D(name string, x, w []float64) (float64, error) {
+	if len(x) == 0 {
+		return 0, fmt.Errorf("no %v!", name)
+	}
+	if len(x) != len(w) {
+		return 0, fmt.Errorf("%v positions and weights don't have the same length!", name)
+	}
+	sum := float64(0)
+	for k := range x {
+		if math.IsNaN(x[k]) || math.IsInf(x[k], 0) {
+			return 0, fmt.Errorf("position of %v %v is %v!", name, k, x[k])
+		}
+		if math.IsNaN(w[k]) || math.IsInf(w[k], 0) || w[k] < 0 {
+			return 0, fmt.Errorf("weight of %v %v is %v!", name, k, w[k])
+		}
+		sum += w[k]
+	}
+	return sum, nil
+}
+
+// indexes of x in the order of the positions
+func order1D(x []float64) []int {
+	idx := make([]int, len(x))
+	for k := range idx {
+		idx[k] = k
+	}
+	sort.Slice(idx, func(i, j int) bool { return x[idx[i]] < x[idx[j]] })
+	return idx
+}
+
+// solve it, the flow is appended to *flow if it is not nil
+func transport1D(x, a, y, b []float64, p float64, flow *[]Cell) (float64, error) {
+	if math.IsNaN(p) || p < 1 || math.IsInf(p, 1) {
+		return 0, fmt.Errorf("exponent %v is invalid, it must be at least 1!", p)
+	}
+	sa, err := check1D("producer", x, a)
+	if err != nil {
+		return 0, err
+	}
+	sb, err := check1D("consumer", y, b)
+	if err != nil {
+		return 0, err
+	}
+	if math.Abs(sa-sb) > EPSILON*math.Max(1, sa) {
+		return 0, fmt.Errorf("total weights are not the same: %v, %v!", sa, sb)
+	}
+
+	dist := func(u, v float64) float64 {
+		d := math.Abs(u - v)
+		switch p {
+		case 1:
+			return d
+		case 2:
+			return d * d
+		}
+		return math.Pow(d, p)
+	}
+	xs, ys := order1D(x), order1D(y)
+	cost := float64(0)
+	i, j := 0, 0
+	ra, rb := a[xs[0]], b[ys[0]]
+	for i < len(xs) && j < len(ys) {
+		q := math.Min(ra, rb)
+		if q > 0 {
+			cost += q * dist(x[xs[i]], y[ys[j]])
+			if flow != nil {
+				*flow = append(*flow, Cell{Row: xs[i], Col: ys[j], Flow: q})
+			}
+		}
+		ra, rb = ra-q, rb-q
+		// the one with nothing left moves on, both of them do if they
+		// have the same left (it is only rounding)
+		nextI, nextJ := ra <= rb, rb <= ra
+		if nextI {
+			if i++; i < len(xs) {
+				ra = a[xs[i]]
+			}
+		}
+		if nextJ {
+			if j++; j < len(ys) {
+				rb = b[ys[j]]
+			}
+		}
+	}
+	return cost, nil
+}
@@ -0,0 +1,154 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// random points on a line, some of them at the same position, and their
+// weights adding up to total
+func randomLine(r *rand.Rand, n int, total float64) ([]float64, []float64) {
+	x, w := make([]float64, n), make([]float64, n)
+	sum := float64(0)
+	for k := range x {
+		x[k] = float64(r.Intn(20)) + r.Float64()*float64(r.Intn(2))
+		w[k] = float64(r.Intn(5))
+		sum += w[k]
+	}
+	if sum == 0 {
+		w[0], sum = 1, 1
+	}
+	for k := range w {
+		w[k] *= total / sum
+	}
+	return x, w
+}
+
+// the points with a positive weight
+func positive(x, w []float64) ([]float64, []float64) {
+	var px, pw []float64
+	for k := range x {
+		if w[k] > 0 {
+			px, pw = append(px, x[k]), append(pw, w[k])
+		}
+	}
+	return px, pw
+}
+
+// the flow ships the weights and costs what Transport1D() tells
+func checkFlow1D(x, a, y, b []float64, p, cost float64, flow []Cell) error {
+	if len(flow) > len(x)+len(y)-1 {
+		return fmt.Errorf("%v cells in flow, more than %v", len(flow), len(x)+len(y)-1)
+	}
+	sa, sb := make([]float64, len(x)), make([]float64, len(y))
+	c := float64(0)
+	for _, f := range flow {
+		if f.Flow <= 0 {
+			return fmt.Errorf("flow %v", f)
+		}
+		sa[f.Row] += f.Flow
+		sb[f.Col] += f.Flow
+		c += f.Flow * math.Pow(math.Abs(x[f.Row]-y[f.Col]), p)
+	}
+	for i := range a {
+		if math.Abs(sa[i]-a[i]) > 1e-9 {
+			return fmt.Errorf("producer %v ships %v, should be %v", i, sa[i], a[i])
+		}
+	}
+	for j := range b {
+		if math.Abs(sb[j]-b[j]) > 1e-9 {
+			return fmt.Errorf("consumer %v gets %v, should be %v", j, sb[j], b[j])
+		}
+	}
+	if math.Abs(c-cost) > 1e-9*math.Max(1, cost) {
+		return fmt.Errorf("flow costs %v, should be %v", c, cost)
+	}
+	return nil
+}
+
+func TestTransport1D(t *testing.T) {
+	r := rand.New(rand.NewSource(31))
+	for n := 0; n < 300; n++ {
+		x, a := randomLine(r, 1+r.Intn(12), 1)
+		y, b := randomLine(r, 1+r.Intn(12), 1)
+		p := []float64{1, 1.5, 2, 3}[n%4]
+		name := fmt.Sprintf("[#%v p=%v]", n, p)
+		cost, flow, err := Transport1D(x, a, y, b, p)
+		if err != nil {
+			t.Error(name, err)
+			return
+		}
+		if err := checkFlow1D(x, a, y, b, p, cost, flow); err != nil {
+			t.Error(name, err)
+			return
+		}
+		if w, err := Wasserstein1D(x, a, y, b, p); err != nil || w != cost {
+			t.Error(fmt.Sprintf("%v Wasserstein1D() is %v, should be %v", name, w, cost), err)
+			return
+		}
+
+		// the simplex method gets the same cost, without the points with
+		// no weight (it doesn't take them)
+		px, pa := positive(x, a)
+		py, pb := positive(y, b)
+		costs := make([][]float64, len(px))
+		for i := range costs {
+			costs[i] = make([]float64, len(py))
+			for j := range costs[i] {
+				costs[i][j] = math.Pow(math.Abs(px[i]-py[j]), p)
+			}
+		}
+		es, err := NewProblem(pa, pb, costs, WithMaxIter(0))
+		if err != nil {
+			t.Error(name, err)
+			return
+		}
+		result, err := es.Solve()
+		if err != nil {
+			t.Error(name, err)
+			return
+		}
+		if math.Abs(result.Objective-cost) > 1e-9*math.Max(1, cost) {
+			t.Error(fmt.Sprintf("%v Transport1D() costs %v, simplex method %v", name, cost, result.Objective))
+			return
+		}
+	}
+
+	// moving every point by d costs d^p
+	x, a := []float64{3, 1, 2}, []float64{0.5, 0.25, 0.25}
+	if cost, _ := Wasserstein1D(x, a, []float64{5, 3, 4}, a, 2); math.Abs(cost-4) > 1e-12 {
+		t.Error(fmt.Sprintf("cost of moving by 2 is %v, should be 4", cost))
+		return
+	}
+
+	inf := math.Inf(1)
+	for k, c := range []struct {
+		x, a, y, b []float64
+		p          float64
+	}{
+		{x, a, x, a, 0.5},
+		{x, a, x, a, math.NaN()},
+		{nil, nil, x, a, 1},
+		{x, a[:2], x, a, 1},
+		{x, a, []float64{1, inf, 2}, a, 1},
+		{x, []float64{1.5, -0.25, -0.25}, x, a, 1},
+		{x, a, x, []float64{0.5, 0.25, 0.5}, 1},
+	} {
+		if _, _, err := Transport1D(c.x, c.a, c.y, c.b, c.p); err == nil {
+			t.Error(fmt.Sprintf("no error for invalid input #%v: %+v", k, c))
+			return
+		}
+	}
+}
+
+func BenchmarkTransport1D(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	x, wx := randomLine(r, 1000, 1)
+	y, wy := randomLine(r, 1000, 1)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		Wasserstein1D(x, wx, y, wy, 1)
+	}
+}
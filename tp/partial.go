@@ -0,0 +1,125 @@
+package tp
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Solve the partial transportation problem: ship exactly mass units at
+// the lowest cost, the producers don't have to ship all of their supply
+// and the consumers don't have to get all of their demand.
+//
+//	supply, demand, costs: same as NewProblem().
+//	mass: quantity to ship, positive and not more than the total supply
+//	      or the total demand.
+//	opts: optional args, same as NewProblem(). SurplusCosts and
+//	      ShortagePenalties are the cost per unit of supply which isn't
+//	      shipped and of demand which isn't served, they are part of the
+//	      objective.
+//
+//	returns the Result{} with the plan (mass in total) in Flow, the
+//	supply left of every producer in Surpluses and the demand left of
+//	every consumer in Shortages.
+//
+// The problem gets a dummy producer whose supply is the demand which
+// isn't served and a dummy consumer whose demand is the supply which
+// isn't shipped, the route between them is forbidden so exactly mass
+// goes through the other routes. The trace and the observer see them as
+// the last row and column.
+func SolvePartial(supply, demand []float64, costs [][]float64, mass float64, opts ...Option) (*Result, error) {
+	return SolvePartialContext(context.Background(), supply, demand, costs, mass, opts...)
+}
+
+// Same as SolvePartial() but the solver stops when the context is done,
+// see Problem.SolveContext().
+func SolvePartialContext(ctx context.Context, supply, demand []float64, costs [][]float64, mass float64, opts ...Option) (*Result, error) {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	sLen, dLen := len(supply), len(demand)
+	if sLen != len(costs) {
+		return nil, fmt.Errorf("producer count doesn't match 1st dimension length of costMatrix!")
+	}
+	if o.SurplusCosts != nil && len(o.SurplusCosts) != sLen {
+		return nil, fmt.Errorf("producer count doesn't match length of surplus costs!")
+	}
+	if o.ShortagePenalties != nil && len(o.ShortagePenalties) != dLen {
+		return nil, fmt.Errorf("consumer count doesn't match length of shortage penalties!")
+	}
+	var sSum, dSum float64
+	for _, s := range supply {
+		sSum += s
+	}
+	for _, d := range demand {
+		dSum += d
+	}
+	if math.IsNaN(mass) || mass < o.Epsilon {
+		return nil, fmt.Errorf("mass %v is too small (<%v)!", mass, o.Epsilon)
+	}
+	if mass > math.Min(sSum, dSum)+o.Epsilon {
+		return nil, fmt.Errorf("mass %v is more than the total supply %v or demand %v!", mass, sSum, dSum)
+	}
+
+	// the dummy producer/consumer, none if nothing is left over
+	inf := math.Inf(1)
+	dummyS, dummyD := dSum-mass, sSum-mass
+	addRow, addCol := dummyS >= o.Epsilon, dummyD >= o.Epsilon
+	s, d := supply, demand
+	c := costs
+	if addRow || addCol {
+		s = append([]float64{}, supply...)
+		d = append([]float64{}, demand...)
+		c = make([][]float64, sLen, sLen+1)
+		for i := range c {
+			c[i] = append([]float64{}, costs[i]...)
+			if addCol {
+				c[i] = append(c[i], 0)
+				if o.SurplusCosts != nil {
+					c[i][len(c[i])-1] = o.SurplusCosts[i]
+				}
+			}
+		}
+		if addCol {
+			d = append(d, dummyD)
+		}
+		if addRow {
+			s = append(s, dummyS)
+			row := make([]float64, len(d))
+			copy(row, o.ShortagePenalties)
+			if addCol {
+				row[dLen] = inf
+			}
+			c = append(c, row)
+		}
+	}
+	inner := *o
+	inner.SurplusCosts, inner.ShortagePenalties = nil, nil
+	es, err := createProblem(s, d, c, nil, &inner)
+	if err != nil {
+		return nil, err
+	}
+	result, err := es.SolveContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the plan without the dummy producer/consumer
+	flow := make([][]float64, sLen)
+	surpluses, shortages := make([]float64, sLen), make([]float64, dLen)
+	for i := range flow {
+		flow[i] = result.Flow[i][:dLen:dLen]
+		if addCol {
+			surpluses[i] = result.Flow[i][dLen]
+		}
+	}
+	if addRow {
+		copy(shortages, result.Flow[sLen])
+	}
+	result.Flow, result.Surpluses, result.Shortages = flow, surpluses, shortages
+	return result, nil
+}
@@ -0,0 +1,136 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/yizha/go/mcf"
+)
+
+// cost of shipping mass as a min-cost flow: the source sends it to the
+// producers (up to their supply), they send it to the consumers which
+// send it to the sink (up to their demand)
+func partialCost(supply, demand []float64, costs [][]float64, mass float64) (float64, error) {
+	g := mcf.NewGraph()
+	src := g.AddNode(mass)
+	sink := g.AddNode(-mass)
+	sLen := len(supply)
+	for i := range supply {
+		g.AddArc(src, g.AddNode(0), 0, supply[i], 0)
+	}
+	for j := range demand {
+		g.AddArc(g.AddNode(0), sink, 0, demand[j], 0)
+	}
+	for i := range costs {
+		for j, c := range costs[i] {
+			if !math.IsInf(c, 1) {
+				g.AddArc(2+i, 2+sLen+j, 0, math.Inf(1), c)
+			}
+		}
+	}
+	s, err := g.Solve()
+	if err != nil {
+		return 0, err
+	}
+	return s.Cost, nil
+}
+
+// the plan ships mass within the supply/demand, the surpluses/shortages
+// are what is left and the objective is its cost
+func checkPartial(tp *TestProblem, mass float64, r *Result) error {
+	total, cost := float64(0), float64(0)
+	for i := range tp.supply {
+		sum := float64(0)
+		for j, f := range r.Flow[i] {
+			if f < -1e-9 {
+				return fmt.Errorf("flow[%v][%v]=%v", i, j, f)
+			}
+			sum += f
+			cost += f * tp.costs[i][j]
+		}
+		if math.Abs(sum+r.Surpluses[i]-tp.supply[i]) > 1e-9 {
+			return fmt.Errorf("producer %v ships %v and keeps %v of %v", i, sum, r.Surpluses[i], tp.supply[i])
+		}
+		total += sum
+	}
+	for j := range tp.demand {
+		sum := float64(0)
+		for i := range tp.supply {
+			sum += r.Flow[i][j]
+		}
+		if math.Abs(sum+r.Shortages[j]-tp.demand[j]) > 1e-9 {
+			return fmt.Errorf("consumer %v gets %v and misses %v of %v", j, sum, r.Shortages[j], tp.demand[j])
+		}
+	}
+	if math.Abs(total-mass) > 1e-9 {
+		return fmt.Errorf("plan ships %v, should be %v", total, mass)
+	}
+	if math.Abs(cost-r.Objective) > 1e-9*math.Max(1, cost) {
+		return fmt.Errorf("objective is %v, plan costs %v", r.Objective, cost)
+	}
+	return nil
+}
+
+func TestPartial(t *testing.T) {
+	r := rand.New(rand.NewSource(25))
+	for n := 0; n < 100; n++ {
+		tp := randomProblem(r, 2+r.Intn(8), 2+r.Intn(8))
+		var sSum, dSum float64
+		for _, s := range tp.supply {
+			sSum += s
+		}
+		for _, d := range tp.demand {
+			dSum += d
+		}
+		max := math.Min(sSum, dSum)
+		for _, mass := range []float64{max * r.Float64(), max / 2, max} {
+			name := fmt.Sprintf("[#%v mass=%v]", n, mass)
+			algo := []Algorithm{AlgoMODI, AlgoNetworkSimplex}[n%2]
+			result, err := SolvePartial(tp.supply, tp.demand, tp.costs, mass, WithAlgorithm(algo), WithMaxIter(0))
+			if err != nil {
+				t.Error(name, err)
+				return
+			}
+			if err := checkPartial(tp, mass, result); err != nil || !result.Optimal() {
+				t.Error(name, result.Status, err)
+				return
+			}
+			expected, err := partialCost(tp.supply, tp.demand, tp.costs, mass)
+			if err != nil {
+				t.Error(name, err)
+				return
+			}
+			if math.Abs(result.Objective-expected) > 1e-6*math.Max(1, expected) {
+				t.Error(fmt.Sprintf("%v cost is %v, min-cost flow is %v", name, result.Objective, expected))
+				return
+			}
+		}
+	}
+
+	// producer 0 is cheap but shipping its supply costs 10 per unit left
+	inf := math.Inf(1)
+	supply, demand := []float64{2, 2}, []float64{3}
+	costs := [][]float64{{5}, {1}}
+	result, err := SolvePartial(supply, demand, costs, 1, WithSurplusCosts([]float64{10, 0}))
+	if err != nil || math.Abs(result.Objective-(5+10)) > 1e-9 || math.Abs(result.Surpluses[0]-1) > 1e-9 ||
+		math.Abs(result.Surpluses[1]-2) > 1e-9 || math.Abs(result.Shortages[0]-2) > 1e-9 {
+		t.Error(fmt.Sprintf("result with surplus costs: %+v", result), err)
+		return
+	}
+	if result, err = SolvePartial(supply, demand, [][]float64{{inf}, {1}}, 3); err == nil {
+		t.Error(fmt.Sprintf("no error for mass more than the allowed routes carry: %+v", result))
+		return
+	}
+	for _, mass := range []float64{0, -1, 3.5, math.NaN()} {
+		if _, err := SolvePartial(supply, demand, costs, mass); err == nil {
+			t.Error(fmt.Sprintf("no error for mass %v", mass))
+			return
+		}
+	}
+	if _, err := SolvePartial(supply, demand, costs[:1], 1); err == nil {
+		t.Error("no error for costs of 1 producer")
+		return
+	}
+}
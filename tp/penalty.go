@@ -0,0 +1,51 @@
+package tp
+
+// cost per unit of supply[i] which isn't shipped
+func (es *Problem) surplusCostOf(i int) float64 {
+	if es.surplusCost == nil {
+		return 0
+	}
+	return es.surplusCost[i]
+}
+
+// penalty per unit of demand[j] which isn't served
+func (es *Problem) shortagePenaltyOf(j int) float64 {
+	if es.shortagePenalty == nil {
+		return 0
+	}
+	return es.shortagePenalty[j]
+}
+
+// Get the amount of every producer's supply which isn't shipped (sent
+// to the dummy consumer), should be called after calling Solve(). All
+// are 0 unless the supply is more than the demand.
+func (es *Problem) GetSurpluses() []float64 {
+	sLen, dLen := es.inputSize()
+	surpluses := make([]float64, sLen)
+	if es.balanced <= 0 {
+		return surpluses
+	}
+	for i := 0; i < sLen; i++ {
+		if fc := &es.flow[i][dLen]; fc.basic || fc.upper {
+			surpluses[i] = fc.value
+		}
+	}
+	return surpluses
+}
+
+// Get the amount of every consumer's demand which isn't served (got
+// from the dummy producer), should be called after calling Solve(). All
+// are 0 unless the demand is more than the supply.
+func (es *Problem) GetShortages() []float64 {
+	sLen, dLen := es.inputSize()
+	shortages := make([]float64, dLen)
+	if es.balanced >= 0 {
+		return shortages
+	}
+	for j := 0; j < dLen; j++ {
+		if fc := &es.flow[sLen][j]; fc.basic || fc.upper {
+			shortages[j] = fc.value
+		}
+	}
+	return shortages
+}
@@ -0,0 +1,228 @@
+package tp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// random penalties, one in ten is math.Inf(1)
+func randomPenalties(r *rand.Rand, n int) []float64 {
+	p := make([]float64, n)
+	for k := range p {
+		p[k] = float64(r.Intn(30))
+		if r.Intn(10) == 0 {
+			p[k] = math.Inf(1)
+		}
+	}
+	return p
+}
+
+func TestPenalties(t *testing.T) {
+	// the consumer with the smaller penalty goes short
+	supply := []float64{10}
+	demand := []float64{6, 6}
+	costs := [][]float64{{1, 1}}
+	for k, penalties := range [][]float64{{5, 1}, {1, 5}} {
+		p, err := NewProblem(supply, demand, costs, WithShortagePenalties(penalties))
+		if err != nil {
+			t.Error("failed to create the shortage problem", err)
+			return
+		}
+		result, err := p.Solve()
+		if err != nil || result.Objective != 12 || result.Shortages[1-k] != 2 || result.Shortages[k] != 0 {
+			t.Error(fmt.Sprintf("shortage problem with penalties %v: %+v", penalties, result), err)
+			return
+		}
+		if s := p.GetShortages(); s[1-k] != 2 || s[k] != 0 || p.GetSurpluses()[0] != 0 {
+			t.Error(fmt.Sprintf("shortage problem with penalties %v: shortages %v, surpluses %v", penalties, s, p.GetSurpluses()))
+			return
+		}
+	}
+
+	// the producer with the smaller disposal cost keeps its supply even
+	// if its route is cheaper
+	p, err := NewProblem([]float64{5, 5}, []float64{6}, [][]float64{{1}, {2}}, WithSurplusCosts([]float64{0, 10}))
+	if err != nil {
+		t.Error("failed to create the surplus problem", err)
+		return
+	}
+	result, err := p.Solve()
+	if err != nil || result.Objective != 11 || result.Surpluses[0] != 4 || result.Surpluses[1] != 0 {
+		t.Error(fmt.Sprintf("surplus problem: %+v", result), err)
+		return
+	}
+
+	r := rand.New(rand.NewSource(17))
+	for k := 0; k < 40; k++ {
+		tp := randomProblem(r, 2+r.Intn(10), 2+r.Intn(10))
+		var sSum, dSum float64
+		for _, x := range tp.supply {
+			sSum += x
+		}
+		for _, x := range tp.demand {
+			dSum += x
+		}
+		if sSum == dSum {
+			continue
+		}
+		surplus := randomPenalties(r, len(tp.supply))
+		shortage := randomPenalties(r, len(tp.demand))
+
+		// same as a balanced problem with the dummy in the inputs
+		supply, demand, costs := tp.supply, tp.demand, copyCosts(tp.costs)
+		if sSum > dSum {
+			demand = append(append([]float64(nil), demand...), sSum-dSum)
+			for i := range costs {
+				costs[i] = append(costs[i], surplus[i])
+			}
+		} else {
+			supply = append(append([]float64(nil), supply...), dSum-sSum)
+			costs = append(costs, shortage)
+		}
+		q, _ := NewProblem(supply, demand, costs, WithMaxIter(0))
+		expected, expectedErr := q.Solve()
+
+		for _, algorithm := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+			p, err := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algorithm), WithMaxIter(0),
+				WithSurplusCosts(surplus), WithShortagePenalties(shortage))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to create [%v]", tp.name), err)
+				return
+			}
+			result, err := p.Solve()
+			if expectedErr != nil {
+				if !errors.Is(err, ErrInfeasible) || !errors.Is(expectedErr, ErrInfeasible) {
+					t.Error(fmt.Sprintf("[%v] %v: expect ErrInfeasible, got %v and %v", tp.name, algorithm, err, expectedErr))
+					return
+				}
+				continue
+			}
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to solve [%v] with %v", tp.name, algorithm), err)
+				return
+			}
+			if !result.Optimal() || math.Abs(result.Objective-expected.Objective) > EPSILON {
+				t.Error(fmt.Sprintf("[%v] %v: cost %v, should be %v", tp.name, algorithm, result.Objective, expected.Objective))
+				return
+			}
+
+			// what isn't shipped (served) is reported, at no cost if
+			// its penalty is infinite
+			for i := range tp.supply {
+				sum := result.Surpluses[i]
+				for j := range tp.demand {
+					sum += result.Flow[i][j]
+				}
+				if math.Abs(sum-tp.supply[i]) > EPSILON || (math.IsInf(surplus[i], 1) && result.Surpluses[i] > EPSILON) {
+					t.Error(fmt.Sprintf("[%v] %v: producer %v ships %v and keeps %v, supply is %v", tp.name, algorithm, i, sum-result.Surpluses[i], result.Surpluses[i], tp.supply[i]))
+					return
+				}
+			}
+			for j := range tp.demand {
+				sum := result.Shortages[j]
+				for i := range tp.supply {
+					sum += result.Flow[i][j]
+				}
+				if math.Abs(sum-tp.demand[j]) > EPSILON || (math.IsInf(shortage[j], 1) && result.Shortages[j] > EPSILON) {
+					t.Error(fmt.Sprintf("[%v] %v: consumer %v gets %v and misses %v, demand is %v", tp.name, algorithm, j, sum-result.Shortages[j], result.Shortages[j], tp.demand[j]))
+					return
+				}
+			}
+		}
+	}
+
+	// a balanced problem keeps its duals within the supply/demand
+	// ranges, the dummy which would be added takes the cheapest
+	// surplus (shortage) relative to the duals
+	for k := 0; k < 10; k++ {
+		tp := randomProblem(r, 2+r.Intn(6), 2+r.Intn(6))
+		var sSum, dSum float64
+		for _, x := range tp.supply {
+			sSum += x
+		}
+		for _, x := range tp.demand {
+			dSum += x
+		}
+		if sSum > dSum {
+			tp.demand[0] += sSum - dSum
+		} else {
+			tp.supply[0] += dSum - sSum
+		}
+		surplus := randomPenalties(r, len(tp.supply))
+		shortage := randomPenalties(r, len(tp.demand))
+		opts := []Option{WithAlgorithm(AlgoNetworkSimplex), WithMaxIter(0), WithSurplusCosts(surplus), WithShortagePenalties(shortage)}
+		p, _ := NewProblem(tp.supply, tp.demand, tp.costs, opts...)
+		if _, err := p.Solve(); err != nil {
+			t.Error(fmt.Sprintf("failed to solve [%v]", tp.name), err)
+			return
+		}
+		s, err := p.GetSensitivity()
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to get sensitivity of [%v]", tp.name), err)
+			return
+		}
+		u, v := p.GetDuals()
+		vDummy, uDummy := math.Inf(1), math.Inf(1)
+		for i := range u {
+			vDummy = math.Min(vDummy, surplus[i]-u[i])
+		}
+		for j := range v {
+			uDummy = math.Min(uDummy, shortage[j]-v[j])
+		}
+		for i := range tp.supply {
+			for _, upper := range []bool{false, true} {
+				rg := s.Supplies[i]
+				if (upper && rg.Upper == tp.supply[i]) || (!upper && rg.Lower == tp.supply[i]) {
+					continue
+				}
+				supply := append([]float64(nil), tp.supply...)
+				supply[i] = math.Max(insideRange(rg, tp.supply[i], upper), EPSILON)
+				q, _ := NewProblem(supply, tp.demand, tp.costs, opts...)
+				result, err := q.Solve()
+				if err != nil {
+					t.Error(fmt.Sprintf("failed to solve [%v] with supply[%v]=%v within %v", tp.name, i, supply[i], rg), err)
+					return
+				}
+				c := float64(0)
+				for k := range supply {
+					c += supply[k] * u[k]
+				}
+				for j := range tp.demand {
+					c += tp.demand[j] * v[j]
+				}
+				if delta := supply[i] - tp.supply[i]; delta > 0 {
+					c += delta * vDummy
+				} else {
+					c -= delta * uDummy
+				}
+				if math.Abs(result.Objective-c) > 1e-6*math.Max(1, math.Abs(c)) {
+					t.Error(fmt.Sprintf("[%v] supply[%v]=%v within %v: optimal cost is %v, duals give %v", tp.name, i, supply[i], rg, result.Objective, c))
+					return
+				}
+			}
+		}
+	}
+
+	// shortages of the Sinkhorn plan
+	result, err = Sinkhorn(supply, demand, costs, 0.01, WithShortagePenalties([]float64{5, 1}), WithMaxIter(0))
+	if err != nil || math.Abs(result.Shortages[1]-2) > 1e-3 || result.Shortages[0] > 1e-3 || math.Abs(result.Objective-12) > 1e-2 {
+		t.Error(fmt.Sprintf("shortages of the Sinkhorn plan: %+v", result), err)
+		return
+	}
+
+	// invalid penalties
+	for _, opt := range []Option{
+		WithSurplusCosts([]float64{1, 2}),
+		WithShortagePenalties([]float64{1, 2, 3}),
+		WithShortagePenalties([]float64{1, math.NaN()}),
+		WithSurplusCosts([]float64{math.Inf(-1)}),
+	} {
+		if _, err = NewProblem(supply, demand, costs, opt); err == nil {
+			t.Error("expect error for invalid penalties")
+			return
+		}
+	}
+}
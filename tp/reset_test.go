@@ -0,0 +1,179 @@
+package tp
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestReset(t *testing.T) {
+	// the costs and the flow are row-major in one slice each
+	tp := testData[1]
+	p, _ := NewProblem(tp.supply, tp.demand, tp.costs)
+	for i := 0; i < p.sLen; i++ {
+		if &p.costMatrix[i][0] != &p.costs[i*p.dLen] || &p.flow[i][0] != &p.cells[i*p.dLen] {
+			t.Error(fmt.Sprintf("row %v isn't a view of the flat slices", i))
+			return
+		}
+	}
+
+	// a problem reset to every instance gets the same result as a new
+	// one, the earlier results are not changed
+	r := rand.New(rand.NewSource(22))
+	reused := &Problem{}
+	var last *Result
+	var lastFlow [][]float64
+	for k := 0; k < 60; k++ {
+		in := randomInstance(r)
+		algo := WithAlgorithm(Algorithm(r.Intn(2)))
+		fresh, err := in.NewProblem(algo, WithMaxIter(0))
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to create [%v]", in.Name), err)
+			return
+		}
+		expected, expectedErr := fresh.Solve()
+		if err = in.reset(reused, algo, WithMaxIter(0)); err != nil {
+			t.Error(fmt.Sprintf("failed to reset to [%v]", in.Name), err)
+			return
+		}
+		result, err := reused.Solve()
+		if (err == nil) != (expectedErr == nil) {
+			t.Error(fmt.Sprintf("[%v] reset: %v, new: %v", in.Name, err, expectedErr))
+			return
+		}
+		if err != nil {
+			continue
+		}
+		if result.Objective != expected.Objective || !reflect.DeepEqual(result.Flow, expected.Flow) ||
+			!reflect.DeepEqual(result.Surpluses, expected.Surpluses) || !reflect.DeepEqual(result.Shortages, expected.Shortages) {
+			t.Error(fmt.Sprintf("[%v] reset result %+v, should be %+v", in.Name, result, expected))
+			return
+		}
+		if last != nil && !reflect.DeepEqual(last.Flow, lastFlow) {
+			t.Error(fmt.Sprintf("[%v] reset changes the previous result", in.Name))
+			return
+		}
+		last, lastFlow = result, copyFlow(result.Flow)
+	}
+
+	// invalid inputs leave the problem as it was
+	p, _ = NewProblem(tp.supply, tp.demand, tp.costs, WithMaxIter(0))
+	result, _ := p.Solve()
+	if err := p.Reset(tp.supply, tp.demand[:1], tp.costs); err == nil {
+		t.Error("expect error for invalid inputs")
+		return
+	}
+	if err := p.Reset(tp.supply, tp.demand, tp.costs, WithMaxIter(-1)); err == nil {
+		t.Error("expect error for invalid options")
+		return
+	}
+	if err := p.ResetCapacitated(tp.supply, tp.demand, tp.costs, [][]float64{{1}}); err == nil {
+		t.Error("expect error for invalid capacities")
+		return
+	}
+	if again, err := p.Solve(); err != nil || again.Objective != result.Objective {
+		t.Error(fmt.Sprintf("cost after invalid resets is %v, should be %v", again.Objective, result.Objective), err)
+		return
+	}
+
+	// resetting to a problem of the same size reuses the memory
+	big := randomProblem(r, 100, 100)
+	p, _ = NewProblem(big.supply, big.demand, big.costs)
+	allocs := testing.AllocsPerRun(10, func() {
+		p.Reset(big.supply, big.demand, big.costs)
+	})
+	if allocs > 2 {
+		t.Error(fmt.Sprintf("reset makes %v allocations", allocs))
+		return
+	}
+
+	// solving it again only allocates the options and the result (the
+	// flow, surpluses and shortages) with both algorithms, the basis
+	// tree and the scratch buffers are reused
+	for _, algo := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+		p, _ = NewProblem(big.supply, big.demand, big.costs, WithAlgorithm(algo), WithMaxIter(0))
+		p.Solve()
+		allocs = testing.AllocsPerRun(5, func() {
+			p.Reset(big.supply, big.demand, big.costs, WithAlgorithm(algo), WithMaxIter(0))
+			p.Solve()
+		})
+		if allocs > 6 {
+			t.Error(fmt.Sprintf("%v: reset and solve make %v allocations", algo, allocs))
+			return
+		}
+	}
+}
+
+// deep copy of a flow matrix
+func copyFlow(flow [][]float64) [][]float64 {
+	c := make([][]float64, len(flow))
+	for i := range flow {
+		c[i] = append([]float64{}, flow[i]...)
+	}
+	return c
+}
+
+// report the GC count and pause per op
+func reportGC(b *testing.B, f func()) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	f()
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gcs/op")
+	b.ReportMetric(float64(time.Duration(after.PauseTotalNs-before.PauseTotalNs))/float64(b.N), "gc-ns/op")
+}
+
+func BenchmarkCreate(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	tp := randomProblem(r, 1000, 1000)
+	b.ReportAllocs()
+	reportGC(b, func() {
+		for n := 0; n < b.N; n++ {
+			if _, err := NewProblem(tp.supply, tp.demand, tp.costs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// solve problems of a similar size one after another, with a new
+// Problem for every one or one Problem reset to each
+func BenchmarkSolveRepeated(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	problems := make([]*TestProblem, 8)
+	for k := range problems {
+		problems[k] = randomProblem(r, 150+r.Intn(10), 150+r.Intn(10))
+	}
+	opts := []Option{WithAlgorithm(AlgoNetworkSimplex), WithMaxIter(0)}
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		reportGC(b, func() {
+			for n := 0; n < b.N; n++ {
+				tp := problems[n%len(problems)]
+				p, _ := NewProblem(tp.supply, tp.demand, tp.costs, opts...)
+				if _, err := p.Solve(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+	b.Run("reset", func(b *testing.B) {
+		b.ReportAllocs()
+		p := &Problem{}
+		reportGC(b, func() {
+			for n := 0; n < b.N; n++ {
+				tp := problems[n%len(problems)]
+				p.Reset(tp.supply, tp.demand, tp.costs, opts...)
+				if _, err := p.Solve(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
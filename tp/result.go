@@ -0,0 +1,134 @@
+package tp
+
+import "fmt"
+
+// Status of the solution returned by Solve().
+type Status int
+
+const (
+	// the solution is optimal
+	StatusOptimal Status = iota
+
+	// the optimization stopped at max iterations, the solution is
+	// feasible but may not be optimal
+	StatusIterationLimit
+
+	// the optimization stopped at the time limit (or the context
+	// deadline), the solution is feasible but may not be optimal
+	StatusTimeLimit
+
+	// the optimization stopped because the context was cancelled, the
+	// solution is feasible but may not be optimal
+	StatusCancelled
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOptimal:
+		return "optimal"
+	case StatusIterationLimit:
+		return "iteration-limit"
+	case StatusTimeLimit:
+		return "time-limit"
+	case StatusCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText writes the status as its String().
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads the status from its String().
+func (s *Status) UnmarshalText(b []byte) error {
+	for x := StatusOptimal; x <= StatusCancelled; x++ {
+		if x.String() == string(b) {
+			*s = x
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status: %v", string(b))
+}
+
+// Result of solving a transportation problem.
+type Result struct {
+	// tells if the solution is optimal or why the optimization stopped
+	Status Status `json:"status"`
+
+	// count of iterations (pivots) run to optimize the initial solution
+	Iterations int `json:"iterations"`
+
+	// the biggest violation of the optimality condition (u+v-c, or
+	// c-u-v for a route at its capacity) among the non-basic cells, it
+	// is not bigger than epsilon when the solution is optimal. For
+	// Sinkhorn() it is the error of the row sums relative to the total
+	// quantity.
+	MaxViolation float64 `json:"max_violation"`
+
+	// total cost of the solution, same as GetCost()
+	Objective float64 `json:"objective"`
+
+	// the flow (transport plan) matrix, same as GetFlow()
+	Flow [][]float64 `json:"flow"`
+
+	// supply of every producer which isn't shipped, same as
+	// GetSurpluses()
+	Surpluses []float64 `json:"surpluses,omitempty"`
+
+	// demand of every consumer which isn't served, same as
+	// GetShortages()
+	Shortages []float64 `json:"shortages,omitempty"`
+
+	// the optimization started from the basis of the previous Solve()
+	WarmStart bool `json:"warm_start,omitempty"`
+
+	// pivots saved by the warm start, an estimate: the baseline is the
+	// iteration count of the last solve which started from scratch,
+	// i.e. of the inputs before the changes (this solve's inputs are
+	// never solved from scratch), minus Iterations. It is clamped to 0,
+	// and it is 0 if it isn't a warm start
+	PivotsSaved int `json:"pivots_saved,omitempty"`
+}
+
+// Optimal tells if the solution is optimal.
+func (r *Result) Optimal() bool {
+	return r.Status == StatusOptimal
+}
+
+// compute the biggest violation among the non-basic cells, u,v must be
+// computed from the current basis
+func (es *Problem) maxViolation() float64 {
+	sLen, dLen := es.sLen, es.dLen
+	pMax := float64(0)
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			if es.flow[i][j].basic {
+				continue
+			}
+			if p := es.violation(i, j); p > pMax {
+				pMax = p
+			}
+		}
+	}
+	return pMax
+}
+
+func (es *Problem) newResult(status Status) *Result {
+	es.result = &Result{
+		Status:       status,
+		Iterations:   es.iterCnt,
+		MaxViolation: es.maxViolation(),
+		Objective:    es.GetCost(),
+		Flow:         es.GetFlow(),
+		Surpluses:    es.GetSurpluses(),
+		Shortages:    es.GetShortages(),
+		WarmStart:    es.warm,
+	}
+	if es.warm && es.coldIterCnt > es.iterCnt {
+		es.result.PivotsSaved = es.coldIterCnt - es.iterCnt
+	}
+	return es.result
+}
@@ -0,0 +1,59 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestResult(t *testing.T) {
+	problems := make([]*TestProblem, 0)
+	problems = append(problems, testData...)
+	r := rand.New(rand.NewSource(3))
+	for k := 0; k < 5; k++ {
+		problems = append(problems, randomProblem(r, 10+r.Intn(10), 10+r.Intn(10)))
+	}
+	for _, tp := range problems {
+		for _, algorithm := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+			p, err := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algorithm), WithMaxIter(0))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to create the problem %v", tp.id), err)
+				return
+			}
+			result, err := p.Solve()
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to solve the problem %v", tp.id), err)
+				return
+			}
+			if result.Status != StatusOptimal || result.MaxViolation > EPSILON {
+				t.Error(fmt.Sprintf("problem %v/%v: status=%v, max violation=%v", tp.id, algorithm, result.Status, result.MaxViolation))
+				return
+			}
+			if result.Iterations != p.GetIterationCount() || result.Objective != p.GetCost() {
+				t.Error(fmt.Sprintf("problem %v/%v: result %+v doesn't match the problem", tp.id, algorithm, result))
+				return
+			}
+			optimal := result.Objective
+
+			// stop after the first iteration
+			if result.Iterations < 2 {
+				continue
+			}
+			p, _ = NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algorithm), WithMaxIter(1))
+			result, err = p.Solve()
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to solve the problem %v", tp.id), err)
+				return
+			}
+			if result.Status != StatusIterationLimit || result.Iterations != 1 || result.MaxViolation <= EPSILON {
+				t.Error(fmt.Sprintf("problem %v/%v: result %+v should stop at the iteration limit", tp.id, algorithm, result))
+				return
+			}
+			if result.Objective < optimal-EPSILON || math.IsNaN(result.Objective) {
+				t.Error(fmt.Sprintf("problem %v/%v: cost %v is lower than the optimal one %v", tp.id, algorithm, result.Objective, optimal))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package tp
+
+import (
+	"math"
+)
+
+// Range of a value, Lower can be -Inf and Upper can be +Inf.
+type Range struct {
+	Lower, Upper float64
+}
+
+// Contains tells if the given value is within the range.
+func (r Range) Contains(x float64) bool {
+	return x >= r.Lower && x <= r.Upper
+}
+
+// Sensitivity (ranging) of an optimal solution, the dummy producer/
+// consumer added for unbalanced inputs is left out.
+type Sensitivity struct {
+	// costs[i][j] can be anywhere in Costs[i][j] while the other costs
+	// are unchanged, the current basis (and flow) stays optimal
+	Costs [][]Range
+
+	// supply[i] can be anywhere in Supplies[i] while the other inputs
+	// are unchanged, the current basis stays feasible (within the
+	// capacities if any) so the duals stay valid. The difference is
+	// absorbed by the dummy producer/consumer (or the one which would
+	// be added to balance the inputs).
+	//
+	// When the total supply is the same as the total demand, the
+	// current value is a kink: more supply goes to a dummy consumer and
+	// less is made up by a dummy producer, and the duals of the two
+	// sides differ. Each side of the range holds only on its own, the
+	// duals of GetDuals() are the ones of the balanced inputs.
+	Supplies []Range
+
+	// same as Supplies but for demand[j], with the same kink for
+	// balanced inputs
+	Demands []Range
+}
+
+// range of delta which can be sent along the tree path from node a to
+// node b without making any flow on it negative or above its capacity,
+// the flow on a forbidden route can't change
+func (es *Problem) pathRange(a, b int) (float64, float64) {
+	sLen, t := es.sLen, es.tree
+	lo, hi := math.Inf(-1), math.Inf(1)
+	// moving delta on the cell between node n and its parent, the flow
+	// goes up by delta if d is +1 and down if -1
+	limit := func(n int, d float64) {
+		ci, cj := es.treeCell(n)
+		f, c := es.flow[ci][cj].value, es.capacityOf(ci, cj)
+		if es.isForbidden(ci, cj) {
+			c = f
+		}
+		if d > 0 {
+			lo, hi = math.Max(lo, -f), math.Min(hi, c-f)
+		} else {
+			lo, hi = math.Max(lo, f-c), math.Min(hi, f)
+		}
+	}
+	x, y := a, b
+	for x != y {
+		if t.depth[x] >= t.depth[y] {
+			// goes from x to its parent
+			if x < sLen { // row -> column, +delta
+				limit(x, 1)
+			} else { // column -> row, -delta
+				limit(x, -1)
+			}
+			x = t.parent[x]
+		} else {
+			// goes from the parent of y to y
+			if y < sLen { // column -> row, -delta
+				limit(y, -1)
+			} else { // row -> column, +delta
+				limit(y, 1)
+			}
+			y = t.parent[y]
+		}
+	}
+	return lo, hi
+}
+
+// range of the cost of a basic cell, changing it by delta shifts the
+// potentials of one side of the tree which changes the reduced costs
+// of the non-basic cells crossing to the other side
+func (es *Problem) basicCostRange(i, j int, side []bool) Range {
+	sLen, dLen, t := es.sLen, es.dLen, es.tree
+
+	// mark the subtree under the cell, row i and column j are on
+	// different sides
+	for k := range side {
+		side[k] = false
+	}
+	x := sLen + j
+	if t.parent[i] == sLen+j {
+		x = i
+	}
+	for n := x; ; n = t.thread[n] {
+		side[n] = true
+		if n == t.last[x] {
+			break
+		}
+	}
+	iSide := side[i]
+
+	// the side of row i keeps its potentials, the other side gets
+	// u-delta for rows and v+delta for columns, the reduced cost of
+	// (k,l) becomes d-delta if row k is on the side of row i and
+	// column l is not, or d+delta for the other way around. It has to
+	// stay >=0 for a cell at 0 and <=0 for a cell at its capacity.
+	lo, hi := math.Inf(1), math.Inf(1)
+	for k := 0; k < sLen; k++ {
+		for l := 0; l < dLen; l++ {
+			fc := &es.flow[k][l]
+			if fc.basic {
+				continue
+			}
+			kSide, lSide := side[k] == iSide, side[sLen+l] == iSide
+			if kSide == lSide {
+				continue
+			}
+			d := math.Max(0, es.costMatrix[k][l]-es.u[k]-es.v[l])
+			if fc.upper {
+				d = math.Max(0, es.u[k]+es.v[l]-es.costMatrix[k][l])
+				kSide = !kSide
+			}
+			if kSide {
+				hi = math.Min(hi, d)
+			} else {
+				lo = math.Min(lo, d)
+			}
+		}
+	}
+	c := es.costMatrix[i][j]
+	return Range{Lower: c - lo, Upper: c + hi}
+}
+
+// returns the node which absorbs the change of a supply/demand, it is
+// the dummy producer/consumer if there is one, -1 otherwise
+func (es *Problem) dummyNode() int {
+	if es.balanced < 0 {
+		return es.nRows
+	} else if es.balanced > 0 {
+		return es.sLen + es.nCols
+	}
+	return -1
+}
+
+// returns the row with the biggest u-surplusCost and the column with the
+// biggest v-shortagePenalty (u and v if they are 0), a dummy consumer
+// (producer) added to balanced inputs would be linked to them with
+// 0-value basic cells so the duals stay optimal. The artificial
+// producer/consumer of a capacitated problem is left out.
+func (es *Problem) dummyAnchors() (int, int) {
+	k, l := 0, 0
+	for i := 1; i < es.nRows; i++ {
+		if es.u[i]-es.surplusCostOf(i) > es.u[k]-es.surplusCostOf(k) {
+			k = i
+		}
+	}
+	for j := 1; j < es.nCols; j++ {
+		if es.v[j]-es.shortagePenaltyOf(j) > es.v[l]-es.shortagePenaltyOf(l) {
+			l = j
+		}
+	}
+	return k, es.sLen + l
+}
+
+// Get the sensitivity (ranging) of the optimal solution.
+// Returns error if the last Solve() didn't reach the optimal solution
+// or the costs/supply/demand are set since it.
+func (es *Problem) GetSensitivity() (*Sensitivity, error) {
+	if err := es.checkSolved(); err != nil {
+		return nil, err
+	}
+	if es.algorithm != AlgoNetworkSimplex {
+		// the U,V method doesn't keep the basis as a tree
+		if err := es.buildTree(); err != nil {
+			return nil, err
+		}
+	}
+
+	sLen, dLen := es.inputSize()
+	s := &Sensitivity{
+		Costs:    make([][]Range, sLen),
+		Supplies: make([]Range, sLen),
+		Demands:  make([]Range, dLen),
+	}
+
+	side := make([]bool, es.sLen+es.dLen)
+	for i := 0; i < sLen; i++ {
+		s.Costs[i] = make([]Range, dLen)
+		for j := 0; j < dLen; j++ {
+			if es.flow[i][j].basic {
+				s.Costs[i][j] = es.basicCostRange(i, j, side)
+				if es.isForbidden(i, j) {
+					// a 0-value basic cell, it stays so at any cost
+					// above the lower bound
+					s.Costs[i][j].Upper = es.infinity
+				}
+			} else if es.flow[i][j].upper {
+				// stays at its capacity as long as u+v>=c
+				s.Costs[i][j] = Range{Lower: math.Inf(-1), Upper: es.u[i] + es.v[j]}
+			} else {
+				// stays non-basic as long as u+v<=c
+				s.Costs[i][j] = Range{Lower: es.u[i] + es.v[j], Upper: math.Inf(1)}
+			}
+		}
+	}
+
+	// a supply change of delta is sent along the tree path from its row
+	// to the node absorbing it, a demand change the other way around
+	dummy := es.dummyNode()
+	k, l := es.dummyAnchors()
+	for i := 0; i < sLen; i++ {
+		var lo, hi float64
+		if dummy >= 0 {
+			lo, hi = es.pathRange(i, dummy)
+		} else {
+			// more supply goes to a dummy consumer linked to row k,
+			// less supply is made up by a dummy producer linked to
+			// column l, unless the dummy can't take any
+			if !math.IsInf(es.surplusCostOf(k), 1) {
+				_, hi = es.pathRange(i, k)
+			}
+			if !math.IsInf(es.shortagePenaltyOf(l-es.sLen), 1) {
+				_, lo = es.pathRange(l, i)
+				lo = -lo
+			}
+		}
+		s.Supplies[i] = Range{Lower: es.supply[i] + lo, Upper: es.supply[i] + hi}
+	}
+	for j := 0; j < dLen; j++ {
+		var lo, hi float64
+		if dummy >= 0 {
+			lo, hi = es.pathRange(dummy, es.sLen+j)
+		} else {
+			if !math.IsInf(es.shortagePenaltyOf(l-es.sLen), 1) {
+				_, hi = es.pathRange(l, es.sLen+j)
+			}
+			if !math.IsInf(es.surplusCostOf(k), 1) {
+				_, lo = es.pathRange(es.sLen+j, k)
+				lo = -lo
+			}
+		}
+		s.Demands[j] = Range{Lower: es.demand[j] + lo, Upper: es.demand[j] + hi}
+	}
+	return s, nil
+}
@@ -0,0 +1,173 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// cost of the given flow with the given cost matrix
+func flowCost(flow, costs [][]float64) float64 {
+	cost := float64(0)
+	for i := range flow {
+		for j := range flow[i] {
+			cost += flow[i][j] * costs[i][j]
+		}
+	}
+	return cost
+}
+
+func copyCosts(costs [][]float64) [][]float64 {
+	c := make([][]float64, len(costs))
+	for i := range costs {
+		c[i] = make([]float64, len(costs[i]))
+		copy(c[i], costs[i])
+	}
+	return c
+}
+
+// a value inside the range, near the given end if it is finite
+func insideRange(r Range, x float64, upper bool) float64 {
+	if upper {
+		if math.IsInf(r.Upper, 1) {
+			return x + 10
+		}
+		return x + (r.Upper-x)*0.9
+	}
+	if math.IsInf(r.Lower, -1) {
+		return x - 10
+	}
+	return x - (x-r.Lower)*0.9
+}
+
+func TestSensitivity(t *testing.T) {
+	problems := make([]*TestProblem, 0)
+	problems = append(problems, testData...)
+	r := rand.New(rand.NewSource(4))
+	for k := 0; k < 5; k++ {
+		problems = append(problems, randomProblem(r, 3+r.Intn(6), 3+r.Intn(6)))
+	}
+	for _, tp := range problems {
+		for _, algorithm := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+			p, err := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algorithm), WithMaxIter(0))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to create the problem [%v]", tp.name), err)
+				return
+			}
+			if _, err = p.Solve(); err != nil {
+				t.Error(fmt.Sprintf("failed to solve the problem [%v]", tp.name), err)
+				return
+			}
+			s, err := p.GetSensitivity()
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to get sensitivity of [%v]", tp.name), err)
+				return
+			}
+			flow := p.GetFlow()
+
+			// the flow stays optimal for a cost inside its range
+			for i := range tp.costs {
+				for j := range tp.costs[i] {
+					cr := s.Costs[i][j]
+					if !cr.Contains(tp.costs[i][j]) {
+						t.Error(fmt.Sprintf("[%v] cost range %v doesn't contain cost %v at (%v,%v)", tp.name, cr, tp.costs[i][j], i, j))
+						return
+					}
+					for _, upper := range []bool{false, true} {
+						costs := copyCosts(tp.costs)
+						costs[i][j] = insideRange(cr, tp.costs[i][j], upper)
+						q, _ := NewProblem(tp.supply, tp.demand, costs, WithMaxIter(0))
+						result, err := q.Solve()
+						if err != nil {
+							t.Error(fmt.Sprintf("failed to solve [%v] with cost (%v,%v)=%v", tp.name, i, j, costs[i][j]), err)
+							return
+						}
+						if c := flowCost(flow, costs); math.Abs(result.Objective-c) > 1e-6*math.Max(1, c) {
+							t.Error(fmt.Sprintf("[%v] cost (%v,%v)=%v within %v: optimal cost is %v, old flow costs %v", tp.name, i, j, costs[i][j], cr, result.Objective, c))
+							return
+						}
+					}
+				}
+			}
+
+			// the duals stay valid for a supply/demand inside its range,
+			// check it with the dual objective for balanced inputs
+			if p.dummyNode() >= 0 {
+				continue
+			}
+			u, v := p.GetDuals()
+			dualCost := func(supply, demand []float64) float64 {
+				cost := float64(0)
+				for i := range supply {
+					cost += supply[i] * u[i]
+				}
+				for j := range demand {
+					cost += demand[j] * v[j]
+				}
+				return cost
+			}
+			uMax, vMax := u[0], v[0]
+			for i := range u {
+				uMax = math.Max(uMax, u[i])
+			}
+			for j := range v {
+				vMax = math.Max(vMax, v[j])
+			}
+			check := func(supply, demand []float64, what string) bool {
+				q, _ := NewProblem(supply, demand, tp.costs, WithMaxIter(0))
+				result, err := q.Solve()
+				if err != nil {
+					t.Error(fmt.Sprintf("failed to solve [%v] with %v", tp.name, what), err)
+					return false
+				}
+				// the dummy producer/consumer has 0 costs, its dual is
+				// -max(v) or -max(u)
+				var sSum, dSum float64
+				for _, x := range supply {
+					sSum += x
+				}
+				for _, x := range demand {
+					dSum += x
+				}
+				c := dualCost(supply, demand)
+				if sSum > dSum {
+					c -= (sSum - dSum) * uMax
+				} else {
+					c -= (dSum - sSum) * vMax
+				}
+				if math.Abs(result.Objective-c) > 1e-6*math.Max(1, c) {
+					t.Error(fmt.Sprintf("[%v] with %v: optimal cost is %v, duals give %v", tp.name, what, result.Objective, c))
+					return false
+				}
+				return true
+			}
+			for i := range tp.supply {
+				for _, upper := range []bool{false, true} {
+					supply := make([]float64, len(tp.supply))
+					copy(supply, tp.supply)
+					supply[i] = insideRange(s.Supplies[i], tp.supply[i], upper)
+					if supply[i] < EPSILON {
+						continue
+					}
+					if !check(supply, tp.demand, fmt.Sprintf("supply[%v]=%v within %v", i, supply[i], s.Supplies[i])) {
+						return
+					}
+				}
+			}
+			for j := range tp.demand {
+				for _, upper := range []bool{false, true} {
+					demand := make([]float64, len(tp.demand))
+					copy(demand, tp.demand)
+					demand[j] = insideRange(s.Demands[j], tp.demand[j], upper)
+					if demand[j] < EPSILON {
+						continue
+					}
+					if !check(tp.supply, demand, fmt.Sprintf("demand[%v]=%v within %v", j, demand[j], s.Demands[j])) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package tp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Solve the entropic-regularized transportation problem with the
+// Sinkhorn-Knopp method, it minimizes sum(flow*costs) - reg*H(flow)
+// where H is the entropy of the flow. The plan gets close to the exact
+// (simplex) one as reg goes down, at the price of more iterations.
+//
+//	supply, demand, costs: same as NewProblem(), math.Inf(1) marks a
+//	                       forbidden route.
+//	reg: regularization, positive, in the same unit as the costs.
+//	opts: optional args, TimeLimit, Trace, SurplusCosts and
+//	      ShortagePenalties are used as for Solve(). MaxIter is
+//	      SINKHORN_MAX_ITER by default (0 means until it converges).
+//	      Epsilon is the convergence tolerance: it stops when the row
+//	      sums of the plan are within Epsilon of the supply (relative to
+//	      the total quantity), the column sums are exact after every
+//	      iteration.
+//
+//	returns the Result{} with the plan in Flow and its transport cost
+//	(without the entropy term) in Objective. Status is StatusOptimal
+//	when it converges.
+//
+// It works on the dual potentials in the log domain so a small reg
+// doesn't underflow exp(-costs/reg). Unbalanced inputs get a dummy
+// producer/consumer, same as the exact solver.
+func Sinkhorn(supply, demand []float64, costs [][]float64, reg float64, opts ...Option) (*Result, error) {
+	return SinkhornContext(context.Background(), supply, demand, costs, reg, opts...)
+}
+
+// Same as Sinkhorn() but stops when the context is done, it is checked
+// between iterations. The plan found so far is returned with
+// StatusCancelled, or StatusTimeLimit if the context deadline is
+// exceeded.
+func SinkhornContext(ctx context.Context, supply, demand []float64, costs [][]float64, reg float64, opts ...Option) (*Result, error) {
+	if math.IsNaN(reg) || math.IsInf(reg, 0) || reg <= 0 {
+		return nil, fmt.Errorf("Given regularization is not positive: %v", reg)
+	}
+	o := DefaultOptions()
+	o.MaxIter = SINKHORN_MAX_ITER
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	// same checks and dummy producer/consumer as the exact solver
+	es, err := createProblem(supply, demand, costs, nil, o)
+	if err != nil {
+		return nil, err
+	}
+	return es.solveSinkhorn(ctx, reg)
+}
+
+// log(sum(exp(x[k]))) without overflow, -Inf terms are left out
+func logSumExp(x []float64) float64 {
+	max := math.Inf(-1)
+	for _, v := range x {
+		if v > max {
+			max = v
+		}
+	}
+	if math.IsInf(max, -1) {
+		return max
+	}
+	sum := float64(0)
+	for _, v := range x {
+		sum += math.Exp(v - max)
+	}
+	return max + math.Log(sum)
+}
+
+func (es *Problem) solveSinkhorn(ctx context.Context, reg float64) (*Result, error) {
+	sLen, dLen := es.sLen, es.dLen
+
+	// -costs/reg, -Inf on the forbidden routes
+	m := make([][]float64, sLen)
+	for i := 0; i < sLen; i++ {
+		m[i] = make([]float64, dLen)
+		for j := 0; j < dLen; j++ {
+			if es.isForbidden(i, j) {
+				m[i][j] = math.Inf(-1)
+			} else {
+				m[i][j] = -es.costMatrix[i][j] / reg
+			}
+		}
+	}
+
+	// f, g are the potentials divided by reg, the plan is
+	// exp(f[i]+g[j]+m[i][j])
+	f := make([]float64, sLen)
+	g := make([]float64, dLen)
+	rowBuf := make([]float64, dLen)
+	colBuf := make([]float64, sLen)
+	rowErr := func() float64 {
+		e := float64(0)
+		for i := 0; i < sLen; i++ {
+			sum := float64(0)
+			for j := 0; j < dLen; j++ {
+				sum += math.Exp(f[i] + g[j] + m[i][j])
+			}
+			e += math.Abs(sum - es.supply[i])
+		}
+		return e / es.quatity
+	}
+
+	start := time.Now()
+	status := StatusOptimal
+	violation := float64(0)
+	for {
+		for i := 0; i < sLen; i++ {
+			for j := 0; j < dLen; j++ {
+				rowBuf[j] = g[j] + m[i][j]
+			}
+			f[i] = math.Log(es.supply[i]) - logSumExp(rowBuf)
+			if math.IsInf(f[i], 1) {
+				return nil, fmt.Errorf("%w: producer %v has no allowed route", ErrInfeasible, i)
+			}
+		}
+		for j := 0; j < dLen; j++ {
+			for i := 0; i < sLen; i++ {
+				colBuf[i] = f[i] + m[i][j]
+			}
+			g[j] = math.Log(es.demand[j]) - logSumExp(colBuf)
+			if math.IsInf(g[j], 1) {
+				return nil, fmt.Errorf("%w: consumer %v has no allowed route", ErrInfeasible, j)
+			}
+		}
+		es.iterCnt += 1
+		violation = rowErr()
+		if es.trace != nil {
+			es.tracef("iteration #%v: row sum error=%v", es.iterCnt, violation)
+		}
+		if violation <= es.epsilon {
+			es.tracef("converged after %v iterations", es.iterCnt)
+			break
+		}
+		if limit, reached := es.limitReached(ctx, start); reached {
+			es.tracef("stopped (%v) after %v iterations", limit, es.iterCnt)
+			status = limit
+			break
+		}
+	}
+
+	// the dummy cells cost the surplus costs (shortage penalties)
+	nRows, nCols := es.inputSize()
+	flow := make([][]float64, nRows)
+	surpluses := make([]float64, nRows)
+	shortages := make([]float64, nCols)
+	cost := float64(0)
+	for i := 0; i < sLen; i++ {
+		if i < nRows {
+			flow[i] = make([]float64, nCols)
+		}
+		for j := 0; j < dLen; j++ {
+			x := math.Exp(f[i] + g[j] + m[i][j])
+			if x == 0 {
+				continue
+			}
+			cost += x * es.costMatrix[i][j]
+			if i < nRows && j < nCols {
+				flow[i][j] = x
+			} else if i < nRows {
+				surpluses[i] = x
+			} else {
+				shortages[j] = x
+			}
+		}
+	}
+	return &Result{
+		Status:       status,
+		Iterations:   es.iterCnt,
+		MaxViolation: violation,
+		Objective:    cost,
+		Flow:         flow,
+		Surpluses:    surpluses,
+		Shortages:    shortages,
+	}, nil
+}
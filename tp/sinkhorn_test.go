@@ -0,0 +1,170 @@
+package tp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSinkhorn(t *testing.T) {
+	problems := make([]*TestProblem, 0)
+	problems = append(problems, testData...)
+	r := rand.New(rand.NewSource(13))
+	for k := 0; k < 5; k++ {
+		problems = append(problems, randomProblem(r, 3+r.Intn(20), 3+r.Intn(20)))
+	}
+	for _, tp := range problems {
+		p, err := NewProblem(tp.supply, tp.demand, tp.costs, WithMaxIter(0))
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to create [%v]", tp.name), err)
+			return
+		}
+		exact, err := p.Solve()
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve [%v]", tp.name), err)
+			return
+		}
+		flow := p.GetFlow()
+		for i := range flow {
+			for j := range flow[i] {
+				if exact.Flow[i][j] != flow[i][j] {
+					t.Error(fmt.Sprintf("[%v] result flow[%v][%v]=%v, should be %v", tp.name, i, j, exact.Flow[i][j], flow[i][j]))
+					return
+				}
+			}
+		}
+
+		// the regularized cost is above the exact one and gets close to
+		// it with a small regularization
+		maxCost := float64(1)
+		for i := range tp.costs {
+			for j := range tp.costs[i] {
+				maxCost = math.Max(maxCost, tp.costs[i][j])
+			}
+		}
+		var sSum, dSum float64
+		for _, x := range tp.supply {
+			sSum += x
+		}
+		for _, x := range tp.demand {
+			dSum += x
+		}
+		// a dummy consumer (producer) with 0 costs slows it down, so the
+		// tolerance is looser than the default one
+		tol := 1e-4
+		total := math.Max(sSum, dSum)
+		for _, scale := range []float64{1e-1, 1e-2} {
+			reg := scale * maxCost
+			result, err := Sinkhorn(tp.supply, tp.demand, tp.costs, reg, WithMaxIter(100000), WithEpsilon(tol))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to solve [%v] with Sinkhorn, reg=%v", tp.name, reg), err)
+				return
+			}
+			if !result.Optimal() || result.MaxViolation > tol {
+				t.Error(fmt.Sprintf("[%v] reg=%v: not converged %v after %v iterations", tp.name, reg, result.MaxViolation, result.Iterations))
+				return
+			}
+			if result.Objective < exact.Objective-tol*maxCost*total {
+				t.Error(fmt.Sprintf("[%v] reg=%v: cost %v is below the exact one %v", tp.name, reg, result.Objective, exact.Objective))
+				return
+			}
+			// the entropy of a plan is at most log(m*n) per unit moved, the
+			// marginals are off by tol at most
+			bound := reg * total * math.Log(float64(len(tp.supply)*len(tp.demand)+1))
+			if result.Objective > exact.Objective+bound+tol*maxCost*total {
+				t.Error(fmt.Sprintf("[%v] reg=%v: cost %v is too far from the exact one %v", tp.name, reg, result.Objective, exact.Objective))
+				return
+			}
+
+			// the plan ships the supply/demand, a dummy takes the rest
+			for i := range tp.supply {
+				sum := float64(0)
+				for j := range tp.demand {
+					sum += result.Flow[i][j]
+				}
+				if sum > tp.supply[i]+tol*total || (sSum <= dSum && sum < tp.supply[i]-tol*total) {
+					t.Error(fmt.Sprintf("[%v] reg=%v: row %v ships %v, supply is %v", tp.name, reg, i, sum, tp.supply[i]))
+					return
+				}
+			}
+			for j := range tp.demand {
+				sum := float64(0)
+				for i := range tp.supply {
+					sum += result.Flow[i][j]
+				}
+				if sum > tp.demand[j]+tol*total || (sSum >= dSum && sum < tp.demand[j]-tol*total) {
+					t.Error(fmt.Sprintf("[%v] reg=%v: column %v gets %v, demand is %v", tp.name, reg, j, sum, tp.demand[j]))
+					return
+				}
+			}
+		}
+	}
+
+	// a big regularization spreads the flow like supply*demand/total
+	supply := []float64{1, 3}
+	demand := []float64{2, 2}
+	costs := [][]float64{{1, 2}, {3, 4}}
+	result, err := Sinkhorn(supply, demand, costs, 1e6)
+	if err != nil {
+		t.Error("failed to solve with a big regularization", err)
+		return
+	}
+	for i := range supply {
+		for j := range demand {
+			if want := supply[i] * demand[j] / 4; math.Abs(result.Flow[i][j]-want) > 1e-3 {
+				t.Error(fmt.Sprintf("flow[%v][%v]=%v, should be %v", i, j, result.Flow[i][j], want))
+				return
+			}
+		}
+	}
+
+	// forbidden routes get no flow
+	costs = [][]float64{{1, math.Inf(1)}, {3, 4}}
+	result, err = Sinkhorn(supply, demand, costs, 0.1, WithMaxIter(0))
+	if err != nil || result.Flow[0][1] != 0 || math.Abs(result.Flow[0][0]-1) > 1e-4 {
+		t.Error(fmt.Sprintf("forbidden route: %+v", result), err)
+		return
+	}
+	costs = [][]float64{{math.Inf(1), math.Inf(1)}, {3, 4}}
+	if _, err = Sinkhorn(supply, demand, costs, 0.1); err == nil {
+		t.Error("expect error for a producer without any allowed route")
+		return
+	}
+
+	// a small regularization takes more iterations than the pivots of
+	// the simplex method, it has its own default max
+	tp := problems[len(problems)-1]
+	result, err = Sinkhorn(tp.supply, tp.demand, tp.costs, 0.01)
+	if err != nil || !result.Optimal() || result.Iterations <= MAX_ITER {
+		t.Error(fmt.Sprintf("expect to converge after more than %v iterations by default: %v after %v iterations", MAX_ITER, result.Status, result.Iterations), err)
+		return
+	}
+
+	// stops at the limits
+	result, err = Sinkhorn(tp.supply, tp.demand, tp.costs, 0.01, WithMaxIter(3))
+	if err != nil || result.Status != StatusIterationLimit || result.Iterations != 3 {
+		t.Error(fmt.Sprintf("expect to stop after 3 iterations: %+v", result), err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = SinkhornContext(ctx, tp.supply, tp.demand, tp.costs, 0.01, WithMaxIter(0))
+	if err != nil || result.Status != StatusCancelled || result.Iterations != 1 {
+		t.Error(fmt.Sprintf("expect to stop after 1 iteration: %+v", result), err)
+		return
+	}
+
+	// invalid inputs
+	for _, reg := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if _, err = Sinkhorn(supply, demand, costs, reg); err == nil {
+			t.Error(fmt.Sprintf("expect error for reg=%v", reg))
+			return
+		}
+	}
+	if _, err = Sinkhorn(supply, demand, [][]float64{{1, 2}}, 1); err == nil {
+		t.Error("expect error for a wrong cost matrix")
+		return
+	}
+}
@@ -1,9 +1,11 @@
 package tp
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"math"
-	//"time"
+	"time"
 )
 
 const (
@@ -12,26 +14,84 @@ const (
 
 	// default max iterations for optimizing the solution
 	MAX_ITER = 100
+
+	// default max iterations of Sinkhorn(), its iterations are much
+	// cheaper than pivots and a small regularization needs many of them
+	SINKHORN_MAX_ITER = 10000
 )
 
+// Algorithm used to optimize the initial feasible solution.
+type Algorithm int
+
+const (
+	// the U,V (MODI) method with the stepping-stone loop search
+	AlgoMODI Algorithm = iota
+
+	// the network simplex method on a spanning tree of the basis
+	AlgoNetworkSimplex
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case AlgoMODI:
+		return "modi"
+	case AlgoNetworkSimplex:
+		return "network-simplex"
+	default:
+		return "unknown"
+	}
+}
+
+func (a Algorithm) valid() bool {
+	return a >= AlgoMODI && a <= AlgoNetworkSimplex
+}
+
 type Problem struct {
 
 	// 'static' variables
 	epsilon, infinity float64
 	maxIter           int
-
-	// inputs, could be adjusted if supply/demand is unbalanced
+	algorithm         Algorithm
+	strategy          InitialStrategy
+	pivotRule         PivotRule
+	timeLimit         time.Duration
+	trace             io.Writer
+	observer          Observer
+
+	// inputs, could be adjusted if supply/demand is unbalanced. The
+	// costs are stored row-major in one slice, the rows of costMatrix
+	// are views of it.
 	supply     []float64
 	demand     []float64
+	costs      []float64
 	costMatrix [][]float64
 
+	// forbidden[i][j] is true if (i,j) is a forbidden route, its cost in
+	// costMatrix is replaced with a big-M cost, nil if there is none.
+	// forbiddenCells keeps the (row-major) buffer when it is nil.
+	forbidden      [][]bool
+	forbiddenCells []bool
+
+	// capacity (upper bound of the flow) of the routes, nil if they are
+	// not capacitated, same layout as costs
+	capacity      [][]float64
+	capacityCells []float64
+
+	// cost of the routes to the dummy consumer (from the dummy
+	// producer), nil if they cost 0
+	surplusCost, shortagePenalty []float64
+
 	// balance flag
 	//  -1: supply < demand
 	//   0: supply == demand
 	//   1: supply > demand
 	balanced int
 
+	// supply, demand size of the inputs
+	nRows, nCols int
+
 	// supply, demand size after adjustment if inputs are unbalanced
+	// (and with the artificial producer/consumer if capacitated)
 	sLen, dLen int
 
 	// total amount to tranport from producers to consumers
@@ -41,6 +101,18 @@ type Problem struct {
 	// iteration count
 	iterCnt int
 
+	// the last Solve() started from the previous basis, and the
+	// iteration count of the last one which didn't
+	warm        bool
+	coldIterCnt int
+
+	// result of the last Solve(), nil if it hasn't been called
+	result *Result
+
+	// the costs or the supply/demand are set since the last Solve(), its
+	// basis and duals are not the ones of the current inputs
+	changed bool
+
 	// u, v matrix
 	u, v []float64
 
@@ -57,11 +129,38 @@ type Problem struct {
 	// 1: row/col is occupied
 	rowFlags, colFlags []int
 
-	// loop (link-list) head
-	loop *cell
-
-	// solution flow
-	flow [][]*flowcell
+	// loop (link-list) head, its cells are in loopCells which is
+	// reused by every loop search
+	loop      *cell
+	loopCells []cell
+
+	// solution flow, same layout as costs
+	cells []flowcell
+	flow  [][]flowcell
+
+	// supply and demand (in one slice) and the quatity before they are
+	// perturbed for PivotPerturbation
+	unperturbed        []float64
+	unperturbedQuatity float64
+
+	// the loop of the last pivot and the cost after it, kept for the
+	// observer only
+	observedLoop []Cell
+	observedCost float64
+
+	// basis spanning tree, only used by the network simplex method
+	tree *basisTree
+
+	// the cell the next search of PivotBlockSearch starts from
+	blockStart int
+
+	// scratch buffers kept so solving a reset problem doesn't allocate
+	// them again: the supply/demand left while finding the initial
+	// solution, the union-find forest of the nodes and what the nodes
+	// send to their parent in restoreBasis()
+	supplyLeft, demandLeft []float64
+	nodeBuf                []int
+	netBuf                 []float64
 }
 
 // to solve degeneracy, use a struct to indicate
@@ -69,6 +168,9 @@ type Problem struct {
 type flowcell struct {
 	basic bool
 	value float64
+
+	// a non-basic cell at its capacity, only for capacitated problems
+	upper bool
 }
 
 type cell struct {
@@ -98,32 +200,78 @@ func (c *cell) String() string {
 	return fmt.Sprintf("(%v,%v)/%v/%v", c.row, c.col, direction, sign)
 }
 
-func createProblem(s, d []float64, c [][]float64, maxIter int, epsilon float64) (*Problem, error) {
+func createProblem(s, d []float64, c, caps [][]float64, opts *Options) (*Problem, error) {
+	es := &Problem{}
+	if err := es.init(s, d, c, caps, opts); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// returns buf resized to n, its capacity is reused if it is enough
+func resize[T any](buf []T, n int) []T {
+	if cap(buf) < n {
+		return make([]T, n)
+	}
+	return buf[:n]
+}
+
+// returns buf resized to rows*cols and zeroed, and the rows x cols
+// matrix whose rows are views of it (row-major), the capacity of buf
+// and m is reused if it is enough
+func reshape[T any](buf []T, m [][]T, rows, cols int) ([]T, [][]T) {
+	buf = resize(buf, rows*cols)
+	clear(buf)
+	m = resize(m, rows)
+	for i := 0; i < rows; i++ {
+		m[i] = buf[i*cols : (i+1)*cols : (i+1)*cols]
+	}
+	return buf, m
+}
+
+// Check the inputs and set up the problem, the buffers of es are reused
+// when they are big enough. es isn't changed if the inputs are invalid.
+func (es *Problem) init(s, d []float64, c, caps [][]float64, opts *Options) error {
+	epsilon := opts.Epsilon
 	sLen := len(s)
 	if sLen < 1 {
-		return nil, fmt.Errorf("not enough producers, need at least 1!")
+		return fmt.Errorf("not enough producers, need at least 1!")
 	}
 	dLen := len(d)
 	if dLen < 1 {
-		return nil, fmt.Errorf("not enough consumers, need at least 1! ")
+		return fmt.Errorf("not enough consumers, need at least 1! ")
 	}
 	if sLen != len(c) {
-		return nil, fmt.Errorf("producer count doesn't match 1st dimension length of costMatrix!")
+		return fmt.Errorf("producer count doesn't match 1st dimension length of costMatrix!")
+	}
+	if opts.SurplusCosts != nil && len(opts.SurplusCosts) != sLen {
+		return fmt.Errorf("producer count doesn't match length of surplus costs!")
 	}
-	if dLen != len(c[0]) {
-		return nil, fmt.Errorf("consumer count doesn't match 2nd dimension length of costMatrix!")
+	if opts.ShortagePenalties != nil && len(opts.ShortagePenalties) != dLen {
+		return fmt.Errorf("consumer count doesn't match length of shortage penalties!")
+	}
+	for i := 0; i < sLen; i++ {
+		if dLen != len(c[i]) {
+			return fmt.Errorf("consumer count doesn't match 2nd dimension length of costMatrix!")
+		}
+		for j := 0; j < dLen; j++ {
+			// +Inf is for a forbidden route
+			if math.IsNaN(c[i][j]) || math.IsInf(c[i][j], -1) {
+				return fmt.Errorf("costMatrix[%v][%v]=%v is invalid!", i, j, c[i][j])
+			}
+		}
 	}
 
 	var sSum, dSum, quatity float64
 	for i := 0; i < sLen; i++ {
 		if s[i] < epsilon {
-			return nil, fmt.Errorf("supply[%v]=%v is too small (<%v)!", i, s[i], epsilon)
+			return fmt.Errorf("supply[%v]=%v is too small (<%v)!", i, s[i], epsilon)
 		}
 		sSum += s[i]
 	}
 	for i := 0; i < dLen; i++ {
 		if d[i] < epsilon {
-			return nil, fmt.Errorf("demand[%v]=%v is too small (<%v)!", i, d[i], epsilon)
+			return fmt.Errorf("demand[%v]=%v is too small (<%v)!", i, d[i], epsilon)
 		}
 		dSum += d[i]
 	}
@@ -141,107 +289,112 @@ func createProblem(s, d []float64, c [][]float64, maxIter int, epsilon float64)
 		quatity = sSum
 		balanced = 0
 	}
-	var supply, demand []float64
-	var costMatrix [][]float64
-	if diff > epsilon { // unbalanced
-		if sSum > dSum {
-			// copy supply
-			supply = make([]float64, sLen)
-			for i := 0; i < sLen; i++ {
-				supply[i] = s[i]
-			}
-			// copy demand and add one more (diff)
-			demand = make([]float64, dLen+1)
-			for i := 0; i < dLen; i++ {
-				demand[i] = d[i]
-			}
-			demand[dLen] = diff
-			// copy cost matrix, append one column (0s)
-			costMatrix = make([][]float64, sLen)
-			for i := 0; i < sLen; i++ {
-				costMatrix[i] = make([]float64, dLen+1)
-				for j := 0; j < dLen; j++ {
-					costMatrix[i][j] = c[i][j]
-				}
-				costMatrix[i][dLen] = 0
-			}
-			// fix demand size
-			dLen += 1
-		} else { // sSum < dSum
-			// copy supply and add one more (diff)
-			supply = make([]float64, sLen+1)
-			for i := 0; i < sLen; i++ {
-				supply[i] = s[i]
-			}
-			supply[sLen] = diff
-			// copy demand
-			demand = make([]float64, dLen)
-			for i := 0; i < dLen; i++ {
-				demand[i] = d[i]
-			}
-			// copy cost matrix and append one row (0s)
-			costMatrix = make([][]float64, sLen+1)
-			for i := 0; i < sLen; i++ {
-				costMatrix[i] = make([]float64, dLen)
-				for j := 0; j < dLen; j++ {
-					costMatrix[i][j] = c[i][j]
-				}
-			}
-			costMatrix[sLen] = make([]float64, dLen)
-			// fix supply size
-			sLen += 1
-		}
-	} else { // balanced
-		supply = make([]float64, sLen)
-		for i := 0; i < sLen; i++ {
-			supply[i] = s[i]
-		}
-		demand = make([]float64, dLen)
-		for i := 0; i < dLen; i++ {
-			demand[i] = d[i]
-		}
-		costMatrix = make([][]float64, sLen)
-		for i := 0; i < sLen; i++ {
-			costMatrix[i] = make([]float64, dLen)
-			for j := 0; j < dLen; j++ {
-				costMatrix[i][j] = c[i][j]
+	if diff <= epsilon {
+		// the difference is too small to add a dummy for
+		balanced = 0
+	}
+
+	// the sizes with the dummy (a column of surplus costs or a row of
+	// shortage penalties) and the artificial producer/consumer
+	nRows, nCols := sLen, dLen
+	if balanced > 0 {
+		dLen += 1
+	} else if balanced < 0 {
+		sLen += 1
+	}
+	if caps != nil {
+		sLen, dLen = sLen+1, dLen+1
+	}
+
+	es.supply = resize(es.supply, sLen)
+	copy(es.supply, s)
+	es.demand = resize(es.demand, dLen)
+	copy(es.demand, d)
+	es.costs, es.costMatrix = reshape(es.costs, es.costMatrix, sLen, dLen)
+	for i := 0; i < nRows; i++ {
+		copy(es.costMatrix[i], c[i])
+	}
+	if balanced > 0 {
+		es.demand[nCols] = diff
+		if opts.SurplusCosts != nil {
+			for i := 0; i < nRows; i++ {
+				es.costMatrix[i][nCols] = opts.SurplusCosts[i]
 			}
 		}
+	} else if balanced < 0 {
+		es.supply[nRows] = diff
+		if opts.ShortagePenalties != nil {
+			copy(es.costMatrix[nRows], opts.ShortagePenalties)
+		}
 	}
-
-	// create flow slice
-	flow := make([][]*flowcell, sLen)
-	for i := 0; i < sLen; i++ {
-		flow[i] = make([]*flowcell, dLen)
-		for j := 0; j < dLen; j++ {
-			flow[i][j] = &flowcell{}
+	if caps != nil {
+		// the routes from/to the artificial producer/consumer are
+		// forbidden (big-M) but the one between them which costs 0
+		ar, ac := sLen-1, dLen-1
+		es.supply[ar], es.demand[ac] = quatity, quatity
+		for i := 0; i < ar; i++ {
+			es.costMatrix[i][ac] = math.Inf(1)
+		}
+		for j := 0; j < ac; j++ {
+			es.costMatrix[ar][j] = math.Inf(1)
 		}
 	}
-	// create Problem struct
-	return &Problem{
-		epsilon:  epsilon,
-		infinity: math.Inf(1),
-		maxIter:  maxIter,
-
-		supply:     supply,
-		demand:     demand,
-		costMatrix: costMatrix,
-		balanced:   balanced,
-
-		sLen:     sLen,
-		dLen:     dLen,
-		quatity:  quatity,
-		iterCnt:  0,
-		u:        make([]float64, sLen),
-		v:        make([]float64, dLen),
-		row:      -1,
-		col:      -1,
-		rowFlags: make([]int, sLen),
-		colFlags: make([]int, dLen),
-		loop:     nil,
-
-		flow: flow,
-	}, nil
+	es.cells, es.flow = reshape(es.cells, es.flow, sLen, dLen)
+
+	es.epsilon = epsilon
+	es.infinity = math.Inf(1)
+	es.maxIter = opts.MaxIter
+	es.algorithm = opts.Algorithm
+	es.strategy = opts.InitialStrategy
+	es.pivotRule = opts.PivotRule
+	es.timeLimit = opts.TimeLimit
+	es.trace = opts.Trace
+	es.observer = opts.Observer
+	es.balanced = balanced
+	es.nRows, es.nCols = nRows, nCols
+	es.sLen, es.dLen = sLen, dLen
+	es.quatity = quatity
+	es.iterCnt = 0
+	es.warm, es.coldIterCnt = false, 0
+	es.result = nil
+	es.changed = false
+	es.u = resize(es.u, sLen)
+	es.v = resize(es.v, dLen)
+	es.row, es.col = -1, -1
+	es.blockStart = 0
+	es.rowFlags = resize(es.rowFlags, sLen)
+	es.colFlags = resize(es.colFlags, dLen)
+	es.loop = nil
+	es.loopCells = resize(es.loopCells, sLen+dLen+1)
+	if es.tree != nil {
+		es.tree.resize(sLen + dLen)
+	}
+	es.supplyLeft = resize(es.supplyLeft, sLen)
+	es.demandLeft = resize(es.demandLeft, dLen)
+	es.nodeBuf = resize(es.nodeBuf, sLen+dLen)
+	es.netBuf = resize(es.netBuf, sLen+dLen)
+	if opts.SurplusCosts != nil {
+		es.surplusCost = append(es.surplusCost[:0], opts.SurplusCosts...)
+	} else {
+		es.surplusCost = nil
+	}
+	if opts.ShortagePenalties != nil {
+		es.shortagePenalty = append(es.shortagePenalty[:0], opts.ShortagePenalties...)
+	} else {
+		es.shortagePenalty = nil
+	}
+	es.forbidden = nil
+	es.capacity = nil
+	if caps != nil {
+		es.setCapacities(caps)
+	} else if es.strategy == InitAuto {
+		es.strategy = InitLeastCost
+		if isAssignment(s, d, epsilon) {
+			es.strategy = InitAssignment
+		}
+	}
+	es.forbidRoutes()
+	return nil
 }
 
 func (es *Problem) printSolution() {
@@ -252,8 +405,6 @@ func (es *Problem) printSolution() {
 			if !es.flow[i][j].basic {
 				continue
 			}
-			//fmt.Printf("i=%v,j=%v,supply=%v,demand=%v,transport=%v\n",
-			//i, j, es.supply[i], es.demand[j], es.flow[i][j])
 			c := es.costMatrix[i][j]
 			fval := es.flow[i][j].value
 			//if fval >= 0 {
@@ -266,86 +417,7 @@ func (es *Problem) printSolution() {
 	fmt.Println("")
 }
 
-// find the initial solution with the "Minimal Cost" method
-func (es *Problem) findFeasibleSolution() int {
-	//fmt.Println("[Finding feasible solution ...]")
-	//t1 := time.Now()
-
-	sLen, dLen, epsilon, infinity := es.sLen, es.dLen, es.epsilon, es.infinity
-
-	quatity := es.quatity
-	//fmt.Printf("quatity=%v\n", quatity)
-	flowCnt := 0
-
-	for {
-		// the least-cost (selected) row/column index
-		si, sj := -1, -1
-		var minCost = infinity
-		// loop to find the least cost row/column
-		for i := 0; i < sLen; i++ {
-			// skip row if supply is "0"
-			if es.supply[i] <= 0 {
-				continue
-			}
-			for j := 0; j < dLen; j++ {
-				// skip column if demand is "0"
-				if es.demand[j] <= 0 {
-					continue
-				}
-				cost := es.costMatrix[i][j]
-				if cost < minCost {
-					si, sj, minCost = i, j, cost
-				} else if cost == minCost {
-					// for same cost cell, choose the one which
-					// transports more
-					sq := math.Min(es.supply[si], es.demand[sj])
-					q := math.Min(es.supply[i], es.demand[j])
-					if q > sq {
-						si, sj = i, j
-					}
-				}
-			}
-		}
-		// substract the selected quatity from supply/demand
-		s := es.supply[si]
-		d := es.demand[sj]
-		diff := s - d
-		q := float64(0)
-		if diff > epsilon { // s > d
-			q = d
-			es.supply[si] = diff
-			es.demand[sj] = 0
-		} else if diff < -epsilon { // s < d
-			q = s
-			es.supply[si] = 0
-			es.demand[sj] = -diff
-		} else { // s == d
-			q = s
-			es.supply[si] = 0
-			es.demand[sj] = 0
-		}
-		// remove flow value from total quatity
-		quatity = quatity - q
-		//fmt.Printf("got min cost cell at (%v,%v)/%v, flow=%v, left=%v\n", si, sj, es.costMatrix[si][sj], q, quatity)
-		// set basic variable
-		fc := es.flow[si][sj]
-		fc.basic = true
-		fc.value = q
-		flowCnt += 1
-
-		if quatity <= epsilon {
-			break
-		}
-	}
-
-	//fmt.Printf("findFeasibleSolution() done in %v\n", time.Now().Sub(t1))
-	//fmt.Println("")
-	return flowCnt
-}
-
 func (es *Problem) computeUV() error {
-	//fmt.Println("[Computing U,V ...]")
-	//t1 := time.Now()
 
 	sLen, dLen := es.sLen, es.dLen
 
@@ -362,8 +434,6 @@ func (es *Problem) computeUV() error {
 	es.u[0] = float64(0)
 	es.rowFlags[0] = 1
 	uComputedCnt += 1
-	//fmt.Println("set u[0]=0")
-	//fmt.Println("")
 
 	more2scan := false
 
@@ -374,31 +444,22 @@ func (es *Problem) computeUV() error {
 			if es.rowFlags[row] != 1 {
 				continue
 			}
-			//fmt.Printf(">> scanning row #%v ...\n", row)
 			for col := 0; col < dLen; col++ {
 				if !es.flow[row][col].basic || es.colFlags[col] > 1 {
-					//fmt.Printf(">>>> skipping col #%v.\n", col)
 					continue
 				}
 				v := es.costMatrix[row][col] - es.u[row]
 				es.v[col] = v
 				vComputedCnt += 1
-				//fmt.Printf(">>>> computed v[%v]=%v\n", col, v)
 				if es.colFlags[col] == 0 {
 					es.colFlags[col] = 1
 					more2scan = true
-					//fmt.Printf(">>>> set colFlags[%v]=1\n", col)
 				}
 			}
 			es.rowFlags[row] = 2
-			//fmt.Printf(">> set rowFlags[%v]=2\n", row)
-			//fmt.Println("")
 		}
 
-		//fmt.Printf("rowFlags=%v, colFlags=%v, uComputedCnt=%v, vComputedCnt=%v\n", es.rowFlags, es.colFlags, uComputedCnt, vComputedCnt)
-		//fmt.Println("")
 		if !more2scan || (uComputedCnt == sLen && vComputedCnt == dLen) {
-			//fmt.Println("break!")
 			break
 		}
 
@@ -408,50 +469,44 @@ func (es *Problem) computeUV() error {
 			if es.colFlags[col] != 1 {
 				continue
 			}
-			//fmt.Printf(">> scanning col #%v ...\n", col)
 			for row := 0; row < sLen; row++ {
 				if !es.flow[row][col].basic || es.rowFlags[row] > 1 {
-					//fmt.Printf(">>>> skipping row #%v.\n", row)
 					continue
 				}
 				u := es.costMatrix[row][col] - es.v[col]
 				es.u[row] = u
 				uComputedCnt += 1
-				//fmt.Printf(">>>> computed u[%v]=%v\n", row, u)
 				if es.rowFlags[row] == 0 {
 					es.rowFlags[row] = 1
 					more2scan = true
-					//fmt.Printf(">>>> set rowFlags[%v]=1\n", row)
 				}
 			}
 			es.colFlags[col] = 2
-			//fmt.Printf(">> set colFlags[%v]=2\n", col)
-			//fmt.Println("")
 		}
 
-		//fmt.Printf("rowFlags=%v, colFlags=%v, uComputedCnt=%v, vComputedCnt=%v\n", es.rowFlags, es.colFlags, uComputedCnt, vComputedCnt)
-		//fmt.Println("")
 		if !more2scan || (uComputedCnt == sLen && vComputedCnt == dLen) {
-			//fmt.Println("break!")
 			break
 		}
 	}
 
-	//fmt.Printf("computed u=%v\n", es.u)
-	//fmt.Printf("computed v=%v\n", es.v)
-	//fmt.Println("")
-
 	if uComputedCnt != sLen || vComputedCnt != dLen {
 		return fmt.Errorf("[computeUV()] U: %v/%v, V: %v/%v", uComputedCnt, sLen, vComputedCnt, dLen)
 	} else {
-		//fmt.Printf("computeUV() finished in %v\n", time.Now().Sub(t1))
 		return nil
 	}
 }
 
 func (es *Problem) isOptimal() bool {
-	//fmt.Println("[Checking if current solution is optimal ...]")
-	//t1 := time.Now()
+	switch es.pivotRule {
+	case PivotFirstEligible:
+		return es.isOptimalFirstEligible()
+	case PivotBland:
+		// always from the top-left corner
+		es.row, es.col = -1, -1
+		return es.isOptimalFirstEligible()
+	case PivotBlockSearch:
+		return es.isOptimalBlockSearch()
+	}
 	// find the base cell by computing the penalty for all no-flow cell
 	sLen, dLen := es.sLen, es.dLen
 	epsilon := es.epsilon
@@ -463,26 +518,97 @@ func (es *Problem) isOptimal() bool {
 			if es.flow[i][j].basic {
 				continue
 			}
-			p := es.u[i] + es.v[j] - es.costMatrix[i][j]
-			//fmt.Printf("i=%v,j=%v,p=%v\n", i, j, p)
+			p := es.violation(i, j)
 			if p > epsilon && p > pMax {
 				es.row, es.col, pMax = i, j, p
 			}
 		}
 	}
 	var optimal bool = (es.row == -1)
-	/*if optimal {
-		fmt.Println("current solution is optimal!")
-	} else {
-		fmt.Printf("current solution is NOT optimal! Optimization starting cell: (%v, %v)\n", es.row, es.col)
-	}
-	fmt.Println("")*/
-	//fmt.Printf("isOptimal() finished in %v\n", time.Now().Sub(t1))
 	return optimal
 }
 
+// violation of the optimality condition on the non-basic cell (i,j),
+// u+v-c for a cell at 0 and c-u-v for a cell at its capacity
+func (es *Problem) violation(i, j int) float64 {
+	p := es.u[i] + es.v[j] - es.costMatrix[i][j]
+	if es.flow[i][j].upper {
+		return -p
+	}
+	return p
+}
+
+// same as isOptimal() but takes the first violating cell as the base
+// cell, the search starts from the cell after the previous base cell
+func (es *Problem) isOptimalFirstEligible() bool {
+	sLen, dLen := es.sLen, es.dLen
+	epsilon := es.epsilon
+	cellCnt := sLen * dLen
+
+	start := 0
+	if es.row >= 0 {
+		start = (es.row*dLen + es.col + 1) % cellCnt
+	}
+	es.row, es.col = -1, -1
+	for k := 0; k < cellCnt; k++ {
+		idx := (start + k) % cellCnt
+		i, j := idx/dLen, idx%dLen
+		if es.flow[i][j].basic {
+			continue
+		}
+		if p := es.violation(i, j); p > epsilon {
+			es.row, es.col = i, j
+			return false
+		}
+	}
+	return true
+}
+
+// min count of cells in a block of PivotBlockSearch
+const minBlockSize = 10
+
+// same as isOptimal() but the cells are scanned in blocks from the one
+// after the last scanned cell, the best violating cell of the first
+// block which has any is the base cell
+func (es *Problem) isOptimalBlockSearch() bool {
+	sLen, dLen := es.sLen, es.dLen
+	epsilon := es.epsilon
+	cellCnt := sLen * dLen
+	block := int(math.Sqrt(float64(cellCnt)))
+	if block < minBlockSize {
+		block = minBlockSize
+	}
+
+	start := es.blockStart
+	if start >= cellCnt {
+		start = 0
+	}
+	es.row, es.col = -1, -1
+	pMax := epsilon
+	i, j := start/dLen, start%dLen
+	for k, cnt := 0, 0; k < cellCnt; k++ {
+		if !es.flow[i][j].basic {
+			if p := es.violation(i, j); p > pMax {
+				es.row, es.col, pMax = i, j, p
+			}
+		}
+		if j++; j == dLen {
+			if j, i = 0, i+1; i == sLen {
+				i = 0
+			}
+		}
+		if cnt++; cnt == block || k == cellCnt-1 {
+			if es.row >= 0 {
+				es.blockStart = i*dLen + j
+				return false
+			}
+			cnt = 0
+		}
+	}
+	return true
+}
+
 func (es *Problem) findLoop() error {
-	//fmt.Println("[Finding a valid loop ...]")
 	sLen, dLen := es.sLen, es.dLen
 	infinity := es.infinity
 	// reset row/col flags
@@ -497,7 +623,10 @@ func (es *Problem) findLoop() error {
 	// as the loop should end with a vertical cell, same as
 	// starting it with a vertical cell (flag=false) which should
 	// end with a horizontal cell
-	head := &cell{
+	// the cells of the chain are kept in loopCells, curr is
+	// loopCells[depth]
+	head := &es.loopCells[0]
+	*head = cell{
 		row:             es.row,
 		col:             es.col,
 		flag:            true,
@@ -507,9 +636,9 @@ func (es *Problem) findLoop() error {
 	}
 
 	curr := head
+	depth := 0
 	step := 0
 
-	//fmt.Printf("starting from cell at %v\n", curr)
 	for {
 
 		nexti, nextj := -1, -1
@@ -561,7 +690,8 @@ func (es *Problem) findLoop() error {
 				}
 			}
 
-			next := &cell{
+			next := &es.loopCells[depth+1]
+			*next = cell{
 				row:             nexti,
 				col:             nextj,
 				flag:            nextFlag,
@@ -571,10 +701,9 @@ func (es *Problem) findLoop() error {
 			}
 			curr.next = next
 			curr = next
+			depth += 1
 			step += 1
 
-			//fmt.Printf("step #%v: found next cell %v\n", step, curr)
-
 			if curr.col == head.col {
 				// found a valid loop
 				break
@@ -583,11 +712,11 @@ func (es *Problem) findLoop() error {
 			if curr == head {
 				// cannot go back from head which means we couldn't
 				// find a valid loop
-				//fmt.Printf("cannot find a valid loop starting from %v\n", head)
 				head = nil
 				break
 			} else {
 				curr = curr.prev
+				depth -= 1
 				if curr.flag { // horizontal
 					es.rowFlags[curr.row] = 0
 				} else { // vertical
@@ -595,7 +724,6 @@ func (es *Problem) findLoop() error {
 				}
 				step += 1
 
-				//fmt.Printf("step #%v: go back to %v\n", step, curr)
 			}
 		}
 	}
@@ -606,159 +734,231 @@ func (es *Problem) findLoop() error {
 		// save loop head cell
 		es.loop = head
 
-		/*fmt.Println("found a valid loop:")
-		p := head
-		for p != nil {
-			if p != head {
-				fmt.Print(" -> ")
-			}
-			fmt.Printf("%v", p)
-			//fmt.Printf("%v/%v/%v", p, es.flow[p.row][p.col], p.loopEvenMinFlow)
-			p = p.next
-		}
-		fmt.Println("")
-		fmt.Printf("loop even-cell min flow: %v\n", head.loopEvenMinFlow)
-		fmt.Println("")*/
-
 		return nil
 	} else {
 		// clear loop head cell
 		es.loop = nil
-		//fmt.Println("")
 		return fmt.Errorf("[findLoop()] cannot find a valid loop starting from (%v,%v).", es.row, es.col)
 	}
 }
 
-func (es *Problem) fixDegeneracy(dCnt int) error {
-	//fmt.Printf("[fixing degeneracy for %v variables ...]\n", dCnt)
-	//t1 := time.Now()
-	sLen, dLen := es.sLen, es.dLen
-	left := dCnt
-	for i := 0; i < sLen; i++ {
-		for j := 0; j < dLen; j++ {
-			fc := es.flow[i][j]
-			if fc.basic {
-				continue
-			}
-			// try to find an independent cell in another word,
-			// starting from this cell it shouldn't form a loop
-			es.row, es.col = i, j
-			if err := es.findLoop(); err == nil {
-				continue
-			}
-			// no loop found, set it as basic cell
-			fc.basic = true
-			fc.value = 0
-			left -= 1
-			//fmt.Printf("assigned (%v,%v) as 0-value basic cell.\n", i, j)
-			if left == 0 {
+// Move the flow around the loop and replace the leaving cell with the
+// entering one in the basis, returns the leaving cell.
+func (es *Problem) applyOptimization() (int, int) {
+	p := es.loop
+	q := p.loopEvenMinFlow
+	epsilon := es.epsilon
+	// only one of the even cells reaching 0 leaves the basis, the first
+	// one in the loop, or the first one row-major with Bland's rule
+	var leave *cell
+	for c := p.next; c != nil; c = c.next {
+		if c.flag || es.flow[c.row][c.col].value-q > epsilon {
+			continue
+		}
+		if leave == nil {
+			leave = c
+			if es.pivotRule != PivotBland {
 				break
 			}
-		}
-		if left == 0 {
-			break
+		} else if c.row < leave.row || (c.row == leave.row && c.col < leave.col) {
+			leave = c
 		}
 	}
-	if left > 0 {
-		return fmt.Errorf("[fixDegeneracy(%v)] failed to find %v independent cells.", dCnt, left)
-	}
-	//fmt.Printf("fixDegenracy finished in %v\n", time.Now().Sub(t1))
-	return nil
-}
-
-func (es *Problem) applyOptimization() {
-	//fmt.Println("[Applying optimaztion ...]")
-	//t1 := time.Now()
-	p := es.loop
-	q := p.loopEvenMinFlow
-	epsilon := es.epsilon
-	removed := false
 	for p != nil {
 		row, col := p.row, p.col
-		fc := es.flow[row][col]
+		fc := &es.flow[row][col]
 		if p.flag { // odd cell
 			fc.basic = true
 			fc.value = fc.value + q
-			//fmt.Printf("added %v to (%v,%v)\n", q, row, col)
 		} else { // even cell
-			fval := fc.value - q
-			fc.value = fval
-			//fmt.Printf("substracted %v from (%v,%v)\n", q, row, col)
-			// only remove the first one if multiply flow cells
-			// reach value 0, this is to prevent degeneracy
-			if !removed && fval <= epsilon {
+			fc.value = fc.value - q
+			if p == leave {
 				fc.basic = false
-				removed = true
-				//fmt.Printf("removed (%v,%v) from basic variables.\n", row, col)
 			}
 		}
 		p = p.next
 	}
-
-	//fmt.Println()
-	//fmt.Printf("applyOptimization finished in %v\n", time.Now().Sub(t1))
+	if leave == nil {
+		return -1, -1
+	}
+	return leave.row, leave.col
 }
 
 // Solve the transportation problem.
-// Returns error if something goes wrong.
-func (es *Problem) Solve() error {
-	//fmt.Println("[Solving the problem ...]")
-	//t1 := time.Now()
+// Returns the result of the optimization, or error if something
+// goes wrong.
+//
+// Solving it again (after changing it with SetCost(), SetSupply() or
+// SetDemand()) starts from the previous basis if it is still feasible,
+// otherwise from a new initial solution.
+func (es *Problem) Solve() (*Result, error) {
+	return es.SolveContext(context.Background())
+}
+
+// Same as Solve() but stops the optimization when the context is done,
+// it is checked between pivots. The solution found so far is returned
+// with StatusCancelled, or StatusTimeLimit if the context deadline is
+// exceeded, it is feasible but may not be optimal.
+func (es *Problem) SolveContext(ctx context.Context) (*Result, error) {
+	result, err := es.solve(ctx)
+	if es.observer != nil {
+		e := DoneEvent{Err: err}
+		if result != nil {
+			e.Status, e.Iterations, e.Cost = result.Status, result.Iterations, result.Objective
+		}
+		es.observer.Done(e)
+	}
+	return result, err
+}
+
+func (es *Problem) solve(ctx context.Context) (*Result, error) {
+	perturbed := es.pivotRule == PivotPerturbation
+	if perturbed {
+		es.perturb()
+	}
+	es.warm = es.result != nil && es.restoreBasis()
+	if !es.warm {
+		es.resetFlow()
+	}
+	es.result = nil
+	es.changed = false
+	es.iterCnt = 0
+	es.row, es.col = -1, -1
+
+	status, err := es.optimize(ctx)
+	if perturbed {
+		es.unperturb()
+		if err == nil && !es.restoreBasis() {
+			es.tracef("basis is not feasible without the perturbation, solving again with %v", PivotBland)
+			es.resetFlow()
+			es.warm = false
+			es.pivotRule = PivotBland
+			status, err = es.optimize(ctx)
+			es.pivotRule = PivotPerturbation
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = es.checkForbidden(status); err != nil {
+		return nil, err
+	}
+	if !es.warm {
+		es.coldIterCnt = es.iterCnt
+	}
+	return es.newResult(status), nil
+}
+
+// optimize the solution with the algorithm of the problem
+func (es *Problem) optimize(ctx context.Context) (Status, error) {
+	if es.algorithm == AlgoNetworkSimplex {
+		return es.solveNetworkSimplex(ctx, es.warm)
+	}
+	return es.solveMODI(ctx, es.warm)
+}
+
+// find the initial solution for the U,V method, the basic cells are
+// completed with 0-value ones for degeneracy
+func (es *Problem) initMODI() error {
 	flowCnt := es.findFeasibleSolution()
-	//es.printSolution()
-	//fmt.Printf("m=%v,n=%v,flowCnt=%v\n", es.sLen, es.dLen, flowCnt)
-	//t2 := time.Now()
-	//fmt.Printf("found feasible solution in %v\n", t2.Sub(t1))
 
 	// fix degeneracy
-	correctCnt := es.sLen + es.dLen - 1
-	dCnt := correctCnt - flowCnt
-	if dCnt > 0 {
-		if err := es.fixDegeneracy(dCnt); err != nil {
-			return err
+	if err := es.completeBasis(); err != nil {
+		return err
+	}
+
+	if es.trace != nil {
+		es.tracef("initial solution (%v): %v basic cells, cost=%v", es.strategy, flowCnt, es.GetCost())
+	}
+	return nil
+}
+
+// optimize the solution with the U,V method, from the previous basis if
+// warm is true
+func (es *Problem) solveMODI(ctx context.Context, warm bool) (Status, error) {
+	if warm {
+		if es.trace != nil {
+			es.tracef("warm start from the previous basis, cost=%v", es.GetCost())
 		}
-	} else if dCnt < 0 {
-		return fmt.Errorf("feasible solution has %v basic cells, while it should be %v!", flowCnt, correctCnt)
+	} else if err := es.initMODI(); err != nil {
+		return 0, err
 	}
-	//es.printSolution()
-	//t3 := time.Now()
-	//fmt.Printf("fixed degeneracy in %v\n", t3.Sub(t2))
+	es.observeBasis(warm)
 
-	maxIter := es.maxIter
-	//optimal := false
+	start := time.Now()
+	status := StatusOptimal
 	for {
-		//t4 := time.Now()
-		//fmt.Printf("iteration #%v\n", es.iterCnt)
 		if err := es.computeUV(); err != nil {
-			return err
+			return 0, err
 		}
-		//fmt.Printf("computed u=%v v=%v\n", es.u, es.v)
+		es.observePotentials()
 		if es.isOptimal() {
-			//fmt.Println("we got optimal solution!")
-			//optimal = true
+			if es.trace != nil {
+				es.tracef("optimal after %v iterations, cost=%v", es.iterCnt, es.GetCost())
+			}
 			break
 		}
-		//fmt.Printf("optimization start cell: (%v, %v)\n", es.row, es.col)
 		if err := es.findLoop(); err != nil {
-			return err
+			return 0, err
+		}
+		// the args would be allocated on every pivot even without a trace
+		if es.trace != nil {
+			es.tracef("iteration #%v: entering (%v,%v), theta=%v", es.iterCnt+1, es.row, es.col, es.loop.loopEvenMinFlow)
+		}
+		if es.observer != nil {
+			es.observeLoop()
 		}
-		es.applyOptimization()
-		//es.printSolution()
+		violation := es.violation(es.row, es.col)
+		leaveRow, leaveCol := es.applyOptimization()
 		es.iterCnt += 1
-		//t5 := time.Now()
-		//fmt.Printf("finished optimization iteration #%v in %v\n", es.iterCnt, t5.Sub(t4))
-		if maxIter > 0 && es.iterCnt >= maxIter {
+		es.observePivot(violation, es.loop.loopEvenMinFlow, leaveRow, leaveCol)
+		if limit, reached := es.limitReached(ctx, start); reached {
+			if es.trace != nil {
+				es.tracef("stopped (%v) after %v iterations, cost=%v", limit, es.iterCnt, es.GetCost())
+			}
+			status = limit
+			// u,v are computed before the last iteration
+			if err := es.computeUV(); err != nil {
+				return 0, err
+			}
+			es.observePotentials()
 			break
 		}
 	}
-	//t6 := time.Now()
-	//fmt.Printf("finished %v optimization iterations in %v\n", es.iterCnt, t6.Sub(t3))
 
-	//fmt.Printf("is optimal solution: %v\n", optimal)
-	//fmt.Printf("iteration ran: %v\n", es.iterCnt)
-	//fmt.Println()
+	return status, nil
+}
+
+// tells if the optimization has to stop because it has run max
+// iterations, reached the time limit or the context is done, and which
+// limit it is
+func (es *Problem) limitReached(ctx context.Context, start time.Time) (Status, bool) {
+	if es.maxIter > 0 && es.iterCnt >= es.maxIter {
+		return StatusIterationLimit, true
+	}
+	if es.timeLimit > 0 && time.Since(start) >= es.timeLimit {
+		return StatusTimeLimit, true
+	}
+	select {
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return StatusTimeLimit, true
+		}
+		return StatusCancelled, true
+	default:
+	}
+	return StatusOptimal, false
+}
 
-	return nil
+// Get the strategy which found the initial solution.
+func (es *Problem) GetInitialStrategy() InitialStrategy {
+	return es.strategy
+}
+
+// Get the count of iterations (pivots) run to optimize the initial
+// solution, should be called after calling Solve().
+func (es *Problem) GetIterationCount() int {
+	return es.iterCnt
 }
 
 // Get the solution cost, should be called after calling Solve().
@@ -767,8 +967,8 @@ func (es *Problem) GetCost() float64 {
 	cost := float64(0)
 	for i := 0; i < sLen; i++ {
 		for j := 0; j < dLen; j++ {
-			fc := es.flow[i][j]
-			if !fc.basic || fc.value == 0 {
+			fc := &es.flow[i][j]
+			if !fc.basic && !fc.upper || fc.value == 0 {
 				continue
 			}
 			cost += fc.value * es.costMatrix[i][j]
@@ -777,22 +977,20 @@ func (es *Problem) GetCost() float64 {
 	return cost
 }
 
+// returns the supply, demand size without the dummy (and artificial)
+// row/column
+func (es *Problem) inputSize() (int, int) {
+	return es.nRows, es.nCols
+}
+
 // Get the flow matrix, should be called after calling Solve().
 func (es *Problem) GetFlow() [][]float64 {
-	balanced, sLen, dLen := es.balanced, 0, 0
-	if balanced < 0 {
-		sLen, dLen = es.sLen-1, es.dLen
-	} else if balanced > 0 {
-		sLen, dLen = es.sLen, es.dLen-1
-	} else {
-		sLen, dLen = es.sLen, es.dLen
-	}
-	flow := make([][]float64, sLen)
+	sLen, dLen := es.inputSize()
+	_, flow := reshape[float64](nil, nil, sLen, dLen)
 	for i := 0; i < sLen; i++ {
-		flow[i] = make([]float64, dLen)
 		for j := 0; j < dLen; j++ {
-			fc := es.flow[i][j]
-			if !fc.basic || fc.value == 0 {
+			fc := &es.flow[i][j]
+			if !fc.basic && !fc.upper || fc.value == 0 {
 				continue
 			}
 			fval := fc.value
@@ -805,65 +1003,58 @@ func (es *Problem) GetFlow() [][]float64 {
 // Get the solution (both the total cost and the flow matrix), should
 // be called after calling Solve().
 func (es *Problem) GetCostAndFlow() (float64, [][]float64) {
-	balanced, sLen, dLen := es.balanced, 0, 0
-	if balanced < 0 {
-		sLen, dLen = es.sLen-1, es.dLen
-	} else if balanced > 0 {
-		sLen, dLen = es.sLen, es.dLen-1
-	} else {
-		sLen, dLen = es.sLen, es.dLen
-	}
-	cost := float64(0)
-	flow := make([][]float64, sLen)
-	for i := 0; i < sLen; i++ {
-		flow[i] = make([]float64, dLen)
-		for j := 0; j < dLen; j++ {
-			fc := es.flow[i][j]
-			if !fc.basic || fc.value == 0 {
-				continue
-			}
-			fval := fc.value
-			flow[i][j] = fval
-			cost += fval * es.costMatrix[i][j]
-		}
-	}
-	return cost, flow
+	return es.GetCost(), es.GetFlow()
 }
 
 // Create a transportation problem from the given args.
 //
 //  supply, demand: positive float64 array/slice.
 //  costs: 2-D matrix, row size should match supply length, column
-//         size should match demand length.
+//         size should match demand length. math.Inf(1) marks a
+//         forbidden route.
 //
 //  opts is for optional args:
 //   opts[0]: MAX_ITER, max iterations to run when optimizing the
 //            solution, default to 100.
 //   opts[1]: EPSILON, used to tell if a float64 value is zero or not,
-//            default to 1e-6.
+//            default to 1e-6 (also used if it is not positive), at
+//            most 1e-3.
+//   opts[2]: Algorithm, the method to optimize the solution with,
+//            0 for U,V (AlgoMODI), 1 for network simplex
+//            (AlgoNetworkSimplex), default to 0.
+//   opts[3]: InitialStrategy, the method to find the initial solution
+//            with, 0 for "Least Cost" (InitLeastCost), 1 for Vogel's
+//            approximation (InitVogel), 2 for "Northwest Corner"
+//            (InitNorthwestCorner), 3 for "Row Minimum"
+//            (InitRowMinimum), 5 for the assignment solver
+//            (InitAssignment), 6 for InitAuto, default to 6.
 //   if you need to use a non-default EPSILON (opt[1]), you must also
-//   set MAX_ITER (opt[0]).
+//   set MAX_ITER (opt[0]), same for opt[2] and opt[3].
 //
 //  returns the Problem{} struct.
+//
+// This is kept for compatibility, NewProblem() takes typed options.
 func CreateProblem(supply, demand []float64, costs [][]float64, opts ...float64) (*Problem, error) {
-	maxIter, epsilon := MAX_ITER, EPSILON
+	options := make([]Option, 0, len(opts))
 	optsLen := len(opts)
 	if optsLen > 0 {
-		maxIter = int(opts[0])
-		if optsLen > 1 {
-			epsilon = opts[1]
-			if epsilon > float64(1e-3) {
-				return nil, fmt.Errorf("Given epsilon is too big (>1e-3): %v", opts[1])
-			}
+		// a negative MAX_ITER used to mean no limit as well
+		maxIter := int(opts[0])
+		if maxIter < 0 {
+			maxIter = 0
+		}
+		options = append(options, WithMaxIter(maxIter))
+		// a non-positive EPSILON used to be taken as is, NewProblem()
+		// rejects it so the default one is used instead
+		if optsLen > 1 && opts[1] > 0 {
+			options = append(options, WithEpsilon(opts[1]))
+		}
+		if optsLen > 2 {
+			options = append(options, WithAlgorithm(Algorithm(opts[2])))
+		}
+		if optsLen > 3 {
+			options = append(options, WithInitialStrategy(InitialStrategy(opts[3])))
 		}
 	}
-
-	//fmt.Printf("maxIter=%v, epsilon=%v\n", maxIter, epsilon)
-
-	p, err := createProblem(supply, demand, costs, maxIter, epsilon)
-	if err == nil {
-		return p, nil
-	} else {
-		return nil, err
-	}
+	return NewProblem(supply, demand, costs, options...)
 }
@@ -1,8 +1,12 @@
 package tp
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"math/rand"
 	"testing"
+	"time"
 )
 
 type TestProblem struct {
@@ -145,10 +149,13 @@ func TestTP(t *testing.T) {
 			t.Error(fmt.Sprintf("failed to create the problem %v", tp.id), err)
 			return
 		} else {
-			err = p.Solve()
+			result, err := p.Solve()
 			if err != nil {
 				t.Error(fmt.Sprintf("failed to solve the problem %v", tp.id), err)
 				return
+			} else if !result.Optimal() {
+				t.Error(fmt.Sprintf("problem %v isn't solved to optimal: %v", tp.id, result.Status))
+				return
 			} else {
 				cost, flow := p.GetCostAndFlow()
 				printProblemAndSolution(tp, cost, flow)
@@ -156,3 +163,58 @@ func TestTP(t *testing.T) {
 		}
 	}
 }
+
+func TestSolveContext(t *testing.T) {
+	r := rand.New(rand.NewSource(10))
+	tp := randomProblem(r, 40, 40)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	for _, algorithm := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+		for _, c := range []struct {
+			ctx    context.Context
+			status Status
+		}{
+			{context.Background(), StatusOptimal},
+			{cancelled, StatusCancelled},
+			{expired, StatusTimeLimit},
+		} {
+			p, err := NewProblem(tp.supply, tp.demand, tp.costs, WithAlgorithm(algorithm), WithMaxIter(0))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to create [%v]", tp.name), err)
+				return
+			}
+			result, err := p.SolveContext(c.ctx)
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to solve [%v] with %v", tp.name, algorithm), err)
+				return
+			}
+			if result.Status != c.status {
+				t.Error(fmt.Sprintf("[%v] %v: status is %v, should be %v", tp.name, algorithm, result.Status, c.status))
+				return
+			}
+			if c.status != StatusOptimal && result.Iterations != 1 {
+				t.Error(fmt.Sprintf("[%v] %v: stopped after %v iterations", tp.name, algorithm, result.Iterations))
+				return
+			}
+
+			// the solution found so far is feasible
+			flow := p.GetFlow()
+			for i := range tp.supply {
+				sum := float64(0)
+				for j := range tp.demand {
+					sum += flow[i][j]
+				}
+				if sum > tp.supply[i]+EPSILON {
+					t.Error(fmt.Sprintf("[%v] %v: row %v ships %v, supply is %v", tp.name, algorithm, i, sum, tp.supply[i]))
+					return
+				}
+			}
+			if math.Abs(result.Objective-p.GetCost()) > EPSILON {
+				t.Error(fmt.Sprintf("[%v] %v: objective %v != cost %v", tp.name, algorithm, result.Objective, p.GetCost()))
+				return
+			}
+		}
+	}
+}
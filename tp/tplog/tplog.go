@@ -0,0 +1,99 @@
+// Package tplog is a tp.Observer which writes a structured trace of the
+// solver through the logging package: the initial basis and the end of
+// the optimization at info level (warn if it stops before the optimal
+// solution, error if it fails), every pivot and the potentials at debug
+// level.
+package tplog
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/yizha/go/logging"
+	"github.com/yizha/go/tp"
+)
+
+// Observer writes what the solver does to a logger.
+type Observer struct {
+	lg *zerolog.Logger
+
+	// if true the pivots are logged with their loop and the basis with
+	// its cells, they can be big
+	Cells bool
+}
+
+// New returns an Observer writing to the logger of the given id, see
+// logging.GetLogger().
+func New(id string) *Observer {
+	return NewWithLogger(logging.GetLogger(id))
+}
+
+// NewWithLogger returns an Observer writing to the given logger.
+func NewWithLogger(lg *zerolog.Logger) *Observer {
+	return &Observer{lg: lg}
+}
+
+// cells logged as an array of {row, col, flow}
+type cells []tp.Cell
+
+func (cs cells) MarshalZerologArray(a *zerolog.Array) {
+	for _, c := range cs {
+		a.Object(cell(c))
+	}
+}
+
+type cell tp.Cell
+
+func (c cell) MarshalZerologObject(e *zerolog.Event) {
+	e.Int("row", c.Row).Int("col", c.Col).Float64("flow", c.Flow)
+}
+
+// Basis logs the basis the optimization starts from.
+func (o *Observer) Basis(e tp.BasisEvent) {
+	ev := o.lg.Info().Str("event", "basis").
+		Str("strategy", e.Strategy.String()).
+		Bool("warm", e.Warm).
+		Int("basic-cells", len(e.Cells)).
+		Float64("cost", e.Cost)
+	if o.Cells {
+		ev = ev.Array("cells", cells(e.Cells))
+	}
+	ev.Msg("initial basis")
+}
+
+// Pivot logs a pivot.
+func (o *Observer) Pivot(e tp.PivotEvent) {
+	ev := o.lg.Debug().Str("event", "pivot").
+		Int("iteration", e.Iteration).
+		Int("row", e.Row).
+		Int("col", e.Col).
+		Float64("theta", e.Theta).
+		Int("leaving-row", e.LeavingRow).
+		Int("leaving-col", e.LeavingCol).
+		Int("loop-size", len(e.Loop)).
+		Float64("cost", e.Cost)
+	if o.Cells {
+		ev = ev.Array("loop", cells(e.Loop))
+	}
+	ev.Msg("pivot")
+}
+
+// Potentials logs the potentials.
+func (o *Observer) Potentials(u, v []float64) {
+	o.lg.Debug().Str("event", "potentials").Floats64("u", u).Floats64("v", v).Msg("potentials")
+}
+
+// Done logs how the optimization ended.
+func (o *Observer) Done(e tp.DoneEvent) {
+	if e.Err != nil {
+		o.lg.Error().Str("event", "done").Err(e.Err).Msg("failed")
+		return
+	}
+	ev := o.lg.Info()
+	if e.Status != tp.StatusOptimal {
+		ev = o.lg.Warn()
+	}
+	ev.Str("event", "done").
+		Str("status", e.Status.String()).
+		Int("iterations", e.Iterations).
+		Float64("cost", e.Cost).
+		Msg("done")
+}
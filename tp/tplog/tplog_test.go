@@ -0,0 +1,80 @@
+package tplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yizha/go/logging"
+	"github.com/yizha/go/tp"
+)
+
+// solve the problem with the observer writing to a buffer at the level,
+// returns the logged events
+func solveLogged(lvl zerolog.Level, withCells bool, opts ...tp.Option) ([]map[string]interface{}, error) {
+	var buf bytes.Buffer
+	lg := zerolog.New(&buf).Level(lvl)
+	o := NewWithLogger(&lg)
+	o.Cells = withCells
+	opts = append(opts, tp.WithObserver(o), tp.WithInitialStrategy(tp.InitNorthwestCorner))
+	p, err := tp.NewProblem([]float64{7, 9, 18}, []float64{5, 8, 7, 14},
+		[][]float64{{19, 30, 50, 10}, {70, 30, 40, 60}, {40, 8, 70, 20}}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.Solve()
+	var events []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		e := make(map[string]interface{})
+		if err := dec.Decode(&e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func TestObserver(t *testing.T) {
+	logging.SetupGlobalConf(logging.DefaultGlobalConf().SetLevel(zerolog.DebugLevel))
+	events, err := solveLogged(zerolog.DebugLevel, true, tp.WithMaxIter(0))
+	if err != nil {
+		t.Error("failed to solve", err)
+		return
+	}
+	count := make(map[string]int)
+	for _, e := range events {
+		count[fmt.Sprint(e["event"])]++
+	}
+	pivots := count["pivot"]
+	if count["basis"] != 1 || pivots == 0 || count["potentials"] != pivots+1 || count["done"] != 1 {
+		t.Error(fmt.Sprintf("logged events: %v", count))
+		return
+	}
+	first, last := events[0], events[len(events)-1]
+	if first["strategy"] != "northwest-corner" || len(first["cells"].([]interface{})) != 6 {
+		t.Error(fmt.Sprintf("basis event: %v", first))
+		return
+	}
+	if last["status"] != "optimal" || last["iterations"] != float64(pivots) || math.Abs(last["cost"].(float64)-743) > 1e-9 {
+		t.Error(fmt.Sprintf("done event: %v", last))
+		return
+	}
+	for _, e := range events {
+		if e["event"] == "pivot" && len(e["loop"].([]interface{})) != int(e["loop-size"].(float64)) {
+			t.Error(fmt.Sprintf("pivot event: %v", e))
+			return
+		}
+	}
+
+	// only the basis and the end at info level, warn if not optimal (the
+	// field names are the ones of the logging package)
+	events, _ = solveLogged(zerolog.InfoLevel, false, tp.WithMaxIter(1))
+	if len(events) != 2 || events[0]["cells"] != nil || events[1]["log-level"] != "warn" || events[1]["status"] != "iteration-limit" {
+		t.Error(fmt.Sprintf("logged events at info level: %v", events))
+		return
+	}
+}
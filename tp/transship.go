@@ -0,0 +1,168 @@
+package tp
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Arc of a transshipment network, goods can be shipped from node From
+// to node To.
+type Arc struct {
+	// tail node index
+	From int
+
+	// head node index
+	To int
+
+	// cost to ship one unit on this arc
+	Cost float64
+}
+
+// Transshipment is a network of nodes which can both receive and ship
+// goods, it is solved as a transportation problem.
+type Transshipment struct {
+	p    *Problem
+	n    int
+	arcs []Arc
+}
+
+// Create a transshipment problem, a node with a positive supply is a
+// source, one with a negative supply is a sink, the others (0) only pass
+// the goods through.
+//
+//	supply: net supply of the nodes.
+//	arcs: directed arcs, at most one from a node to another, the costs
+//	      are not negative.
+//	opts: optional args, see DefaultOptions() for the default values.
+//
+//	returns the Transshipment{} struct.
+//
+// Every node becomes both a producer and a consumer of the
+// transportation problem, with a buffer B (the total supply or demand,
+// the bigger one) added to its supply and demand. The route (k,k) costs
+// 0 and takes what doesn't pass through node k, the route (k,l) is the
+// arc from k to l and the other routes are forbidden. Same as NewProblem()
+// the total supply and demand don't have to match, the sources keep
+// (the sinks miss) the difference. Solve() returns an error wrapping
+// ErrInfeasible if the sinks can't be reached from the sources.
+func NewTransshipmentProblem(supply []float64, arcs []Arc, opts ...Option) (*Transshipment, error) {
+	n := len(supply)
+	var sSum, dSum float64
+	for k, x := range supply {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("supply[%v]=%v is invalid!", k, x)
+		}
+		if x > 0 {
+			sSum += x
+		} else {
+			dSum -= x
+		}
+	}
+	buffer := math.Max(sSum, dSum)
+	if buffer == 0 {
+		return nil, fmt.Errorf("nothing to ship, all supplies are 0!")
+	}
+
+	costs := make([][]float64, n)
+	for k := 0; k < n; k++ {
+		costs[k] = make([]float64, n)
+		for l := 0; l < n; l++ {
+			costs[k][l] = math.Inf(1)
+		}
+		costs[k][k] = 0
+	}
+	for _, a := range arcs {
+		if a.From < 0 || a.From >= n || a.To < 0 || a.To >= n || a.From == a.To {
+			return nil, fmt.Errorf("arc (%v,%v) is invalid!", a.From, a.To)
+		}
+		if math.IsInf(a.Cost, 0) || math.IsNaN(a.Cost) || a.Cost < 0 {
+			return nil, fmt.Errorf("arc (%v,%v) has invalid cost %v!", a.From, a.To, a.Cost)
+		}
+		if !math.IsInf(costs[a.From][a.To], 1) {
+			return nil, fmt.Errorf("duplicate arc (%v,%v)!", a.From, a.To)
+		}
+		costs[a.From][a.To] = a.Cost
+	}
+
+	s := make([]float64, n)
+	d := make([]float64, n)
+	for k, x := range supply {
+		s[k] = buffer + math.Max(x, 0)
+		d[k] = buffer + math.Max(-x, 0)
+	}
+	// the difference goes to (comes from) a slack node connected to the
+	// sources (sinks) only, the dummy of the transportation problem would
+	// take the buffer of any node
+	if sSum > dSum {
+		d = append(d, sSum-dSum)
+		for k := 0; k < n; k++ {
+			c := math.Inf(1)
+			if supply[k] > 0 {
+				c = 0
+			}
+			costs[k] = append(costs[k], c)
+		}
+	} else if sSum < dSum {
+		s = append(s, dSum-sSum)
+		row := make([]float64, n)
+		for l := 0; l < n; l++ {
+			row[l] = math.Inf(1)
+			if supply[l] < 0 {
+				row[l] = 0
+			}
+		}
+		costs = append(costs, row)
+	}
+	p, err := NewProblem(s, d, costs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Transshipment{
+		p:    p,
+		n:    n,
+		arcs: append([]Arc(nil), arcs...),
+	}, nil
+}
+
+// Solve the problem, the Flow of the returned Result is the node to
+// node flow (on the arcs), see Problem.Solve() for the rest.
+func (t *Transshipment) Solve() (*Result, error) {
+	return t.SolveContext(context.Background())
+}
+
+// Same as Solve() but stops when the context is done, see
+// Problem.SolveContext().
+func (t *Transshipment) SolveContext(ctx context.Context) (*Result, error) {
+	result, err := t.p.SolveContext(ctx)
+	if err != nil {
+		return result, err
+	}
+	// the diagonal is the buffer left at the nodes, the slack node is
+	// left out. The rows are copied, the result of t.p keeps its flow.
+	flow := make([][]float64, t.n)
+	for k := 0; k < t.n; k++ {
+		flow[k] = append([]float64(nil), result.Flow[k][:t.n]...)
+		flow[k][k] = 0
+	}
+	r := *result
+	r.Flow = flow
+	return &r, nil
+}
+
+// Get the flow on the arcs, in the order they were given.
+func (t *Transshipment) GetArcFlows() []float64 {
+	flow := t.p.GetFlow()
+	flows := make([]float64, len(t.arcs))
+	for k, a := range t.arcs {
+		flows[k] = flow[a.From][a.To]
+	}
+	return flows
+}
+
+// Get the underlying transportation problem, node k is both its
+// producer k and consumer k, the last consumer (producer) is the slack
+// node if the supply is more (less) than the demand.
+func (t *Transshipment) Problem() *Problem {
+	return t.p
+}
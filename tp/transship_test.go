@@ -0,0 +1,203 @@
+package tp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// shortest paths between all the nodes (Floyd-Warshall)
+func shortestPaths(n int, arcs []Arc) [][]float64 {
+	dist := make([][]float64, n)
+	for k := 0; k < n; k++ {
+		dist[k] = make([]float64, n)
+		for l := 0; l < n; l++ {
+			dist[k][l] = math.Inf(1)
+		}
+		dist[k][k] = 0
+	}
+	for _, a := range arcs {
+		dist[a.From][a.To] = math.Min(dist[a.From][a.To], a.Cost)
+	}
+	for m := 0; m < n; m++ {
+		for k := 0; k < n; k++ {
+			for l := 0; l < n; l++ {
+				dist[k][l] = math.Min(dist[k][l], dist[k][m]+dist[m][l])
+			}
+		}
+	}
+	return dist
+}
+
+func TestTransshipment(t *testing.T) {
+	// two plants ship through a warehouse which is cheaper than the
+	// direct arcs, one of the customers can only be reached through it
+	supply := []float64{30, 20, 0, -25, -25}
+	arcs := []Arc{
+		{0, 2, 1}, {1, 2, 2}, {0, 3, 6}, {2, 3, 2}, {2, 4, 3},
+	}
+	ts, err := NewTransshipmentProblem(supply, arcs)
+	if err != nil {
+		t.Error("failed to create the warehouse problem", err)
+		return
+	}
+	result, err := ts.Solve()
+	if err != nil || !result.Optimal() || math.Abs(result.Objective-195) > EPSILON {
+		t.Error(fmt.Sprintf("warehouse problem: %+v", result), err)
+		return
+	}
+	// the inner problem keeps the buffers on its diagonal
+	if inner := ts.p.result; inner == result || inner.Flow[0][0] == 0 {
+		t.Error(fmt.Sprintf("warehouse problem: inner result %+v is changed", inner))
+		return
+	}
+	want := []float64{30, 20, 0, 25, 25}
+	for k, x := range ts.GetArcFlows() {
+		if math.Abs(x-want[k]) > EPSILON || math.Abs(result.Flow[arcs[k].From][arcs[k].To]-x) > EPSILON {
+			t.Error(fmt.Sprintf("warehouse problem: arc %v flow is %v, should be %v", arcs[k], x, want[k]))
+			return
+		}
+	}
+
+	r := rand.New(rand.NewSource(14))
+	for k := 0; k < 30; k++ {
+		n := 3 + r.Intn(15)
+		supply := make([]float64, n)
+		for i := range supply {
+			switch r.Intn(3) {
+			case 0:
+				supply[i] = float64(1 + r.Intn(50))
+			case 1:
+				supply[i] = -float64(1 + r.Intn(50))
+			}
+		}
+		supply[0], supply[n-1] = float64(1+r.Intn(50)), -float64(1+r.Intn(50))
+		arcs := make([]Arc, 0)
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if i != j && r.Intn(3) == 0 {
+					arcs = append(arcs, Arc{i, j, float64(r.Intn(20))})
+				}
+			}
+		}
+		name := fmt.Sprintf("random %v nodes %v arcs", n, len(arcs))
+
+		// same as the transportation problem between the sources and
+		// the sinks on the shortest paths
+		dist := shortestPaths(n, arcs)
+		sources, sinks := make([]int, 0), make([]int, 0)
+		for i, x := range supply {
+			if x > 0 {
+				sources = append(sources, i)
+			} else if x < 0 {
+				sinks = append(sinks, i)
+			}
+		}
+		s, d := make([]float64, len(sources)), make([]float64, len(sinks))
+		costs := make([][]float64, len(sources))
+		for i, src := range sources {
+			s[i] = supply[src]
+			costs[i] = make([]float64, len(sinks))
+			for j, dst := range sinks {
+				d[j] = -supply[dst]
+				costs[i][j] = dist[src][dst]
+			}
+		}
+		p, err := NewProblem(s, d, costs, WithMaxIter(0))
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to create the reference of [%v]", name), err)
+			return
+		}
+		expected, expectedErr := p.Solve()
+
+		for _, algorithm := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+			ts, err := NewTransshipmentProblem(supply, arcs, WithAlgorithm(algorithm), WithMaxIter(0))
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to create [%v]", name), err)
+				return
+			}
+			result, err := ts.Solve()
+			if expectedErr != nil {
+				if !errors.Is(err, ErrInfeasible) || !errors.Is(expectedErr, ErrInfeasible) {
+					t.Error(fmt.Sprintf("[%v] %v: expect ErrInfeasible, got %v and %v", name, algorithm, err, expectedErr))
+					return
+				}
+				continue
+			}
+			if err != nil {
+				t.Error(fmt.Sprintf("failed to solve [%v] with %v", name, algorithm), err)
+				return
+			}
+			if !result.Optimal() || math.Abs(result.Objective-expected.Objective) > EPSILON {
+				t.Error(fmt.Sprintf("[%v] %v: cost %v, should be %v", name, algorithm, result.Objective, expected.Objective))
+				return
+			}
+
+			// the arc flows keep the balance of every node, a dummy
+			// takes the rest if the supply and demand don't match
+			var sSum, dSum float64
+			for _, x := range supply {
+				if x > 0 {
+					sSum += x
+				} else {
+					dSum -= x
+				}
+			}
+			net := make([]float64, n)
+			cost := float64(0)
+			for i, x := range ts.GetArcFlows() {
+				if x < -EPSILON {
+					t.Error(fmt.Sprintf("[%v] %v: arc %v has negative flow %v", name, algorithm, arcs[i], x))
+					return
+				}
+				net[arcs[i].From] += x
+				net[arcs[i].To] -= x
+				cost += x * arcs[i].Cost
+			}
+			if math.Abs(cost-result.Objective) > EPSILON {
+				t.Error(fmt.Sprintf("[%v] %v: arc flows cost %v, objective is %v", name, algorithm, cost, result.Objective))
+				return
+			}
+			for i, x := range supply {
+				ok := math.Abs(net[i]-x) <= EPSILON
+				if sSum > dSum && x > 0 {
+					ok = net[i] <= x+EPSILON && net[i] >= -EPSILON
+				} else if sSum < dSum && x < 0 {
+					ok = net[i] >= x-EPSILON && net[i] <= EPSILON
+				}
+				if !ok {
+					t.Error(fmt.Sprintf("[%v] %v: node %v ships %v, supply is %v", name, algorithm, i, net[i], x))
+					return
+				}
+			}
+		}
+	}
+
+	// invalid inputs
+	for _, c := range []struct {
+		supply []float64
+		arcs   []Arc
+	}{
+		{[]float64{0, 0}, []Arc{{0, 1, 1}}},
+		{[]float64{1, math.NaN()}, []Arc{{0, 1, 1}}},
+		{[]float64{1, -1}, []Arc{{0, 2, 1}}},
+		{[]float64{1, -1}, []Arc{{0, 0, 1}}},
+		{[]float64{1, -1}, []Arc{{0, 1, -1}}},
+		{[]float64{1, -1}, []Arc{{0, 1, math.Inf(1)}}},
+		{[]float64{1, -1}, []Arc{{0, 1, 1}, {0, 1, 2}}},
+	} {
+		if _, err := NewTransshipmentProblem(c.supply, c.arcs); err == nil {
+			t.Error(fmt.Sprintf("expect error for %v, %v", c.supply, c.arcs))
+			return
+		}
+	}
+
+	// the sink can't be reached
+	ts, _ = NewTransshipmentProblem([]float64{1, 0, -1}, []Arc{{0, 1, 1}, {2, 1, 1}})
+	if _, err = ts.Solve(); !errors.Is(err, ErrInfeasible) {
+		t.Error("expect ErrInfeasible for an unreachable sink, got", err)
+		return
+	}
+}
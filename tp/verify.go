@@ -0,0 +1,356 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ViolationKind tells which condition a Violation breaks.
+type ViolationKind int
+
+const (
+	// the flow on a route is negative
+	ViolationNegativeFlow ViolationKind = iota
+	// the flow on a forbidden route (+Inf cost) isn't 0
+	ViolationForbiddenRoute
+	// the flow on a route is more than its capacity
+	ViolationCapacity
+	// the flow out of a producer doesn't match its supply (or is more
+	// than it, or leaves a surplus with an infinite cost, when the
+	// supply is more than the demand)
+	ViolationSupply
+	// the flow into a consumer doesn't match its demand (or is more
+	// than it, or leaves a shortage with an infinite penalty, when the
+	// demand is more than the supply)
+	ViolationDemand
+	// u[i]+v[j] is more than costs[i][j] on a route below its capacity
+	ViolationDualFeasibility
+	// u[i]+v[j] is less than costs[i][j] on a route with flow
+	ViolationComplementarySlackness
+)
+
+func (k ViolationKind) String() string {
+	switch k {
+	case ViolationNegativeFlow:
+		return "negative-flow"
+	case ViolationForbiddenRoute:
+		return "forbidden-route"
+	case ViolationCapacity:
+		return "capacity"
+	case ViolationSupply:
+		return "supply"
+	case ViolationDemand:
+		return "demand"
+	case ViolationDualFeasibility:
+		return "dual-feasibility"
+	case ViolationComplementarySlackness:
+		return "complementary-slackness"
+	}
+	return fmt.Sprintf("ViolationKind(%d)", int(k))
+}
+
+// tells if it is a violation of the dual conditions
+func (k ViolationKind) dual() bool {
+	return k == ViolationDualFeasibility || k == ViolationComplementarySlackness
+}
+
+// Violation of a condition found by Verify().
+type Violation struct {
+	Kind ViolationKind
+
+	// the producer and the consumer, -1 if it is about the whole
+	// consumer (supply violations) or producer (demand violations).
+	// Col is len(demand) for the dummy consumer which takes the
+	// surpluses and Row is len(supply) for the dummy producer which
+	// covers the shortages.
+	Row, Col int
+
+	// the value found (a flow, a sum of flows or u+v) and the value it
+	// is checked against (0, a capacity, a supply/demand or a cost)
+	Value, Bound float64
+
+	// by how much the condition is broken, it is more than epsilon
+	Amount float64
+}
+
+func (v Violation) String() string {
+	where := fmt.Sprintf("route (%v,%v)", v.Row, v.Col)
+	if v.Col < 0 {
+		where = fmt.Sprintf("producer %v", v.Row)
+	} else if v.Row < 0 {
+		where = fmt.Sprintf("consumer %v", v.Col)
+	}
+	return fmt.Sprintf("%v at %v: %v against %v (off by %v)", v.Kind, where, v.Value, v.Bound, v.Amount)
+}
+
+// Report of Verify().
+type Report struct {
+	// all the violations found, the primal ones first
+	Violations []Violation
+
+	// the biggest amount of the primal (flow) and the dual violations
+	MaxPrimalViolation float64
+	MaxDualViolation   float64
+
+	// total cost of the flow, with the surplus costs and the shortage
+	// penalties
+	Cost float64
+
+	// the duals are given and checked
+	DualsChecked bool
+
+	// the dual objective of the given duals and Cost minus it, they are
+	// NaN unless the duals are checked and feasible. The gap is 0
+	// (within rounding) when the flow is optimal.
+	DualObjective float64
+	Gap           float64
+}
+
+// Feasible tells if the flow satisfies all the primal conditions.
+func (r *Report) Feasible() bool {
+	for _, v := range r.Violations {
+		if !v.Kind.dual() {
+			return false
+		}
+	}
+	return true
+}
+
+// Optimal tells if the duals are checked and no condition is violated,
+// the duals then certify the flow is optimal.
+func (r *Report) Optimal() bool {
+	return r.DualsChecked && len(r.Violations) == 0
+}
+
+func (r *Report) String() string {
+	var sb strings.Builder
+	status := "feasible"
+	if r.Optimal() {
+		status = "optimal"
+	} else if !r.Feasible() {
+		status = "infeasible"
+	}
+	fmt.Fprintf(&sb, "%v, cost %v", status, r.Cost)
+	if r.DualsChecked {
+		fmt.Fprintf(&sb, ", max dual violation %v", r.MaxDualViolation)
+		if !math.IsNaN(r.Gap) {
+			fmt.Fprintf(&sb, ", gap %v", r.Gap)
+		}
+	}
+	fmt.Fprintf(&sb, ", max primal violation %v, %v violation(s)", r.MaxPrimalViolation, len(r.Violations))
+	for _, v := range r.Violations {
+		fmt.Fprintf(&sb, "\n  %v", v)
+	}
+	return sb.String()
+}
+
+func (r *Report) add(kind ViolationKind, row, col int, value, bound, amount float64) {
+	r.Violations = append(r.Violations, Violation{Kind: kind, Row: row, Col: col, Value: value, Bound: bound, Amount: amount})
+	if kind.dual() {
+		r.MaxDualViolation = math.Max(r.MaxDualViolation, amount)
+	} else {
+		r.MaxPrimalViolation = math.Max(r.MaxPrimalViolation, amount)
+	}
+}
+
+// Verify the flow of the instance without solving it again, and the
+// duals (u, v) if they are given, e.g. the result of Solve() and the
+// duals of GetDuals().
+//
+//	in: the inputs of the problem, with its capacities and penalties.
+//	flow: len(in.Supply) x len(in.Demand) matrix.
+//	u, v: the duals of the producers and consumers, or both nil.
+//	epsilon: tolerance of the checks, EPSILON if it is not positive.
+//
+//	returns the report of the violations found.
+//
+// The primal checks are: the flow is not negative, 0 on the forbidden
+// routes and not more than the capacities, and it ships the supply and
+// serves the demand, the smaller side is matched exactly when the
+// inputs are unbalanced. The dual checks are: u[i]+v[j]<=costs[i][j]
+// on the routes below their capacity and u[i]+v[j]>=costs[i][j] on the
+// routes with flow. The duals of the dummy consumer/producer of
+// unbalanced inputs aren't given, the best ones are derived from u, v
+// and the penalties (v of the dummy consumer is the smallest
+// surplusCost[i]-u[i]) and the surpluses/shortages are checked against
+// them the same way.
+// Returns error if the flow or the duals don't match the inputs.
+func Verify(in *Instance, flow [][]float64, u, v []float64, epsilon float64) (*Report, error) {
+	if epsilon <= 0 {
+		epsilon = EPSILON
+	}
+	sLen, dLen := len(in.Supply), len(in.Demand)
+	if len(in.Costs) != sLen || len(flow) != sLen {
+		return nil, fmt.Errorf("producer count doesn't match 1st dimension length of costs/flow!")
+	}
+	for i := 0; i < sLen; i++ {
+		if len(in.Costs[i]) != dLen || len(flow[i]) != dLen {
+			return nil, fmt.Errorf("consumer count doesn't match 2nd dimension length of costs/flow!")
+		}
+	}
+	if in.Capacities != nil {
+		if len(in.Capacities) != sLen {
+			return nil, fmt.Errorf("producer count doesn't match 1st dimension length of capacities!")
+		}
+		for i := 0; i < sLen; i++ {
+			if len(in.Capacities[i]) != dLen {
+				return nil, fmt.Errorf("consumer count doesn't match 2nd dimension length of capacities!")
+			}
+		}
+	}
+	if (u == nil) != (v == nil) || (u != nil && (len(u) != sLen || len(v) != dLen)) {
+		return nil, fmt.Errorf("duals don't match the producer/consumer count!")
+	}
+	if in.SurplusCosts != nil && len(in.SurplusCosts) != sLen {
+		return nil, fmt.Errorf("surplus cost count doesn't match producer count!")
+	}
+	if in.ShortagePenalties != nil && len(in.ShortagePenalties) != dLen {
+		return nil, fmt.Errorf("shortage penalty count doesn't match consumer count!")
+	}
+	capacity := func(i, j int) float64 {
+		if in.Capacities == nil {
+			return math.Inf(1)
+		}
+		return in.Capacities[i][j]
+	}
+	surplusCost := func(i int) float64 {
+		if in.SurplusCosts == nil {
+			return 0
+		}
+		return in.SurplusCosts[i]
+	}
+	shortagePenalty := func(j int) float64 {
+		if in.ShortagePenalties == nil {
+			return 0
+		}
+		return in.ShortagePenalties[j]
+	}
+
+	r := &Report{DualObjective: math.NaN(), Gap: math.NaN()}
+	rowSum := make([]float64, sLen)
+	colSum := make([]float64, dLen)
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			x, c := flow[i][j], in.Costs[i][j]
+			rowSum[i] += x
+			colSum[j] += x
+			if x < -epsilon {
+				r.add(ViolationNegativeFlow, i, j, x, 0, -x)
+			}
+			if math.IsInf(c, 1) {
+				if math.Abs(x) > epsilon {
+					r.add(ViolationForbiddenRoute, i, j, x, 0, math.Abs(x))
+				}
+				continue
+			}
+			if cap := capacity(i, j); x-cap > epsilon {
+				r.add(ViolationCapacity, i, j, x, cap, x-cap)
+			}
+			r.Cost += x * c
+		}
+	}
+
+	// the bigger side may keep a surplus/shortage
+	sSum, dSum := 0.0, 0.0
+	for _, s := range in.Supply {
+		sSum += s
+	}
+	for _, d := range in.Demand {
+		dSum += d
+	}
+	surpluses := make([]float64, sLen)
+	shortages := make([]float64, dLen)
+	for i, s := range in.Supply {
+		diff := s - rowSum[i]
+		if sSum-dSum > epsilon && diff > 0 {
+			surpluses[i] = diff
+			if sc := surplusCost(i); math.IsInf(sc, 1) {
+				r.add(ViolationSupply, i, -1, rowSum[i], s, diff)
+			} else {
+				r.Cost += diff * sc
+			}
+		} else if math.Abs(diff) > epsilon {
+			r.add(ViolationSupply, i, -1, rowSum[i], s, math.Abs(diff))
+		}
+	}
+	for j, d := range in.Demand {
+		diff := d - colSum[j]
+		if dSum-sSum > epsilon && diff > 0 {
+			shortages[j] = diff
+			if sp := shortagePenalty(j); math.IsInf(sp, 1) {
+				r.add(ViolationDemand, -1, j, colSum[j], d, diff)
+			} else {
+				r.Cost += diff * sp
+			}
+		} else if math.Abs(diff) > epsilon {
+			r.add(ViolationDemand, -1, j, colSum[j], d, math.Abs(diff))
+		}
+	}
+	if u == nil {
+		return r, nil
+	}
+
+	// the dual objective is sum(u*supply)+sum(v*demand) plus the
+	// (negative) duals of the capacities at the capacitated routes and
+	// the dual of the dummy times its quantity
+	r.DualsChecked = true
+	dual := 0.0
+	for i := 0; i < sLen; i++ {
+		dual += u[i] * in.Supply[i]
+	}
+	for j := 0; j < dLen; j++ {
+		dual += v[j] * in.Demand[j]
+	}
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			x, c := flow[i][j], in.Costs[i][j]
+			if math.IsInf(c, 1) {
+				continue
+			}
+			d := u[i] + v[j] - c
+			cap := capacity(i, j)
+			atCap := !math.IsInf(cap, 1) && x >= cap-epsilon
+			if d > epsilon && !atCap {
+				r.add(ViolationDualFeasibility, i, j, u[i]+v[j], c, d)
+			}
+			if d < -epsilon && x > epsilon {
+				r.add(ViolationComplementarySlackness, i, j, u[i]+v[j], c, -d)
+			}
+			if d > 0 && !math.IsInf(cap, 1) {
+				dual -= d * cap
+			}
+		}
+	}
+	if sSum-dSum > epsilon {
+		// v of the dummy consumer, which can't be more than
+		// surplusCost[i]-u[i] of any producer
+		vd := math.Inf(1)
+		for i := 0; i < sLen; i++ {
+			vd = math.Min(vd, surplusCost(i)-u[i])
+		}
+		for i := 0; i < sLen; i++ {
+			if d := surplusCost(i) - u[i] - vd; surpluses[i] > epsilon && d > epsilon {
+				r.add(ViolationComplementarySlackness, i, dLen, u[i]+vd, surplusCost(i), d)
+			}
+		}
+		dual += vd * (sSum - dSum)
+	} else if dSum-sSum > epsilon {
+		// u of the dummy producer
+		ud := math.Inf(1)
+		for j := 0; j < dLen; j++ {
+			ud = math.Min(ud, shortagePenalty(j)-v[j])
+		}
+		for j := 0; j < dLen; j++ {
+			if d := shortagePenalty(j) - v[j] - ud; shortages[j] > epsilon && d > epsilon {
+				r.add(ViolationComplementarySlackness, sLen, j, ud+v[j], shortagePenalty(j), d)
+			}
+		}
+		dual += ud * (dSum - sSum)
+	}
+	if r.MaxDualViolation == 0 && !math.IsInf(dual, 0) {
+		r.DualObjective = dual
+		r.Gap = r.Cost - dual
+	}
+	return r, nil
+}
@@ -0,0 +1,222 @@
+package tp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// solve the instance and verify the result with its duals, returns the
+// report or the error solving it
+func solveAndVerify(in *Instance, opts ...Option) (*Report, error) {
+	p, err := in.NewProblem(opts...)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.Solve()
+	if err != nil {
+		return nil, err
+	}
+	u, v := p.GetDuals()
+	report, err := Verify(in, result.Flow, u, v, 0)
+	if err != nil {
+		return nil, err
+	}
+	if math.Abs(report.Cost-result.Objective) > 1e-6*math.Max(1, math.Abs(result.Objective)) {
+		return nil, fmt.Errorf("cost %v, the objective is %v", report.Cost, result.Objective)
+	}
+	return report, nil
+}
+
+// the report certifies the optimal solution
+func checkOptimal(report *Report) error {
+	if !report.Optimal() || math.Abs(report.Gap) > 1e-6*math.Max(1, math.Abs(report.Cost)) {
+		return fmt.Errorf("not optimal: %v", report)
+	}
+	return nil
+}
+
+func TestVerify(t *testing.T) {
+	// every fixture with both algorithms, all of them are checked
+	for _, tp := range testData {
+		in := &Instance{Name: tp.name, Supply: tp.supply, Demand: tp.demand, Costs: tp.costs}
+		for _, algo := range []Algorithm{AlgoMODI, AlgoNetworkSimplex} {
+			report, err := solveAndVerify(in, WithAlgorithm(algo), WithMaxIter(0))
+			if err == nil {
+				err = checkOptimal(report)
+			}
+			if err != nil {
+				t.Errorf("[%v] %v: %v", tp.name, algo, err)
+			}
+		}
+	}
+
+	// the fixtures in testdata
+	paths, _ := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if len(paths) == 0 {
+		t.Error("no fixture in testdata")
+		return
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Errorf("failed to open %v: %v", path, err)
+			continue
+		}
+		in, err := ReadJSON(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("failed to read %v: %v", path, err)
+			continue
+		}
+		report, err := solveAndVerify(in, WithMaxIter(0))
+		if err == nil {
+			err = checkOptimal(report)
+		}
+		if err != nil {
+			t.Errorf("%v: %v", path, err)
+		}
+	}
+
+	// random instances with forbidden routes, capacities and penalties
+	r := rand.New(rand.NewSource(20))
+	verified := 0
+	for k := 0; k < 60; k++ {
+		in := randomInstance(r)
+		report, err := solveAndVerify(in, WithMaxIter(0))
+		if errors.Is(err, ErrInfeasible) {
+			// the forbidden routes and capacities can't carry it
+			continue
+		}
+		if err == nil {
+			err = checkOptimal(report)
+		}
+		if err != nil {
+			t.Errorf("[%v] %+v: %v", in.Name, in, err)
+		}
+		verified++
+	}
+
+	if verified < 30 {
+		t.Error(fmt.Sprintf("only %v random instances out of 60 are feasible", verified))
+		return
+	}
+
+	// the duals of a solution which isn't optimal yet don't certify it
+	tp := testData[0]
+	in := &Instance{Supply: tp.supply, Demand: tp.demand, Costs: tp.costs}
+	p, _ := in.NewProblem(WithInitialStrategy(InitNorthwestCorner), WithMaxIter(1))
+	result, _ := p.Solve()
+	u, v := p.GetDuals()
+	report, err := Verify(in, result.Flow, u, v, 0)
+	if err != nil || result.Optimal() || report.Optimal() || !report.Feasible() || report.MaxDualViolation == 0 || !math.IsNaN(report.Gap) {
+		t.Error(fmt.Sprintf("%v solution is reported as %v", result.Status, report), err)
+		return
+	}
+
+	// without duals only the flow is checked
+	p, _ = in.NewProblem()
+	result, _ = p.Solve()
+	report, err = Verify(in, result.Flow, nil, nil, 0)
+	if err != nil || report.Optimal() || !report.Feasible() || report.DualsChecked || len(report.Violations) != 0 {
+		t.Error(fmt.Sprintf("report without duals: %v", report), err)
+		return
+	}
+
+	// broken flows and duals are reported
+	u, v = p.GetDuals()
+	inf := math.Inf(1)
+	for _, c := range []struct {
+		name   string
+		in     *Instance
+		change func(flow [][]float64, u, v []float64)
+		kinds  []ViolationKind
+	}{
+		{"moved flow", in, func(flow [][]float64, u, v []float64) {
+			flow[0][0] += 10
+			flow[1][1] -= 10
+		}, []ViolationKind{ViolationSupply, ViolationDemand}},
+		{"negative flow", in, func(flow [][]float64, u, v []float64) {
+			flow[2][0] -= 1
+			flow[2][1] += 1
+			flow[0][0] += 1
+			flow[0][1] -= 1
+		}, []ViolationKind{ViolationNegativeFlow}},
+		{"changed dual", in, func(flow [][]float64, u, v []float64) {
+			u[1] += 1
+		}, []ViolationKind{ViolationDualFeasibility}},
+		{"forbidden route", &Instance{Supply: in.Supply, Demand: in.Demand, Costs: [][]float64{
+			{3, inf, 7, 4}, {2, 6, 5, 9}, {8, 3, 3, 2},
+		}}, nil, []ViolationKind{ViolationForbiddenRoute}},
+		{"capacity", &Instance{Supply: in.Supply, Demand: in.Demand, Costs: in.Costs, Capacities: [][]float64{
+			{inf, 100, inf, inf}, {inf, inf, inf, inf}, {inf, inf, inf, inf},
+		}}, func(flow [][]float64, u, v []float64) {
+			u[0] = math.NaN()
+		}, []ViolationKind{ViolationCapacity}},
+	} {
+		flow := make([][]float64, len(result.Flow))
+		for i := range flow {
+			flow[i] = append([]float64{}, result.Flow[i]...)
+		}
+		u2, v2 := append([]float64{}, u...), append([]float64{}, v...)
+		if c.change != nil {
+			c.change(flow, u2, v2)
+		}
+		if c.name == "capacity" {
+			// the capacity is only checked on the flow
+			u2, v2 = nil, nil
+		}
+		report, err := Verify(c.in, flow, u2, v2, 0)
+		if err != nil {
+			t.Error(fmt.Sprintf("[%v] failed to verify", c.name), err)
+			return
+		}
+		found := make(map[ViolationKind]bool)
+		for _, v := range report.Violations {
+			found[v.Kind] = true
+		}
+		ok := true
+		for _, kind := range c.kinds {
+			ok = ok && found[kind]
+		}
+		if !ok || report.Optimal() {
+			t.Error(fmt.Sprintf("[%v] violations should include %v: %v", c.name, c.kinds, report))
+			return
+		}
+	}
+
+	// a surplus left on a producer whose surplus cost is more than the
+	// others' breaks the complementary slackness with the dummy consumer
+	in = &Instance{Supply: []float64{10, 10}, Demand: []float64{10}, Costs: [][]float64{{1}, {1}}, SurplusCosts: []float64{0, 5}}
+	report, err = Verify(in, [][]float64{{0}, {10}}, []float64{0, 0}, []float64{1}, 0)
+	if err != nil || !report.Optimal() || math.Abs(report.Gap) > 1e-9 {
+		t.Error(fmt.Sprintf("optimal surplus is reported as %v", report), err)
+		return
+	}
+	report, err = Verify(in, [][]float64{{10}, {0}}, []float64{0, 0}, []float64{1}, 0)
+	if err != nil || report.Optimal() || len(report.Violations) != 1 || report.Violations[0].Row != 1 || report.Violations[0].Col != 1 ||
+		report.Violations[0].Kind != ViolationComplementarySlackness {
+		t.Error(fmt.Sprintf("costly surplus is reported as %v", report), err)
+		return
+	}
+
+	// invalid inputs
+	for _, c := range []struct {
+		flow [][]float64
+		u, v []float64
+	}{
+		{[][]float64{{10}}, nil, nil},
+		{[][]float64{{10}, {0, 1}}, nil, nil},
+		{[][]float64{{10}, {0}}, []float64{0, 0}, nil},
+		{[][]float64{{10}, {0}}, []float64{0}, []float64{1}},
+	} {
+		if _, err := Verify(in, c.flow, c.u, c.v, 0); err == nil {
+			t.Error(fmt.Sprintf("expect error for %v, %v, %v", c.flow, c.u, c.v))
+			return
+		}
+	}
+}
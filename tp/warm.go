@@ -0,0 +1,209 @@
+package tp
+
+import (
+	"fmt"
+	"math"
+)
+
+// Set costs[i][j] of the problem, math.Inf(1) makes it a forbidden route.
+// The next Solve() starts from the current basis, the flow stays the
+// same and only the duals change. GetReducedCosts() and GetSensitivity()
+// return an error until it is called.
+func (es *Problem) SetCost(i, j int, c float64) error {
+	if i < 0 || i >= es.nRows || j < 0 || j >= es.nCols {
+		return fmt.Errorf("cost index (%v,%v) is out of range!", i, j)
+	}
+	if math.IsNaN(c) || math.IsInf(c, -1) {
+		return fmt.Errorf("cost %v is invalid!", c)
+	}
+	if es.isForbidden(i, j) {
+		es.forbidden[i][j] = false
+	}
+	es.costMatrix[i][j] = c
+	es.changed = true
+	if es.forbidden != nil || math.IsInf(c, 1) {
+		es.reforbid()
+	}
+	return nil
+}
+
+// Set supply[i] of the problem. The next Solve() starts from the current
+// basis if it stays feasible with the new supply, the problem starts
+// over if the change adds or removes the dummy producer/consumer.
+func (es *Problem) SetSupply(i int, x float64) error {
+	if i < 0 || i >= es.nRows {
+		return fmt.Errorf("supply index %v is out of range!", i)
+	}
+	if x < es.epsilon {
+		return fmt.Errorf("supply[%v]=%v is too small (<%v)!", i, x, es.epsilon)
+	}
+	es.supply[i] = x
+	es.changed = true
+	if !es.rebalance() {
+		return es.rebuild()
+	}
+	return nil
+}
+
+// Set demand[j] of the problem, same as SetSupply() otherwise.
+func (es *Problem) SetDemand(j int, x float64) error {
+	if j < 0 || j >= es.nCols {
+		return fmt.Errorf("demand index %v is out of range!", j)
+	}
+	if x < es.epsilon {
+		return fmt.Errorf("demand[%v]=%v is too small (<%v)!", j, x, es.epsilon)
+	}
+	es.demand[j] = x
+	es.changed = true
+	if !es.rebalance() {
+		return es.rebuild()
+	}
+	return nil
+}
+
+// put the +Inf cost back on the forbidden routes and mark them again,
+// the big-M cost depends on the other costs
+func (es *Problem) reforbid() {
+	if es.forbidden != nil {
+		for i := 0; i < es.sLen; i++ {
+			for j := 0; j < es.dLen; j++ {
+				if es.forbidden[i][j] {
+					es.costMatrix[i][j] = es.infinity
+				}
+			}
+		}
+		es.forbidden = nil
+	}
+	es.forbidRoutes()
+}
+
+// Update the dummy (and artificial) producer/consumer after a supply/
+// demand change. Returns false if the balance flag changes, the dummy
+// has to be added or removed then.
+func (es *Problem) rebalance() bool {
+	var sSum, dSum float64
+	for i := 0; i < es.nRows; i++ {
+		sSum += es.supply[i]
+	}
+	for j := 0; j < es.nCols; j++ {
+		dSum += es.demand[j]
+	}
+	balanced, quatity := 0, sSum
+	if sSum-dSum > es.epsilon {
+		balanced = 1
+	} else if dSum-sSum > es.epsilon {
+		balanced, quatity = -1, dSum
+	}
+	if balanced != es.balanced {
+		return false
+	}
+	if balanced < 0 {
+		es.supply[es.nRows] = dSum - sSum
+	} else if balanced > 0 {
+		es.demand[es.nCols] = sSum - dSum
+	}
+	es.quatity = quatity
+	if es.capacity != nil {
+		es.supply[es.sLen-1] = quatity
+		es.demand[es.dLen-1] = quatity
+	}
+	return true
+}
+
+// Set up the problem again from its current inputs (in its own memory),
+// the next Solve() starts from scratch.
+func (es *Problem) rebuild() error {
+	sLen, dLen := es.inputSize()
+	supply := make([]float64, sLen)
+	copy(supply, es.supply)
+	demand := make([]float64, dLen)
+	copy(demand, es.demand)
+	_, costs := reshape[float64](nil, nil, sLen, dLen)
+	var caps [][]float64
+	if es.capacity != nil {
+		_, caps = reshape[float64](nil, nil, sLen, dLen)
+	}
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < dLen; j++ {
+			costs[i][j] = es.costMatrix[i][j]
+			if es.isForbidden(i, j) {
+				costs[i][j] = es.infinity
+			}
+		}
+		if caps != nil {
+			copy(caps[i], es.capacity[i])
+		}
+	}
+	opts := &Options{
+		MaxIter:         es.maxIter,
+		Epsilon:         es.epsilon,
+		Algorithm:       es.algorithm,
+		InitialStrategy: es.strategy,
+		PivotRule:       es.pivotRule,
+		TimeLimit:       es.timeLimit,
+		Trace:           es.trace,
+		Observer:        es.observer,
+
+		SurplusCosts:      es.surplusCost,
+		ShortagePenalties: es.shortagePenalty,
+	}
+	return es.init(supply, demand, costs, caps, opts)
+}
+
+// Rebuild the tree of the current basis and compute the flow of its
+// cells from the supply/demand. Returns false if there is no basis to
+// start from or it isn't feasible any more.
+func (es *Problem) restoreBasis() bool {
+	if err := es.buildTree(); err != nil {
+		return false
+	}
+	sLen, t := es.sLen, es.tree
+
+	// what the subtree of a node sends to its parent, the rows send
+	// their supply and the columns take their demand, the non-basic
+	// cells at their capacity take their part of it
+	net := es.netBuf
+	for i := 0; i < sLen; i++ {
+		net[i] = es.supply[i]
+	}
+	for j := 0; j < es.dLen; j++ {
+		net[sLen+j] = -es.demand[j]
+	}
+	for i := 0; i < sLen; i++ {
+		for j := 0; j < es.dLen; j++ {
+			if fc := &es.flow[i][j]; fc.upper {
+				net[i] -= fc.value
+				net[sLen+j] += fc.value
+			} else if !fc.basic {
+				fc.value = 0
+			}
+		}
+	}
+
+	// walk the thread backwards so the children are done before their
+	// parents, the root is the first node of the thread
+	for x := t.last[0]; x != 0; x = t.rthread[x] {
+		f := net[x]
+		if x >= sLen {
+			f = -f
+		}
+		i, j := es.treeCell(x)
+		if f < -es.epsilon || f > es.capacityOf(i, j)+es.epsilon {
+			return false
+		}
+		es.flow[i][j].value = math.Min(math.Max(0, f), es.capacityOf(i, j))
+		net[t.parent[x]] += net[x]
+	}
+	return true
+}
+
+// clear the solution so the next solve starts from scratch
+func (es *Problem) resetFlow() {
+	for i := 0; i < es.sLen; i++ {
+		for j := 0; j < es.dLen; j++ {
+			fc := &es.flow[i][j]
+			fc.basic, fc.upper, fc.value = false, false, 0
+		}
+	}
+	es.loop = nil
+}
@@ -0,0 +1,204 @@
+package tp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// solve the problem from scratch with the same inputs as the warm one
+func coldObjective(supply, demand []float64, costs, caps [][]float64, opts ...Option) (float64, error) {
+	var p *Problem
+	var err error
+	if caps != nil {
+		p, err = NewCapacitatedProblem(supply, demand, costs, caps, opts...)
+	} else {
+		p, err = NewProblem(supply, demand, costs, opts...)
+	}
+	if err != nil {
+		return 0, err
+	}
+	result, err := p.Solve()
+	if err != nil {
+		return 0, err
+	}
+	return result.Objective, nil
+}
+
+func TestWarmStart(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	saved, warmCnt := 0, 0
+	for k := 0; k < 30; k++ {
+		tp := randomProblem(r, 3+r.Intn(20), 3+r.Intn(20))
+		supply := append([]float64{}, tp.supply...)
+		demand := append([]float64{}, tp.demand...)
+		costs := copyCosts(tp.costs)
+		var caps [][]float64
+		opts := []Option{WithMaxIter(0), WithAlgorithm(Algorithm(k % 2))}
+		var p *Problem
+		var err error
+		if k%3 == 2 {
+			if caps, err = randomCapacities(r, tp); err != nil {
+				t.Error(fmt.Sprintf("failed to get capacities for [%v]", tp.name), err)
+				return
+			}
+			p, err = NewCapacitatedProblem(supply, demand, costs, caps, opts...)
+		} else {
+			p, err = NewProblem(supply, demand, costs, opts...)
+		}
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to create [%v]", tp.name), err)
+			return
+		}
+		result, err := p.Solve()
+		if err != nil {
+			t.Error(fmt.Sprintf("failed to solve [%v]", tp.name), err)
+			return
+		}
+		if result.WarmStart || result.PivotsSaved != 0 {
+			t.Error(fmt.Sprintf("[%v] first solve is a warm start: %+v", tp.name, result))
+			return
+		}
+
+		for round := 0; round < 5; round++ {
+			what := ""
+			switch round {
+			case 0, 1:
+				// a few cost changes
+				for n := 0; n < 3; n++ {
+					i, j := r.Intn(len(supply)), r.Intn(len(demand))
+					costs[i][j] = float64(r.Intn(50))
+					if err = p.SetCost(i, j, costs[i][j]); err != nil {
+						break
+					}
+				}
+				what = "costs"
+			case 2:
+				// same change on both sides keeps the balance
+				i, j, x := r.Intn(len(supply)), r.Intn(len(demand)), float64(1+r.Intn(10))
+				supply[i] += x
+				demand[j] += x
+				if err = p.SetSupply(i, supply[i]); err == nil {
+					err = p.SetDemand(j, demand[j])
+				}
+				what = "supply/demand"
+			case 3:
+				// forbid a route
+				i, j := r.Intn(len(supply)), r.Intn(len(demand))
+				costs[i][j] = math.Inf(1)
+				err = p.SetCost(i, j, costs[i][j])
+				what = "forbidden route"
+			case 4:
+				// may add/remove the dummy producer/consumer
+				i := r.Intn(len(supply))
+				supply[i] = float64(1 + r.Intn(100))
+				err = p.SetSupply(i, supply[i])
+				what = "supply"
+			}
+			if err != nil {
+				t.Error(fmt.Sprintf("[%v] failed to change %v", tp.name, what), err)
+				return
+			}
+			result, err = p.Solve()
+			c, cerr := coldObjective(supply, demand, costs, caps, opts...)
+			if errors.Is(err, ErrInfeasible) && errors.Is(cerr, ErrInfeasible) {
+				// the capacities can't carry it any more
+				break
+			}
+			if err != nil {
+				t.Error(fmt.Sprintf("[%v] failed to re-solve after changing %v", tp.name, what), err)
+				return
+			}
+			if err = cerr; err != nil {
+				t.Error(fmt.Sprintf("[%v] failed to solve from scratch after changing %v", tp.name, what), err)
+				return
+			}
+			if !result.Optimal() || math.Abs(result.Objective-c) > 1e-6*math.Max(1, c) {
+				t.Error(fmt.Sprintf("[%v] after changing %v: re-solve %+v, from scratch %v", tp.name, what, result, c))
+				return
+			}
+			if p.algorithm == AlgoNetworkSimplex {
+				if err = checkTree(p); err != nil {
+					t.Error(fmt.Sprintf("[%v] bad basis tree after changing %v", tp.name, what), err)
+					return
+				}
+			}
+			if result.PivotsSaved < 0 || (!result.WarmStart && result.PivotsSaved != 0) {
+				t.Error(fmt.Sprintf("[%v] after changing %v: %v pivots saved, warm start: %v", tp.name, what, result.PivotsSaved, result.WarmStart))
+				return
+			}
+			if result.WarmStart {
+				warmCnt += 1
+				saved += result.PivotsSaved
+			}
+		}
+	}
+	if warmCnt == 0 || saved == 0 {
+		t.Error(fmt.Sprintf("%v warm starts saved %v pivots", warmCnt, saved))
+		return
+	}
+
+	// a cost change keeps the basis feasible
+	tp := testData[0]
+	p, _ := NewProblem(tp.supply, tp.demand, tp.costs)
+	if _, err := p.Solve(); err != nil {
+		t.Error("failed to solve", err)
+		return
+	}
+	if err := p.SetCost(0, 0, tp.costs[0][0]+1); err != nil {
+		t.Error("failed to set cost", err)
+		return
+	}
+	if result, err := p.Solve(); err != nil || !result.WarmStart {
+		t.Error(fmt.Sprintf("expect a warm start, got %+v", result), err)
+		return
+	}
+
+	// the sensitivity and the reduced costs of the last solve are not
+	// the ones of the changed inputs
+	for k, set := range []func() error{
+		func() error { return p.SetCost(0, 1, tp.costs[0][1]+1) },
+		func() error { return p.SetSupply(0, tp.supply[0]) },
+		func() error { return p.SetDemand(0, tp.demand[0]) },
+	} {
+		if err := set(); err != nil {
+			t.Error(fmt.Sprintf("failed to set #%v", k), err)
+			return
+		}
+		if _, err := p.GetSensitivity(); err == nil {
+			t.Error(fmt.Sprintf("expect error for the sensitivity after set #%v", k))
+			return
+		}
+		if _, err := p.GetReducedCosts(); err == nil {
+			t.Error(fmt.Sprintf("expect error for the reduced costs after set #%v", k))
+			return
+		}
+		if _, err := p.Solve(); err != nil {
+			t.Error(fmt.Sprintf("failed to solve after set #%v", k), err)
+			return
+		}
+		if _, err := p.GetSensitivity(); err != nil {
+			t.Error(fmt.Sprintf("failed to get the sensitivity after solving set #%v", k), err)
+			return
+		}
+	}
+
+	// invalid changes
+	for _, err := range []error{
+		p.SetCost(-1, 0, 1),
+		p.SetCost(0, len(tp.demand), 1),
+		p.SetCost(0, 0, math.NaN()),
+		p.SetCost(0, 0, math.Inf(-1)),
+		p.SetSupply(len(tp.supply), 1),
+		p.SetSupply(0, 0),
+		p.SetDemand(-1, 1),
+		p.SetDemand(0, -1),
+	} {
+		if err == nil {
+			t.Error("expect error for an invalid change")
+			return
+		}
+	}
+}
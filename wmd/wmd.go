@@ -1,8 +1,10 @@
 package wmd
 
 import (
-	//"fmt"
+	"context"
+	"fmt"
 	"math"
+	"math/rand"
 	"strings"
 
 	"github.com/yizha/go/tp"
@@ -12,23 +14,29 @@ import (
 type nbdoc struct {
 	nbow []float64
 	wvec []w2v.Vector
+	// count of the words in the model, repeated ones included (nbow is
+	// the word counts divided by the count of the unique ones)
+	total int
 }
 
 func toNbDoc(words []string, m *w2v.Model) *nbdoc {
 	wvs := make([]w2v.Vector, 0, len(words))
 	wmap := make(map[string][]int) // word --> [id, cnt]
 	wcnt := 0
+	total := 0
 	for _, w := range words {
 		w = strings.ToLower(w)
 		wmeta, ok := wmap[w]
 		if ok {
 			wmeta[1] = wmeta[1] + 1
+			total += 1
 		} else {
 			wv := m.GetVectorByWord(w)
 			if wv == nil {
 				continue
 			}
 			wcnt += 1
+			total += 1
 			id := len(wvs)
 			wvs = append(wvs, wv)
 			wmap[w] = []int{id, 1}
@@ -48,6 +56,7 @@ func toNbDoc(words []string, m *w2v.Model) *nbdoc {
 		return &nbdoc{
 			nbow: nbow,
 			wvec: wvs,
+			total: total,
 		}
 	} else {
 		return nil
@@ -74,10 +83,74 @@ func calculateDistanceMatrix(d1, d2 *nbdoc, vsize int) [][]float64 {
 	return dm
 }
 
+// NotOptimalError is returned along with the distance when the solver
+// stops before it reaches the optimal solution, the distance is then
+// only an upper bound of the word-move-distance.
+type NotOptimalError struct {
+	Result *tp.Result
+}
+
+func (e *NotOptimalError) Error() string {
+	return fmt.Sprintf("solution is not optimal (%v) after %v iterations, max violation: %v",
+		e.Result.Status, e.Result.Iterations, e.Result.MaxViolation)
+}
+
 // returns the word-move-distance between the given two words slice
 // if one of the words slice doesn't have any word in the model then
 // this function returns math.Inf(1).
+// If the solver doesn't reach the optimal solution it returns the
+// distance it got along with a *NotOptimalError.
 func Wmd(d1, d2 []string, m *w2v.Model) (float64, error) {
+	return WmdContext(context.Background(), d1, d2, m)
+}
+
+// Solver solves the transportation problem between the two bag of
+// words, both the exact and the approximate solvers in tp return the
+// same *tp.Result so Wmd can switch between them.
+type Solver func(ctx context.Context, supply, demand []float64, costs [][]float64) (*tp.Result, error)
+
+// Exact solves the problem with the simplex method of tp, it is the
+// solver used by Wmd().
+func Exact(ctx context.Context, supply, demand []float64, costs [][]float64) (*tp.Result, error) {
+	p, err := tp.CreateProblem(supply, demand, costs)
+	if err != nil {
+		return nil, err
+	}
+	return p.SolveContext(ctx)
+}
+
+// ExactSolver returns a solver like Exact with the given options, e.g.
+// tp.WithPivotRule(tp.PivotPerturbation) for the documents whose words
+// have the same weights, their problems are heavily degenerate.
+func ExactSolver(opts ...tp.Option) Solver {
+	return func(ctx context.Context, supply, demand []float64, costs [][]float64) (*tp.Result, error) {
+		p, err := tp.NewProblem(supply, demand, costs, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return p.SolveContext(ctx)
+	}
+}
+
+// SinkhornSolver returns a solver with the entropic regularization reg
+// (see tp.Sinkhorn()), it is much faster on big documents and the
+// distance it gets is a bit above the exact one. opts are passed to
+// tp.Sinkhorn() as is.
+func SinkhornSolver(reg float64, opts ...tp.Option) Solver {
+	return func(ctx context.Context, supply, demand []float64, costs [][]float64) (*tp.Result, error) {
+		return tp.SinkhornContext(ctx, supply, demand, costs, reg, opts...)
+	}
+}
+
+// Same as Wmd() but the solver stops when the context is done, the
+// distance it got so far is returned along with a *NotOptimalError.
+func WmdContext(ctx context.Context, d1, d2 []string, m *w2v.Model) (float64, error) {
+	return WmdWith(ctx, d1, d2, m, Exact)
+}
+
+// Same as WmdContext() but the distance is calculated with the given
+// solver.
+func WmdWith(ctx context.Context, d1, d2 []string, m *w2v.Model, solve Solver) (float64, error) {
 	nbd1 := toNbDoc(d1, m)
 	nbd2 := toNbDoc(d2, m)
 
@@ -90,13 +163,157 @@ func Wmd(d1, d2 []string, m *w2v.Model) (float64, error) {
 	//fmt.Printf("supply: %v\n", nbd1.nbow)
 	//fmt.Printf("demand: %v\n", nbd2.nbow)
 	//fmt.Printf("costs:  %v\n", dm)
-	p, err := tp.CreateProblem(nbd1.nbow, nbd2.nbow, dm)
+	result, err := solve(ctx, nbd1.nbow, nbd2.nbow, dm)
 	if err != nil {
 		return -1, err
 	}
-	err = p.Solve()
+	if !result.Optimal() {
+		return result.Objective, &NotOptimalError{Result: result}
+	}
+	return result.Objective, nil
+}
+
+// PartialWmd returns the distance from the query to the part of the
+// document matching it best, mass of the query is moved with
+// tp.SolvePartial(). Every word weighs 1 divided by the query's word
+// count, so a document longer than the query has more mass than it and
+// its words far from the query are left out (the words of a shorter one
+// weigh 1 divided by its own word count, it takes all of the query).
+// The distance is the cost per unit moved, it is 0 if the document has
+// the query's words whatever else it has, and it is Wmd() when all of
+// the query is moved to a document with as many words in the model and
+// neither of them repeats a word (the words weigh their count divided
+// by the count of the unique ones in Wmd()).
+//
+//	mass: fraction of the query to move, in (0,1], less than 1 leaves
+//	      out the query words which are the farthest from the document.
+//	opts: options of the solver.
+//
+// It returns math.Inf(1) if one of the words slice doesn't have any word
+// in the model. If the solver doesn't reach the optimal solution it
+// returns the distance it got along with a *NotOptimalError.
+func PartialWmd(query, doc []string, m *w2v.Model, mass float64, opts ...tp.Option) (float64, error) {
+	return PartialWmdContext(context.Background(), query, doc, m, mass, opts...)
+}
+
+// Same as PartialWmd() but the solver stops when the context is done,
+// the distance it got so far is returned along with a *NotOptimalError.
+func PartialWmdContext(ctx context.Context, query, doc []string, m *w2v.Model, mass float64, opts ...tp.Option) (float64, error) {
+	if math.IsNaN(mass) || mass <= 0 || mass > 1 {
+		return -1, fmt.Errorf("mass %v is not in (0,1]!", mass)
+	}
+	nbd1 := toNbDoc(query, m)
+	nbd2 := toNbDoc(doc, m)
+
+	if nbd1 == nil || nbd2 == nil {
+		return math.Inf(1), nil
+	}
+
+	// the word counts of the query are divided by its word count (so
+	// it sums to 1), the document's by the word count of the shorter one
+	supply := normalize(nbd1.nbow)
+	scale := float64(nbd2.total) / math.Min(float64(nbd1.total), float64(nbd2.total))
+	demand := normalize(nbd2.nbow)
+	for j := range demand {
+		demand[j] *= scale
+	}
+
+	dm := calculateDistanceMatrix(nbd1, nbd2, m.FeatureSize)
+	result, err := tp.SolvePartialContext(ctx, supply, demand, dm, mass, opts...)
 	if err != nil {
 		return -1, err
 	}
-	return p.GetCost(), nil
+	if !result.Optimal() {
+		return result.Objective / mass, &NotOptimalError{Result: result}
+	}
+	return result.Objective / mass, nil
+}
+
+// SlicedWmd returns the sliced Wasserstein distance of order p between
+// the given two words slice, a cheap proxy of the word-move-distance:
+// the word vectors are projected onto random directions and the 1-D
+// problems are solved by tp.Wasserstein1D() in O(n log n), the distance
+// is the p-th root of the mean of their costs. The weights of the words
+// are scaled to sum to 1. With p = 1 and no repeated words it is never
+// above Wmd() and it gets closer with more projections.
+//
+//	projections: count of random directions, at least 1.
+//	r: source of the directions, a fixed seed one is used if it is nil
+//	   so the same words get the same distance.
+//
+// Like Wmd() it returns math.Inf(1) if one of the words slice doesn't
+// have any word in the model.
+func SlicedWmd(d1, d2 []string, m *w2v.Model, projections int, p float64, r *rand.Rand) (float64, error) {
+	if projections < 1 {
+		return -1, fmt.Errorf("count of projections %v is less than 1!", projections)
+	}
+	nbd1 := toNbDoc(d1, m)
+	nbd2 := toNbDoc(d2, m)
+
+	if nbd1 == nil || nbd2 == nil {
+		return math.Inf(1), nil
+	}
+	if r == nil {
+		r = rand.New(rand.NewSource(1))
+	}
+
+	w1, w2 := normalize(nbd1.nbow), normalize(nbd2.nbow)
+	dir := make([]float64, m.FeatureSize)
+	x := make([]float64, len(nbd1.wvec))
+	y := make([]float64, len(nbd2.wvec))
+	sum := float64(0)
+	for n := 0; n < projections; n++ {
+		randomDirection(r, dir)
+		project(nbd1.wvec, dir, x)
+		project(nbd2.wvec, dir, y)
+		cost, err := tp.Wasserstein1D(x, w1, y, w2, p)
+		if err != nil {
+			return -1, err
+		}
+		sum += cost
+	}
+	return math.Pow(sum/float64(projections), 1/p), nil
+}
+
+// the weights divided by their sum, the nbow of a document with repeated
+// words sums to more than 1
+func normalize(nbow []float64) []float64 {
+	sum := float64(0)
+	for _, w := range nbow {
+		sum += w
+	}
+	weights := make([]float64, len(nbow))
+	for k, w := range nbow {
+		weights[k] = w / sum
+	}
+	return weights
+}
+
+// a random unit vector, uniform on the sphere
+func randomDirection(r *rand.Rand, dir []float64) {
+	for {
+		norm := float64(0)
+		for k := range dir {
+			dir[k] = r.NormFloat64()
+			norm += dir[k] * dir[k]
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for k := range dir {
+				dir[k] /= norm
+			}
+			return
+		}
+	}
+}
+
+// positions of the word vectors on the direction
+func project(wvec []w2v.Vector, dir []float64, x []float64) {
+	for i, v := range wvec {
+		s := float64(0)
+		for k := range dir {
+			s += v[k] * dir[k]
+		}
+		x[i] = s
+	}
 }
@@ -1,10 +1,13 @@
 package wmd
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 
+	"github.com/yizha/go/tp"
 	"github.com/yizha/go/w2v"
 )
 
@@ -38,5 +41,158 @@ func TestWMD(t *testing.T) {
 		t.Error("Wmd() returns error:", err)
 		return
 	}
+	if d, err := WmdContext(context.Background(), d1, d2, model); err != nil || math.Abs(d-distance) > 1e-9 {
+		t.Error(fmt.Sprintf("WmdContext() is %v, Wmd() is %v, error: %v", d, distance, err))
+		return
+	}
+
+	// the regularized distance is a bit above the exact one
+	for _, reg := range []float64{0.1, 0.01} {
+		d, err := WmdWith(context.Background(), d1, d2, model, SinkhornSolver(reg, tp.WithMaxIter(0)))
+		if err != nil || d < distance-1e-6 || d > distance+reg*math.Log(10) {
+			t.Error(fmt.Sprintf("WmdWith() of Sinkhorn(%v) is %v, exact one is %v, error: %v", reg, d, distance, err))
+			return
+		}
+	}
+
+	// the words have the same weights, every pivot rule gets the same
+	// distance from the degenerate northwest corner solution
+	for _, rule := range []tp.PivotRule{tp.PivotDantzig, tp.PivotFirstEligible, tp.PivotBland, tp.PivotPerturbation} {
+		solve := ExactSolver(tp.WithPivotRule(rule), tp.WithInitialStrategy(tp.InitNorthwestCorner), tp.WithMaxIter(0))
+		if d, err := WmdWith(context.Background(), d1, d2, model, solve); err != nil || math.Abs(d-distance) > 1e-9 {
+			t.Error(fmt.Sprintf("WmdWith() of ExactSolver(%v) is %v, Wmd() is %v, error: %v", rule, d, distance, err))
+			return
+		}
+	}
+	distance, err = Wmd(d1, d1, model)
+	if err != nil || distance > 1e-6 {
+		t.Error(fmt.Sprintf("Wmd() of the same words is %v, error: %v", distance, err))
+		return
+	}
 	fmt.Printf("wmd between [%v] and [%v] is %v\n", t1, t2, distance)
 }
+
+func TestSlicedWmd(t *testing.T) {
+	d1 := strings.Split("A test word", " ")
+	d2 := strings.Split("The text world", " ")
+	distance, _ := Wmd(d1, d2, model)
+
+	// the projections are never longer than the vectors, with enough of
+	// them it gets close to the exact distance from below
+	sliced, err := SlicedWmd(d1, d2, model, 500, 1, nil)
+	if err != nil || sliced > distance+1e-9 || sliced < distance/2 {
+		t.Error(fmt.Sprintf("SlicedWmd() is %v, Wmd() is %v, error: %v", sliced, distance, err))
+		return
+	}
+	if d, err := SlicedWmd(d1, d2, model, 500, 1, nil); err != nil || d != sliced {
+		t.Error(fmt.Sprintf("SlicedWmd() is %v the second time, %v the first time, error: %v", d, sliced, err))
+		return
+	}
+	if d, err := SlicedWmd(d1, d1, model, 10, 2, nil); err != nil || d > 1e-9 {
+		t.Error(fmt.Sprintf("SlicedWmd() of the same words is %v, error: %v", d, err))
+		return
+	}
+
+	// the only direction of 1-D vectors is the vectors themselves
+	line := &w2v.Model{FeatureSize: 1, Word2id: model.Word2id, Vectors: make([]w2v.Vector, len(model.Vectors))}
+	for k, v := range model.Vectors {
+		line.Vectors[k] = w2v.Vector{v[0]}
+	}
+	distance, _ = Wmd(d1, d2, line)
+	if d, err := SlicedWmd(d1, d2, line, 3, 1, nil); err != nil || math.Abs(d-distance) > 1e-9 {
+		t.Error(fmt.Sprintf("SlicedWmd() of 1-D vectors is %v, Wmd() is %v, error: %v", d, distance, err))
+		return
+	}
+
+	// repeated words weigh more, repeating all of them changes nothing
+	repeated, err := SlicedWmd(strings.Split("A test test word", " "), d2, model, 100, 1, nil)
+	if err != nil || repeated <= 0 || math.IsInf(repeated, 1) {
+		t.Error(fmt.Sprintf("SlicedWmd() with a repeated word is %v, error: %v", repeated, err))
+		return
+	}
+	sliced, _ = SlicedWmd(d1, d2, model, 100, 1, nil)
+	if d, err := SlicedWmd(strings.Split("A A test test word word", " "), d2, model, 100, 1, nil); err != nil || math.Abs(d-sliced) > 1e-9 {
+		t.Error(fmt.Sprintf("SlicedWmd() with every word twice is %v, should be %v, error: %v", d, sliced, err))
+		return
+	}
+
+	if d, err := SlicedWmd([]string{"unknown"}, d2, model, 10, 1, nil); err != nil || !math.IsInf(d, 1) {
+		t.Error(fmt.Sprintf("SlicedWmd() without known words is %v, error: %v", d, err))
+		return
+	}
+	if _, err := SlicedWmd(d1, d2, model, 0, 1, nil); err == nil {
+		t.Error("no error for SlicedWmd() without projections")
+		return
+	}
+}
+
+func TestPartialWmd(t *testing.T) {
+	d1 := strings.Split("A test word", " ")
+	d2 := strings.Split("The text world", " ")
+	distance, _ := Wmd(d1, d2, model)
+	if d, err := PartialWmd(d1, d2, model, 1); err != nil || math.Abs(d-distance) > 1e-9 {
+		t.Error(fmt.Sprintf("PartialWmd() of all the words is %v, Wmd() is %v, error: %v", d, distance, err))
+		return
+	}
+
+	// Wmd() divides the word counts by the count of the unique words,
+	// PartialWmd() scales them to sum to 1 so repeating every word of
+	// both documents changes nothing
+	d1 = strings.Split("A test test word", " ")
+	if d, err := Wmd(d1, strings.Split("The text world", " "), model); err != nil || math.Abs(d-0.19235189469) > 1e-9 {
+		t.Error(fmt.Sprintf("Wmd() of repeated words is %v, error: %v", d, err))
+		return
+	}
+	if nbd := toNbDoc(d1, model); nbd.nbow[1] != 2.0/3 || nbd.total != 4 {
+		t.Error(fmt.Sprintf("nbow of %v is %v, %v words", d1, nbd.nbow, nbd.total))
+		return
+	}
+	distance, _ = Wmd(strings.Split("A test word", " "), strings.Split("The text world", " "), model)
+	d1 = strings.Split("A A test test word word", " ")
+	d2 = strings.Split("The The text text world world", " ")
+	if d, err := PartialWmd(d1, d2, model, 1); err != nil || math.Abs(d-distance) > 1e-9 {
+		t.Error(fmt.Sprintf("PartialWmd() of repeated words is %v, should be %v, error: %v", d, distance, err))
+		return
+	}
+
+	// the document has the query's words and more, it isn't the other
+	// way round
+	query := strings.Split("test word", " ")
+	doc := strings.Split("A test word the text", " ")
+	if d, err := PartialWmd(query, doc, model, 1); err != nil || d > 1e-9 {
+		t.Error(fmt.Sprintf("PartialWmd() of a query in the document is %v, error: %v", d, err))
+		return
+	}
+	if d, err := PartialWmd(doc, query, model, 1); err != nil || d < 1e-3 {
+		t.Error(fmt.Sprintf("PartialWmd() of a document in the query is %v, error: %v", d, err))
+		return
+	}
+	if d, _ := Wmd(query, doc, model); d < 1e-3 {
+		t.Error(fmt.Sprintf("Wmd() of a query in the document is %v", d))
+		return
+	}
+
+	// moving less leaves the farthest words out, it costs less per unit
+	query = strings.Split("test word world", " ")
+	doc = strings.Split("test word text", " ")
+	all, err := PartialWmd(query, doc, model, 1, tp.WithAlgorithm(tp.AlgoNetworkSimplex))
+	if err != nil {
+		t.Error("PartialWmd() returns error:", err)
+		return
+	}
+	if d, err := PartialWmd(query, doc, model, 2.0/3); err != nil || d > 1e-9 || all < 1e-3 {
+		t.Error(fmt.Sprintf("PartialWmd() of 2/3 is %v, all is %v, error: %v", d, all, err))
+		return
+	}
+
+	if d, err := PartialWmd([]string{"unknown"}, d2, model, 1); err != nil || !math.IsInf(d, 1) {
+		t.Error(fmt.Sprintf("PartialWmd() without known words is %v, error: %v", d, err))
+		return
+	}
+	for _, mass := range []float64{0, 1.5, math.NaN()} {
+		if _, err := PartialWmd(d1, d2, model, mass); err == nil {
+			t.Error(fmt.Sprintf("no error for PartialWmd() of mass %v", mass))
+			return
+		}
+	}
+}